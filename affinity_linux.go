@@ -0,0 +1,20 @@
+//go:build linux
+
+package fulltext
+
+import "syscall"
+import "unsafe"
+
+// setAffinity pins the calling OS thread to cpu
+func setAffinity(cpu int) error {
+	var mask [16]uint64
+	if cpu < 0 || cpu >= len(mask)*64 {
+		return syscall.EINVAL
+	}
+	mask[cpu/64] |= 1 << (cpu % 64)
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
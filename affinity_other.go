@@ -0,0 +1,8 @@
+//go:build !linux
+
+package fulltext
+
+// setAffinity is a no-op where thread affinity is not supported, it is only a hint
+func setAffinity(cpu int) error {
+	return nil
+}
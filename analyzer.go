@@ -0,0 +1,67 @@
+package fulltext
+
+import "fmt"
+import "strings"
+import "sync"
+
+var ErrUnknownAnalyzer = fmt.Errorf("unknown_analyzer")
+
+// Analyzer turns text into the normalized words to index, for example by tokenizing, lowercasing, dropping stopwords and stemming.
+type Analyzer interface {
+	Analyze(text string) []string
+}
+
+var analyzersMu sync.RWMutex
+var analyzers = make(map[string]Analyzer)
+
+// RegisterAnalyzer makes an analyzer available by name to NewOpts.Analyzer.
+// The analyzer subpackage registers its pipelines when imported.
+func RegisterAnalyzer(name string, a Analyzer) {
+	analyzersMu.Lock()
+	analyzers[name] = a
+	analyzersMu.Unlock()
+}
+
+// UnstemmedAnalyzer is an Analyzer which can also analyze text without stemming, such as to keep "testing" apart from
+// "test", enabling NewOpts.IndexUnstemmed
+type UnstemmedAnalyzer interface {
+	Analyzer
+	AnalyzeUnstemmed(text string) []string
+}
+
+// unstemmedMark prefixes the unstemmed words indexed by NewOpts.IndexUnstemmed, and caseMark the words keeping their
+// case indexed by NewOpts.DualCase. Analyzers never emit them.
+const unstemmedMark = "\x00"
+const caseMark = "\x01"
+
+// marked reports whether word is indexed behind a mark
+func marked(word string) bool {
+	return strings.HasPrefix(word, unstemmedMark) || strings.HasPrefix(word, caseMark)
+}
+
+// LookupAnalyzer returns the analyzer registered under name
+func LookupAnalyzer(name string) (a Analyzer, ok bool) {
+	analyzersMu.RLock()
+	a, ok = analyzers[name]
+	analyzersMu.RUnlock()
+	return
+}
+
+// analyzeQuery runs the named analyzer over a looked up word, keeping its first token, without stemming when unstemmed.
+// Loading rejects shards naming analyzers not registered in this process, which would otherwise leave the word as it is.
+func analyzeQuery(name, word string, unstemmed bool) string {
+	a, ok := LookupAnalyzer(name)
+	if !ok {
+		return word
+	}
+	var tokens []string
+	if u, ok := a.(UnstemmedAnalyzer); ok && unstemmed {
+		tokens = u.AnalyzeUnstemmed(word)
+	} else {
+		tokens = a.Analyze(word)
+	}
+	if len(tokens) == 0 {
+		return ""
+	}
+	return tokens[0]
+}
@@ -0,0 +1,164 @@
+// package analyzer implements composable text analysis pipelines (tokenize → lowercase → stopwords → stem)
+// and registers them with fulltext under their language names, for use as NewOpts.Analyzer.
+//
+//	import _ "github.com/neurlang/fulltext/analyzer"
+//
+//	opts := fulltext.NewDefaultOpts()
+//	opts.Analyzer = "english"
+package analyzer
+
+import "github.com/neurlang/fulltext"
+import "strings"
+import "unicode"
+
+// Tokenizer splits text into raw tokens
+type Tokenizer func(text string) []string
+
+// Filter transforms a single token, returning "" drops the token
+type Filter func(token string) string
+
+// Pipeline is a tokenizer followed by filters applied in order and a stemmer. It implements fulltext.Analyzer and
+// fulltext.UnstemmedAnalyzer.
+type Pipeline struct {
+	Tokenizer Tokenizer
+	Filters   []Filter
+	// Stemmer runs after the filters, skipped by AnalyzeUnstemmed. nil = no stemming.
+	Stemmer Filter
+}
+
+// Analyze tokenizes text and runs every token through the filters and the stemmer
+func (p *Pipeline) Analyze(text string) []string {
+	return p.analyze(text, p.Stemmer)
+}
+
+// AnalyzeUnstemmed tokenizes text and runs every token through the filters only
+func (p *Pipeline) AnalyzeUnstemmed(text string) []string {
+	return p.analyze(text, nil)
+}
+
+func (p *Pipeline) analyze(text string, stemmer Filter) []string {
+	var tokens []string
+	if p.Tokenizer != nil {
+		tokens = p.Tokenizer(text)
+	} else {
+		tokens = []string{text}
+	}
+	var out = tokens[:0]
+next:
+	for _, token := range tokens {
+		for _, filter := range p.Filters {
+			if token = filter(token); token == "" {
+				continue next
+			}
+		}
+		if stemmer != nil {
+			if token = stemmer(token); token == "" {
+				continue
+			}
+		}
+		out = append(out, token)
+	}
+	return out
+}
+
+// Words splits text on every character that is neither a letter nor a digit
+func Words(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Lowercase maps the token to lower case
+func Lowercase(token string) string {
+	return strings.ToLower(token)
+}
+
+// ASCIIFold maps accented characters to their base ASCII forms
+func ASCIIFold(token string) string {
+	return fulltext.FoldASCII(token)
+}
+
+// Stopwords drops the listed words
+func Stopwords(words ...string) Filter {
+	var set = make(map[string]struct{}, len(words))
+	for _, word := range words {
+		set[word] = struct{}{}
+	}
+	return func(token string) string {
+		if _, ok := set[token]; ok {
+			return ""
+		}
+		return token
+	}
+}
+
+// Suffixes is a light stemmer replacing the first matching suffix, given as suffix and replacement pairs,
+// as long as at least minStem characters of the token remain.
+func Suffixes(minStem int, pairs ...string) Filter {
+	return func(token string) string {
+		for k := 0; k+1 < len(pairs); k += 2 {
+			if strings.HasSuffix(token, pairs[k]) && len(token)-len(pairs[k]) >= minStem {
+				return token[:len(token)-len(pairs[k])] + pairs[k+1]
+			}
+		}
+		return token
+	}
+}
+
+// Register makes a pipeline available under name to NewOpts.Analyzer
+func Register(name string, p *Pipeline) {
+	fulltext.RegisterAnalyzer(name, p)
+}
+
+// Standard tokenizes on non alphanumeric characters and lowercases
+func Standard() *Pipeline {
+	return &Pipeline{Tokenizer: Words, Filters: []Filter{Lowercase}}
+}
+
+var englishStopwords = []string{"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "if", "in", "into", "is", "it",
+	"no", "not", "of", "on", "or", "such", "that", "the", "their", "then", "there", "these",
+	"they", "this", "to", "was", "will", "with"}
+
+var germanStopwords = []string{"aber", "als", "am", "an", "auch", "auf", "aus", "bei", "das", "dass", "dem", "den", "der",
+	"des", "die", "ein", "eine", "einen", "einer", "es", "für", "im", "in", "ist", "mit", "nicht",
+	"oder", "sich", "sie", "und", "von", "zu"}
+
+var frenchStopwords = []string{"au", "aux", "avec", "ce", "ces", "dans", "de", "des", "du", "elle", "en", "et", "il", "je",
+	"la", "le", "les", "leur", "mais", "ne", "nous", "on", "ou", "par", "pas", "pour", "qui", "sur",
+	"un", "une", "vous"}
+
+// English adds English stopwords and a light suffix stemmer to Standard
+func English() *Pipeline {
+	return &Pipeline{Tokenizer: Words, Filters: []Filter{
+		Lowercase,
+		Stopwords(englishStopwords...),
+	}, Stemmer: Suffixes(3, "ingly", "", "edly", "", "ies", "y", "ing", "", "ed", "", "ly", "", "es", "", "s", "")}
+}
+
+// German adds German stopwords, umlaut folding and a light suffix stemmer to Standard
+func German() *Pipeline {
+	return &Pipeline{Tokenizer: Words, Filters: []Filter{
+		Lowercase,
+		Stopwords(germanStopwords...),
+		ASCIIFold,
+	}, Stemmer: Suffixes(3, "ungen", "", "ern", "", "en", "", "er", "", "es", "", "e", "", "s", "")}
+}
+
+// French adds French stopwords, accent folding and a light suffix stemmer to Standard
+func French() *Pipeline {
+	return &Pipeline{Tokenizer: Words, Filters: []Filter{
+		Lowercase,
+		Stopwords(frenchStopwords...),
+		ASCIIFold,
+	}, Stemmer: Suffixes(3, "ements", "", "ement", "", "euses", "", "euse", "", "es", "", "s", "", "e", "")}
+}
+
+func init() {
+	Register("standard", Standard())
+	Register("english", English())
+	Register("german", German())
+	Register("french", French())
+	Register("social", Social())
+	Register("url", URL())
+	Register("code", Code())
+}
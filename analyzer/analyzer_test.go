@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/neurlang/fulltext"
+)
+
+// TestEnglishPipeline tests tokenizing, lowercasing, stopword removal and stemming
+func TestEnglishPipeline(t *testing.T) {
+	got := English().Analyze("The Indexes are RUNNING, searching quickly!")
+	want := []string{"index", "runn", "search", "quick"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+// TestAnalyzerOption tests that a registered analyzer is applied to rows and queries
+func TestAnalyzerOption(t *testing.T) {
+	data := map[string]fulltext.BagOfWords{
+		"doc:1": {"The Golang backend, running fast": {}},
+		"doc:2": {"Rust systems programming": {}},
+	}
+	opts := fulltext.NewDefaultOpts()
+	opts.Analyzer = "english"
+	idx, err := fulltext.New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var results []string
+	for pk := range idx.Lookup("Runs", true, true) {
+		results = append(results, pk)
+	}
+	if len(results) != 1 || results[0] != "doc:1" {
+		t.Fatalf("expected doc:1, got %v", results)
+	}
+
+	opts.Analyzer = "klingon"
+	if _, err := fulltext.New(opts, data, nil); err != fulltext.ErrUnknownAnalyzer {
+		t.Fatalf("expected ErrUnknownAnalyzer, got %v", err)
+	}
+}
+
+// TestDisableStemming tests that unstemmed lookups tell words with a common stem apart in indexes keeping them
+func TestDisableStemming(t *testing.T) {
+	data := map[string]fulltext.BagOfWords{
+		"doc:1": {"Testing frameworks": {}},
+		"doc:2": {"Tested units": {}},
+		"doc:3": {"Test suite": {}},
+	}
+	lookup := func(idx *fulltext.Index, word string, disable bool) (results []string) {
+		for pk := range idx.LookupWith(word, &fulltext.LookupOpts{Exact: true, Dedup: true, DisableStemming: disable}) {
+			results = append(results, pk)
+		}
+		sort.Strings(results)
+		return
+	}
+	opts := fulltext.NewDefaultOpts()
+	opts.Analyzer = "english"
+	stemmed, err := fulltext.New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	opts.IndexUnstemmed = true
+	idx, err := fulltext.New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf, err := idx.SerializeProto()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var loaded fulltext.Index
+	if err := loaded.DeserializeProto(buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, tc := range []struct {
+		idx     *fulltext.Index
+		word    string
+		disable bool
+		want    []string
+	}{
+		{&loaded, "testing", false, []string{"doc:1", "doc:2", "doc:3"}},
+		{&loaded, "Testing", true, []string{"doc:1"}},
+		{&loaded, "tested", true, []string{"doc:2"}},
+		{stemmed, "testing", true, []string{"doc:1", "doc:2", "doc:3"}},
+	} {
+		if got := lookup(tc.idx, tc.word, tc.disable); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("expected %v looking up %q with DisableStemming %v, got %v", tc.want, tc.word, tc.disable, got)
+		}
+	}
+	opts.Analyzer = ""
+	if _, err := fulltext.New(opts, data, nil); !errors.Is(err, fulltext.ErrConflictingOpts) {
+		t.Fatalf("expected ErrConflictingOpts without an analyzer, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package analyzer
+
+import "unicode"
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character, which are written without spaces between words
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// CJKBigrams splits runs of CJK characters into overlapping character bigrams (a lone character stays a unigram),
+// while other text is split like Words. "東京都に住む" yields 東京, 京都, 都に, に住, 住む.
+func CJKBigrams(text string) []string {
+	var tokens []string
+	var run []rune
+	flush := func() {
+		if len(run) == 1 {
+			tokens = append(tokens, string(run))
+		}
+		for k := 0; k+1 < len(run); k++ {
+			tokens = append(tokens, string(run[k:k+2]))
+		}
+		run = run[:0]
+	}
+	var start = -1
+	for pos, r := range text {
+		if isCJK(r) {
+			if start >= 0 {
+				tokens = append(tokens, Words(text[start:pos])...)
+				start = -1
+			}
+			run = append(run, r)
+			continue
+		}
+		flush()
+		if start < 0 {
+			start = pos
+		}
+	}
+	flush()
+	if start >= 0 {
+		tokens = append(tokens, Words(text[start:])...)
+	}
+	return tokens
+}
+
+// CJK tokenizes CJK text into bigrams and lowercases the rest.
+// Queries keep their first bigram only, so longer CJK queries match a superset of rows.
+func CJK() *Pipeline {
+	return &Pipeline{Tokenizer: CJKBigrams, Filters: []Filter{Lowercase}}
+}
+
+func init() {
+	Register("cjk", CJK())
+}
@@ -0,0 +1,45 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/neurlang/fulltext"
+)
+
+// TestCJKBigrams tests bigram segmentation of mixed script text
+func TestCJKBigrams(t *testing.T) {
+	got := CJKBigrams("東京都に住む Go言語 한국어")
+	want := []string{"東京", "京都", "都に", "に住", "住む", "Go", "言語", "한국", "국어"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+// TestCJKLookup tests that CJK text becomes searchable
+func TestCJKLookup(t *testing.T) {
+	data := map[string]fulltext.BagOfWords{
+		"doc:1": {"東京都に住む": {}},
+		"doc:2": {"大阪府に住む": {}},
+	}
+	opts := fulltext.NewDefaultOpts()
+	opts.Analyzer = "cjk"
+	idx, err := fulltext.New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var results []string
+	for pk := range idx.Lookup("京都", true, true) {
+		results = append(results, pk)
+	}
+	if len(results) != 1 || results[0] != "doc:1" {
+		t.Fatalf("expected doc:1, got %v", results)
+	}
+	count := 0
+	for range idx.Lookup("住む", true, true) {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 results, got %d", count)
+	}
+}
@@ -0,0 +1,48 @@
+package analyzer
+
+import "strings"
+import "unicode"
+
+// Identifiers tokenizes source code into its identifiers, each followed by its snake_case and camelCase parts,
+// so both the original identifier and its words can be looked up. "parseHTTPRequest" yields parseHTTPRequest,
+// parse, HTTP and Request, "max_retry_count" yields max_retry_count, max, retry and count.
+func Identifiers(text string) []string {
+	var tokens []string
+	identifiers := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '$'
+	})
+	for _, identifier := range identifiers {
+		parts := identifierParts(identifier)
+		if len(parts) != 1 || parts[0] != identifier {
+			tokens = append(tokens, identifier)
+		}
+		tokens = append(tokens, parts...)
+	}
+	return tokens
+}
+
+// identifierParts splits an identifier on underscores, dollars and case changes. An uppercase run is one part,
+// except for its last letter starting the next part: "HTTPServer" splits into HTTP and Server.
+func identifierParts(identifier string) []string {
+	var parts []string
+	for _, word := range strings.FieldsFunc(identifier, func(r rune) bool { return r == '_' || r == '$' }) {
+		runes := []rune(word)
+		start := 0
+		for k := 1; k < len(runes); k++ {
+			prev, curr := runes[k-1], runes[k]
+			lowerToUpper := !unicode.IsUpper(prev) && unicode.IsUpper(curr)
+			upperRunEnds := unicode.IsUpper(prev) && unicode.IsUpper(curr) && k+1 < len(runes) && unicode.IsLower(runes[k+1])
+			if lowerToUpper || upperRunEnds {
+				parts = append(parts, string(runes[start:k]))
+				start = k
+			}
+		}
+		parts = append(parts, string(runes[start:]))
+	}
+	return parts
+}
+
+// Code tokenizes source code into lowercased identifiers and their parts, for source code search
+func Code() *Pipeline {
+	return &Pipeline{Tokenizer: Identifiers, Filters: []Filter{Lowercase}}
+}
@@ -0,0 +1,43 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/neurlang/fulltext"
+)
+
+// TestIdentifiers tests splitting identifiers into their parts, keeping the identifiers
+func TestIdentifiers(t *testing.T) {
+	for text, tokens := range map[string][]string{
+		"func parseHTTPRequest(r *Request)": {"func", "parseHTTPRequest", "parse", "HTTP", "Request", "r", "Request"},
+		"max_retry_count = 3":               {"max_retry_count", "max", "retry", "count", "3"},
+		"$scope.v2Client.JSONBody":          {"$scope", "scope", "v2Client", "v2", "Client", "JSONBody", "JSON", "Body"},
+	} {
+		if got := Identifiers(text); !reflect.DeepEqual(got, tokens) {
+			t.Errorf("expected %q, got %q", tokens, got)
+		}
+	}
+}
+
+// TestCodeLookup tests finding source files by an identifier part and by the whole identifier
+func TestCodeLookup(t *testing.T) {
+	opts := fulltext.NewDefaultOpts()
+	opts.Analyzer = "code"
+	idx, err := fulltext.New(opts, map[string]fulltext.BagOfWords{
+		"main.go": {"func parseHTTPRequest(r *Request) error": {}},
+		"util.go": {"const max_retry_count = 3": {}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for word, file := range map[string]string{"http": "main.go", "parseHTTPRequest": "main.go", "retry": "util.go", "max_retry_count": "util.go"} {
+		var results []string
+		for pk := range idx.Lookup(word, true, true) {
+			results = append(results, pk)
+		}
+		if len(results) != 1 || results[0] != file {
+			t.Errorf("expected [%s] for %q, got %v", file, word, results)
+		}
+	}
+}
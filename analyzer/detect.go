@@ -0,0 +1,56 @@
+package analyzer
+
+import "sort"
+import "strings"
+
+// Detector guesses the language of a text from the stopwords of each language it holds, naming languages
+// like the registered analyzers, so the detected language selects the analyzer indexing the text
+type Detector struct {
+	languages map[string]map[string]struct{}
+}
+
+// NewDetector detects the languages of the analyzers registered by this package: english, german and french
+func NewDetector() *Detector {
+	d := &Detector{languages: make(map[string]map[string]struct{})}
+	d.Add("english", englishStopwords...)
+	d.Add("german", germanStopwords...)
+	d.Add("french", frenchStopwords...)
+	return d
+}
+
+// Add teaches the detector the stopwords of language
+func (d *Detector) Add(language string, stopwords ...string) {
+	set := d.languages[language]
+	if set == nil {
+		set = make(map[string]struct{}, len(stopwords))
+		d.languages[language] = set
+	}
+	for _, word := range stopwords {
+		set[word] = struct{}{}
+	}
+}
+
+// Detect returns the language whose stopwords occur most often in text, ties by name, or "" when text holds none
+func (d *Detector) Detect(text string) string {
+	names := make([]string, 0, len(d.languages))
+	for language := range d.languages {
+		names = append(names, language)
+	}
+	sort.Strings(names)
+	hits := make(map[string]int, len(names))
+	for _, word := range Words(text) {
+		word = strings.ToLower(word)
+		for _, language := range names {
+			if _, ok := d.languages[language][word]; ok {
+				hits[language]++
+			}
+		}
+	}
+	var best string
+	for _, language := range names {
+		if hits[language] > hits[best] {
+			best = language
+		}
+	}
+	return best
+}
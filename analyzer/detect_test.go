@@ -0,0 +1,24 @@
+package analyzer
+
+import (
+	"testing"
+)
+
+// TestDetector tests detecting the language of texts by their stopwords
+func TestDetector(t *testing.T) {
+	d := NewDetector()
+	for text, language := range map[string]string{
+		"The quick brown fox jumps over the lazy dog and the cat": "english",
+		"Der schnelle braune Fuchs springt über den faulen Hund":  "german",
+		"Le renard brun saute par dessus le chien paresseux":      "french",
+		"12345 golang": "",
+	} {
+		if detected := d.Detect(text); detected != language {
+			t.Errorf("expected %q for %q, got %q", language, text, detected)
+		}
+	}
+	d.Add("spanish", "el", "la", "los", "y", "de")
+	if detected := d.Detect("el zorro y los perros"); detected != "spanish" {
+		t.Errorf("expected spanish, got %q", detected)
+	}
+}
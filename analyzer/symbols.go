@@ -0,0 +1,114 @@
+package analyzer
+
+import "strings"
+import "unicode"
+
+// Policy chooses what a Symbols tokenizer does with a kind of token
+type Policy byte
+
+const (
+	// Split tokenizes like Words: emoji separate words, "#golang" yields golang and "snake_case" yields snake and case
+	Split Policy = iota
+	// Keep keeps the token whole: an emoji, "#golang", "@user" or "snake_case"
+	Keep
+	// Drop discards the token
+	Drop
+)
+
+// Symbols configures a tokenizer for social and log text, the zero value splits like Words
+type Symbols struct {
+	Emoji       Policy
+	Hashtags    Policy
+	Mentions    Policy
+	Underscores Policy
+}
+
+// isEmoji reports whether r is an emoji or another pictographic symbol
+func isEmoji(r rune) bool {
+	return unicode.Is(unicode.So, r) || (r >= 0x1F000 && r <= 0x1FAFF)
+}
+
+// isEmojiJoiner reports whether r glues emoji into one sequence, the zero width joiner, variation selectors and skin tones
+func isEmojiJoiner(r rune) bool {
+	return r == 0x200D || (r >= 0xFE00 && r <= 0xFE0F) || (r >= 0x1F3FB && r <= 0x1F3FF)
+}
+
+// isWord reports whether r continues an identifier
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// Tokenizer returns the tokenizer applying the policies
+func (s Symbols) Tokenizer() Tokenizer {
+	return func(text string) []string {
+		var tokens []string
+		runes := []rune(text)
+		for k := 0; k < len(runes); {
+			r := runes[k]
+			switch {
+			case isEmoji(r):
+				end := k + 1
+				for end < len(runes) && (isEmoji(runes[end]) || isEmojiJoiner(runes[end])) {
+					end++
+				}
+				if s.Emoji == Keep {
+					tokens = append(tokens, string(runes[k:end]))
+				}
+				k = end
+			case (r == '#' || r == '@') && k+1 < len(runes) && isWord(runes[k+1]) && (k == 0 || !isWord(runes[k-1])):
+				end := k + 1
+				for end < len(runes) && isWord(runes[end]) {
+					end++
+				}
+				policy := s.Hashtags
+				if r == '@' {
+					policy = s.Mentions
+				}
+				switch policy {
+				case Keep:
+					tokens = append(tokens, string(runes[k:end]))
+				case Split:
+					tokens = s.identifier(tokens, string(runes[k+1:end]))
+				}
+				k = end
+			case isWord(r):
+				end := k + 1
+				for end < len(runes) && isWord(runes[end]) {
+					end++
+				}
+				tokens = s.identifier(tokens, string(runes[k:end]))
+				k = end
+			default:
+				k++
+			}
+		}
+		return tokens
+	}
+}
+
+// identifier appends word to tokens by the underscore policy
+func (s Symbols) identifier(tokens []string, word string) []string {
+	if !strings.Contains(word, "_") {
+		return append(tokens, word)
+	}
+	switch s.Underscores {
+	case Keep:
+		return append(tokens, word)
+	case Drop:
+		return tokens
+	}
+	for _, part := range strings.Split(word, "_") {
+		if part != "" {
+			tokens = append(tokens, part)
+		}
+	}
+	return tokens
+}
+
+// Social keeps emoji, hashtags, mentions and underscored identifiers as lowercased tokens
+func Social() *Pipeline {
+	return &Pipeline{
+		Tokenizer: Symbols{Emoji: Keep, Hashtags: Keep, Mentions: Keep, Underscores: Keep}.Tokenizer(),
+		Filters:   []Filter{Lowercase},
+	}
+}
@@ -0,0 +1,28 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestSymbols tests keeping, splitting and dropping emoji, hashtags, mentions and underscored identifiers
+func TestSymbols(t *testing.T) {
+	text := "loving #golang_tips 🚀👍🏽 thanks @go_team, see snake_case"
+	for _, c := range []struct {
+		symbols Symbols
+		tokens  []string
+	}{
+		{Symbols{}, []string{"loving", "golang", "tips", "thanks", "go", "team", "see", "snake", "case"}},
+		{Symbols{Emoji: Keep, Hashtags: Keep, Mentions: Keep, Underscores: Keep},
+			[]string{"loving", "#golang_tips", "🚀👍🏽", "thanks", "@go_team", "see", "snake_case"}},
+		{Symbols{Emoji: Drop, Hashtags: Drop, Mentions: Drop, Underscores: Drop}, []string{"loving", "thanks", "see"}},
+		{Symbols{Hashtags: Split, Mentions: Keep, Underscores: Keep}, []string{"loving", "golang_tips", "thanks", "@go_team", "see", "snake_case"}},
+	} {
+		if tokens := c.symbols.Tokenizer()(text); !reflect.DeepEqual(tokens, c.tokens) {
+			t.Errorf("%+v: expected %q, got %q", c.symbols, c.tokens, tokens)
+		}
+	}
+	if tokens := (Symbols{Mentions: Keep}).Tokenizer()("a@b.com"); !reflect.DeepEqual(tokens, Words("a@b.com")) {
+		t.Errorf("expected an address to split like Words, got %q", tokens)
+	}
+}
@@ -0,0 +1,29 @@
+package analyzer
+
+import "strings"
+import "unicode"
+
+// isURLSeparator reports whether r separates the components of URLs and file paths
+func isURLSeparator(r rune) bool {
+	return strings.ContainsRune(`/\._-?=&:#@+%~,;`, r) || unicode.IsSpace(r)
+}
+
+// URLParts splits every whitespace separated URL or file path into its components on / . _ - ? = & and similar
+// boundaries, keeping the whole URL as a token too, so both a component and the complete URL can be looked up.
+// "s3://bucket/invoices/2024.pdf" yields s3://bucket/invoices/2024.pdf, s3, bucket, invoices, 2024 and pdf.
+func URLParts(text string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(text) {
+		parts := strings.FieldsFunc(field, isURLSeparator)
+		if len(parts) != 1 || parts[0] != field {
+			tokens = append(tokens, field)
+		}
+		tokens = append(tokens, parts...)
+	}
+	return tokens
+}
+
+// URL tokenizes URLs and file paths into their lowercased components
+func URL() *Pipeline {
+	return &Pipeline{Tokenizer: URLParts, Filters: []Filter{Lowercase}}
+}
@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/neurlang/fulltext"
+)
+
+// TestURLParts tests splitting URLs and paths into components
+func TestURLParts(t *testing.T) {
+	for text, tokens := range map[string][]string{
+		"s3://bucket/invoices/2024.pdf":          {"s3://bucket/invoices/2024.pdf", "s3", "bucket", "invoices", "2024", "pdf"},
+		"https://x.io/a?user_id=7&page=2 report": {"https://x.io/a?user_id=7&page=2", "https", "x", "io", "a", "user", "id", "7", "page", "2", "report"},
+		`C:\Users\me\my-notes.txt`:               {`C:\Users\me\my-notes.txt`, "C", "Users", "me", "my", "notes", "txt"},
+	} {
+		if got := URLParts(text); !reflect.DeepEqual(got, tokens) {
+			t.Errorf("expected %q, got %q", tokens, got)
+		}
+	}
+}
+
+// TestURLLookup tests that a path component finds the row holding the whole path
+func TestURLLookup(t *testing.T) {
+	opts := fulltext.NewDefaultOpts()
+	opts.Analyzer = "url"
+	idx, err := fulltext.New(opts, map[string]fulltext.BagOfWords{
+		"doc:1": {"s3://bucket/invoices/2024.pdf": {}},
+		"doc:2": {"s3://bucket/receipts/2024.pdf": {}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var results []string
+	for pk := range idx.Lookup("invoice", true, true) {
+		results = append(results, pk)
+	}
+	if len(results) != 1 || results[0] != "doc:1" {
+		t.Fatalf("expected [doc:1], got %v", results)
+	}
+}
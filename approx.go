@@ -0,0 +1,103 @@
+package fulltext
+
+import "math"
+import "math/rand/v2"
+
+// approxMinShards is the number of shards ApproxMatchCount samples before it may stop
+const approxMinShards = 16
+
+// approxTolerance is the margin relative to the estimate at which ApproxMatchCount stops sampling
+const approxTolerance = 0.1
+
+// MatchCount is the estimated number of rows matching a word, see ApproxMatchCount
+type MatchCount struct {
+	// Count is the estimate, the true count is between Low and High at the requested confidence
+	Count int
+	Low   int
+	High  int
+	// Sampled is the number of shards probed out of Shards holding rows, all of them make Count exact
+	Sampled int
+	Shards  int
+}
+
+// ApproxMatchCount estimates the number of rows Lookup(word, true, true) yields by probing a random sample of the
+// shards, so dashboards can show match volumes over huge indexes without iterating the matches. Shards are sampled
+// until the margin of the estimate at confidence, such as 0.95, is within a tenth of it; confidence 1 probes every
+// shard. Rows are extrapolated by the fraction of the sampled rows matching, so shards of uneven sizes are weighted
+// by their rows. A confidence outside (0, 1] returns an OptsError.
+func (i *Index) ApproxMatchCount(word string, confidence float64) (MatchCount, error) {
+	if !(confidence > 0 && confidence <= 1) {
+		return MatchCount{}, &OptsError{Field: "Confidence", Err: ErrOutOfRange}
+	}
+	var shards []int
+	var total float64
+	for curr := range i.private {
+		if i.private[curr].Rows > 0 {
+			shards = append(shards, curr)
+			total += float64(i.private[curr].Rows)
+		}
+	}
+	rand.Shuffle(len(shards), func(x, y int) { shards[x], shards[y] = shards[y], shards[x] })
+	var words = []string{word}
+	if len(i.middleware) > 0 {
+		words = i.rewrite(word)
+	}
+	// z is the normal quantile of the two sided confidence, infinite at 1
+	z := math.Sqrt2 * math.Erfinv(confidence)
+	c := MatchCount{Shards: len(shards)}
+	var matches, rows []float64
+	var matched, sampled float64
+	var estimate, margin float64
+	for _, curr := range shards {
+		m, r := float64(i.shardMatches(curr, words)), float64(i.private[curr].Rows)
+		matches, rows = append(matches, m), append(rows, r)
+		matched, sampled = matched+m, sampled+r
+		c.Sampled++
+		// ratio estimator of the total, its variance shrinks by the unsampled fraction of the shards
+		ratio := matched / sampled
+		estimate, margin = ratio*total, 0
+		if c.Sampled == c.Shards {
+			break
+		}
+		if c.Sampled < approxMinShards || math.IsInf(z, 1) {
+			continue
+		}
+		var residuals float64
+		for k := range matches {
+			residuals += (matches[k] - ratio*rows[k]) * (matches[k] - ratio*rows[k])
+		}
+		n, shardCount := float64(c.Sampled), float64(c.Shards)
+		variance := shardCount * shardCount * (1 - n/shardCount) / n * residuals / (n - 1)
+		margin = z * math.Sqrt(variance)
+		if margin <= approxTolerance*estimate {
+			break
+		}
+	}
+	c.Count = int(math.Round(estimate))
+	c.Low = int(max(matched, math.Floor(estimate-margin)))
+	c.High = int(min(total, math.Ceil(estimate+margin)))
+	return c, nil
+}
+
+// shardMatches counts the live rows of shard curr matching any of words exactly
+func (i *Index) shardMatches(curr int, words []string) int {
+	p := &i.private[curr]
+	minWord := p.minWord()
+	matches := make(map[uint64]struct{})
+	for _, word := range words {
+		query := p.query(word)
+		if len(query) < minWord || !p.routable(query, minWord, true, true) {
+			continue
+		}
+		p.probe(query, minWord, true, true, 0, func(pos uint64, _ float64) bool {
+			if len(i.deleted) > 0 && i.deletedAt(curr, pos) {
+				return true
+			}
+			if i.visible == nil || i.visible(p.key(pos)) {
+				matches[pos] = struct{}{}
+			}
+			return true
+		}, func() bool { return false }, func() {}, nil)
+	}
+	return len(matches)
+}
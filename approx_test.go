@@ -0,0 +1,50 @@
+package fulltext
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestApproxMatchCount tests that sampled counts bracket the true count and confidence 1 counts exactly
+func TestApproxMatchCount(t *testing.T) {
+	data := make(map[string][]string)
+	for j := 0; j < 4000; j++ {
+		if j%4 == 0 {
+			data[fmt.Sprintf("doc:%04d", j)] = []string{"alpha"}
+		} else {
+			data[fmt.Sprintf("doc:%04d", j)] = []string{"bravo"}
+		}
+	}
+	opts := NewDefaultOpts()
+	opts.TargetShardRows = 50
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var exact int
+	for range idx.Lookup("alpha", true, true) {
+		exact++
+	}
+	c, err := idx.ApproxMatchCount("alpha", 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if c.Count != exact || c.Low != exact || c.High != exact || c.Sampled != c.Shards {
+		t.Fatalf("expected an exact count of %d, got %+v", exact, c)
+	}
+	c, err = idx.ApproxMatchCount("alpha", 0.95)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if c.Sampled >= c.Shards || c.Low > c.Count || c.Count > c.High {
+		t.Fatalf("expected a sampled count within its bounds, got %+v", c)
+	}
+	if c.Count < exact*3/4 || c.Count > exact*5/4 {
+		t.Fatalf("expected a sampled count near %d, got %+v", exact, c)
+	}
+	for _, confidence := range []float64{0, -1, 1.5} {
+		if _, err := idx.ApproxMatchCount("alpha", confidence); err == nil {
+			t.Errorf("expected an error for confidence %v", confidence)
+		}
+	}
+}
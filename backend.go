@@ -0,0 +1,99 @@
+package fulltext
+
+import quaternary "github.com/neurlang/quaternary/v1"
+import "encoding/binary"
+import "fmt"
+import "sync"
+
+var ErrUnknownFilterBackend = fmt.Errorf("unknown_filter_backend")
+
+// FilterBackend builds and probes the filters of a shard, each mapping keys to values of bitLimit bits. Lookups only
+// reach the filters through it, so shards can be backed by xor filters, plain hash maps or on-disk B-trees instead of
+// quaternary filters. Keys a filter was not built with may return arbitrary values, which lookups detect as false
+// positives, and filters shorter than 2 bytes are treated as empty. Methods are called concurrently.
+type FilterBackend interface {
+	// New builds a filter of the values of m, big endian bytes of bitLimit bits, 0 = values of any length.
+	// FalsePositiveFunctions trades space for fewer arbitrary answers to absent keys, where the backend supports it.
+	New(m map[string][]byte, bitLimit, falsePositiveFunctions byte) []byte
+	// Get returns the value of key
+	Get(filter []byte, bitLimit uint64, key string) []byte
+	// GetNum returns the value of key as a number
+	GetNum(filter []byte, bitLimit uint64, key string) uint64
+}
+
+var backendsMu sync.RWMutex
+var backends = make(map[string]FilterBackend)
+
+// RegisterFilterBackend makes a filter backend available by name to NewOpts.FilterBackend. Shards record the name,
+// so processes loading them must register the backend under the same name before.
+func RegisterFilterBackend(name string, b FilterBackend) {
+	backendsMu.Lock()
+	backends[name] = b
+	backendsMu.Unlock()
+}
+
+// LookupFilterBackend returns the filter backend registered under name, "" is the built in quaternary backend
+func LookupFilterBackend(name string) (b FilterBackend, ok bool) {
+	if name == "" {
+		return quaternaryBackend{}, true
+	}
+	backendsMu.RLock()
+	b, ok = backends[name]
+	backendsMu.RUnlock()
+	return
+}
+
+// quaternaryBackend is the FilterBackend of shards not naming another one
+type quaternaryBackend struct{}
+
+func (quaternaryBackend) New(m map[string][]byte, bitLimit, falsePositiveFunctions byte) []byte {
+	return quaternary.New(m, bitLimit, falsePositiveFunctions)
+}
+
+func (quaternaryBackend) Get(filter []byte, bitLimit uint64, key string) []byte {
+	return quaternary.Get(filter, bitLimit, key)
+}
+
+func (quaternaryBackend) GetNum(filter []byte, bitLimit uint64, key string) uint64 {
+	return quaternary.GetNum(filter, bitLimit, key)
+}
+
+// backend returns the filter backend of the shard
+func (p *index) backend() FilterBackend {
+	if p.filters != nil {
+		return p.filters
+	}
+	return quaternaryBackend{}
+}
+
+// resolveBackend looks up the filter backend named by a loaded shard
+func (p *index) resolveBackend() *ValidationError {
+	b, ok := LookupFilterBackend(p.Backend)
+	if !ok {
+		return &ValidationError{Field: "backend", Err: ErrUnknownFilterBackend}
+	}
+	p.filters = b
+	return nil
+}
+
+// numbers encodes the values of m as big endian bytes of bitLimit bits, the way quaternary encodes numbers
+func numbers(m map[string]uint64, bitLimit byte) map[string][]byte {
+	width := int(bitLimit+7) / 8
+	out := make(map[string][]byte, len(m))
+	values := make([]byte, width*len(m))
+	var b [8]byte
+	for k, v := range m {
+		binary.BigEndian.PutUint64(b[:], v)
+		out[k] = values[:width:width]
+		copy(out[k], b[8-width:])
+		values = values[width:]
+	}
+	return out
+}
+
+// rowKey encodes row pos as the key of the primary key filter
+func rowKey(pos uint64) string {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], pos)
+	return string(b[:])
+}
@@ -0,0 +1,57 @@
+package fulltext
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+// countingBackend is the quaternary backend counting its probes
+type countingBackend struct {
+	quaternaryBackend
+	probes atomic.Int64
+}
+
+func (b *countingBackend) GetNum(filter []byte, bitLimit uint64, key string) uint64 {
+	b.probes.Add(1)
+	return b.quaternaryBackend.GetNum(filter, bitLimit, key)
+}
+
+// TestFilterBackend tests that shards built with a registered backend are probed through it and remember its name
+func TestFilterBackend(t *testing.T) {
+	backend := new(countingBackend)
+	RegisterFilterBackend("counting", backend)
+	opts := NewDefaultOpts()
+	opts.FilterBackend = "counting"
+	idx, err := New(opts, map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	serialized, _ := idx.SerializeProto()
+	var loaded Index
+	if err := loaded.DeserializeProto(serialized); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var n int
+	for range loaded.Lookup("backend", true, true) {
+		n++
+	}
+	if n != 2 || backend.probes.Load() == 0 {
+		t.Fatalf("expected 2 results probed through the backend, got %d after %d probes", n, backend.probes.Load())
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	opts.FilterBackend = "missing"
+	if _, err := New(opts, map[string][]string{"doc:1": {"golang"}}, nil); !errors.Is(err, ErrUnknownFilterBackend) {
+		t.Fatalf("expected ErrUnknownFilterBackend, got %v", err)
+	}
+	loaded.private[0].Backend = "missing"
+	serialized, _ = loaded.SerializeProto()
+	if err := new(Index).DeserializeProto(serialized); !errors.Is(err, ErrUnknownFilterBackend) {
+		t.Fatalf("expected ErrUnknownFilterBackend, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package fulltext
+
+import "sync"
+
+// bagCache shares the bags of a shard between its bucket goroutines, so each row is fetched once per shard
+// instead of once per bucket. Rows past the limit are not cached but fetched every time.
+type bagCache struct {
+	getter func(primaryKey string) BagOfWords
+	limit  int
+
+	mut     sync.Mutex
+	entries map[string]*cachedBag
+}
+
+// cachedBag is fetched once by whichever goroutine asks first, the others wait for it
+type cachedBag struct {
+	once sync.Once
+	bag  BagOfWords
+}
+
+func newBagCache(getter func(primaryKey string) BagOfWords, limit int) *bagCache {
+	return &bagCache{getter: getter, limit: limit, entries: make(map[string]*cachedBag, limit)}
+}
+
+// get returns the bag of the row with primaryKey, reading through to the getter
+func (c *bagCache) get(primaryKey string) BagOfWords {
+	c.mut.Lock()
+	e, ok := c.entries[primaryKey]
+	if !ok {
+		if len(c.entries) >= c.limit {
+			c.mut.Unlock()
+			return c.getter(primaryKey)
+		}
+		e = new(cachedBag)
+		c.entries[primaryKey] = e
+	}
+	c.mut.Unlock()
+	e.once.Do(func() {
+		e.bag = c.getter(primaryKey)
+	})
+	return e.bag
+}
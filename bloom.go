@@ -0,0 +1,76 @@
+package fulltext
+
+import "hash/fnv"
+
+// bloomHashes is the number of probes per shingle in the shard bloom filter
+const bloomHashes = 4
+
+// addShingles remembers every shingle of word for the shard bloom filter
+func (p *index) addShingles(word string, minWord int) {
+	if p.shingles == nil {
+		p.shingles = make(map[string]struct{})
+	}
+	for t := 0; t+minWord <= len(word); t++ {
+		p.shingles[word[t:t+minWord]] = struct{}{}
+	}
+}
+
+// buildBloom turns the remembered shingles into the shard bloom filter with bitsPerShingle bits per entry
+func (p *index) buildBloom(bitsPerShingle byte) {
+	if bitsPerShingle == 0 || len(p.shingles) == 0 {
+		p.shingles = nil
+		return
+	}
+	bits := uint64(len(p.shingles))*uint64(bitsPerShingle) | 63
+	p.Bloom = make([]byte, (bits+1)/8)
+	for shingle := range p.shingles {
+		h1, h2 := bloomHash(p.salted(shingle))
+		for k := uint64(0); k < bloomHashes; k++ {
+			bit := (h1 + k*h2) % bits
+			p.Bloom[bit>>3] |= 1 << (bit & 7)
+		}
+	}
+	p.shingles = nil
+}
+
+// mayContain reports whether the shard may contain shingle, shards without a bloom filter may contain anything
+func (p *index) mayContain(shingle string) bool {
+	if len(p.Bloom) == 0 {
+		return true
+	}
+	bits := uint64(len(p.Bloom))*8 - 1
+	h1, h2 := bloomHash(p.salted(shingle))
+	for k := uint64(0); k < bloomHashes; k++ {
+		bit := (h1 + k*h2) % bits
+		if p.Bloom[bit>>3]&(1<<(bit&7)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// routable reports whether lookup of word can possibly hit the shard, judged by its bloom filter.
+// Deduplicated exact lookups tolerate one missing shingle, other lookups hit on any shingle.
+func (p *index) routable(word string, minWord int, exact, dedup bool) bool {
+	if len(p.Bloom) == 0 {
+		return true
+	}
+	var present, total int
+	for t := 0; t+minWord <= len(word); t++ {
+		total++
+		if p.mayContain(word[t : t+minWord]) {
+			present++
+		}
+	}
+	if exact && dedup {
+		return present+1 >= total
+	}
+	return present > 0
+}
+
+func bloomHash(s string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	sum := h.Sum64()
+	return sum, sum>>33 | 1
+}
@@ -0,0 +1,71 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestShardBloomRouting tests that shards without the word are skipped and hits are kept
+func TestShardBloomRouting(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+		"doc:3": {"python", "scripting"},
+	}
+	idx, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var routed, bloomed int
+	for curr := range idx.private {
+		p := &idx.private[curr]
+		if len(p.Bloom) > 0 {
+			bloomed++
+		}
+		if p.Rows > 0 && p.routable("scripting", 3, true, true) {
+			routed++
+		}
+	}
+	if bloomed != 3 || routed != 1 {
+		t.Fatalf("expected 3 bloom filters routing to 1 shard, got %d routing to %d", bloomed, routed)
+	}
+	for _, exact := range []bool{true, false} {
+		count := 0
+		for range idx.Lookup("backend", exact, true) {
+			count++
+		}
+		if count != 2 {
+			t.Fatalf("expected 2 results, got %d", count)
+		}
+	}
+
+	// a shard holding the rows of the routed shard under another bloom filter must not be probed
+	var target, other = -1, -1
+	for curr := range idx.private {
+		p := &idx.private[curr]
+		switch {
+		case p.Rows > 0 && p.routable("scripting", 3, true, true):
+			target = curr
+		case p.Rows > 0 && other < 0:
+			other = curr
+		}
+	}
+	bloom := idx.private[other].Bloom
+	idx.private[other] = idx.private[target]
+	idx.private[other].Bloom = bloom
+	count := 0
+	for range idx.Lookup("scripting", true, true) {
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("expected the shard rejected by its bloom filter to be skipped, got %d results", count)
+	}
+
+	opts := NewDefaultOpts()
+	opts.BloomBitsPerShingle = 0
+	idx, _ = New(opts, data, nil)
+	for _, p := range idx.private {
+		if len(p.Bloom) != 0 {
+			t.Fatal("expected no bloom filter when disabled")
+		}
+	}
+}
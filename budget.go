@@ -0,0 +1,45 @@
+package fulltext
+
+import "fmt"
+
+var ErrLimitExceeded = fmt.Errorf("limit_exceeded")
+
+// QueryLimitError reports the LookupOpts limit a lookup exceeded, it wraps ErrLimitExceeded
+type QueryLimitError struct {
+	Limit string
+	Max   int
+}
+
+func (e *QueryLimitError) Error() string {
+	return fmt.Sprintf("fulltext: lookup exceeds %s %d: %v", e.Limit, e.Max, ErrLimitExceeded)
+}
+
+func (e *QueryLimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// budget bounds the work of one lookup and records the error once it is exceeded
+type budget struct {
+	candidates  int
+	shardProbes int
+	err         error
+}
+
+// budget returns the limits of opts, nil when unlimited
+func (opts *LookupOpts) budget() *budget {
+	if opts.MaxCandidates <= 0 && opts.MaxShardProbes <= 0 {
+		return nil
+	}
+	return &budget{candidates: opts.MaxCandidates, shardProbes: opts.MaxShardProbes}
+}
+
+// LookupLimited is LookupWith returning a function reporting, once the iteration ended, a QueryLimitError when the
+// lookup was aborted by LookupOpts.MaxCandidates or MaxShardProbes. Rows yielded before a MaxCandidates abort are a
+// partial result, a MaxShardProbes abort yields no rows. Opts can be nil.
+func (i *Index) LookupLimited(word string, opts *LookupOpts) (func(yield func(primaryKey string) bool), func() error) {
+	if opts == nil {
+		opts = new(LookupOpts)
+	}
+	limit := &budget{candidates: opts.MaxCandidates, shardProbes: opts.MaxShardProbes}
+	return i.lookupWith(word, opts, limit), func() error { return limit.err }
+}
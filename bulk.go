@@ -0,0 +1,29 @@
+package fulltext
+
+import "fmt"
+
+var ErrBulkGetterMismatch = fmt.Errorf("bulk_getter_length_mismatch")
+
+// bulk fetches the words of every row of the shard with a single BulkGetter call, normalized like the getter results,
+// returning a getter serving them. ErrBulkGetterMismatch is returned when the result does not match the keys.
+func (p *index) bulk(bulkGetter func(pks []string) []BagOfWords, normalize func(BagOfWords) BagOfWords) (func(primaryKey string) BagOfWords, error) {
+	pks := make([]string, p.Rows)
+	for j := range pks {
+		pks[j] = p.key(uint64(j + 1))
+	}
+	result := bulkGetter(pks)
+	if len(result) != len(pks) {
+		return nil, ErrBulkGetterMismatch
+	}
+	bags := make(map[string]BagOfWords, len(pks))
+	for j, pk := range pks {
+		if normalize != nil {
+			bags[pk] = normalize(result[j])
+		} else {
+			bags[pk] = result[j]
+		}
+	}
+	return func(pk string) BagOfWords {
+		return bags[pk]
+	}, nil
+}
@@ -0,0 +1,85 @@
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestBulkGetter tests that buckets are built from one bulk call per shard instead of per row getter calls
+func TestBulkGetter(t *testing.T) {
+	words := make(map[string]BagOfWords)
+	for j := 0; j < 100; j++ {
+		words[fmt.Sprintf("doc:%03d", j)] = BagOfWords{fmt.Sprintf("word%03d", j): {}, "backend": {}}
+	}
+	var getterCalls, bulkCalls int
+	getter := func(pk string) BagOfWords {
+		getterCalls++
+		return words[pk]
+	}
+	opts := NewDefaultOpts()
+	opts.BulkGetter = func(pks []string) []BagOfWords {
+		bulkCalls++
+		bags := make([]BagOfWords, len(pks))
+		for j, pk := range pks {
+			bags[j] = words[pk]
+		}
+		return bags
+	}
+	idx, err := New(opts, words, getter)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if getterCalls != len(words) || bulkCalls != len(idx.private) {
+		t.Fatalf("expected %d getter calls and %d bulk calls, got %d and %d", len(words), len(idx.private), getterCalls, bulkCalls)
+	}
+	var found bool
+	for pk := range idx.Lookup("ord042", false, true) {
+		found = found || pk == "doc:042"
+	}
+	if !found {
+		t.Fatal("expected doc:042")
+	}
+	opts.BulkGetter = func(pks []string) []BagOfWords { return nil }
+	if _, err := New(opts, words, getter); !errors.Is(err, ErrBulkGetterMismatch) {
+		t.Fatalf("expected ErrBulkGetterMismatch, got %v", err)
+	}
+}
+
+// TestBagCache tests that the bucket goroutines of a shard share one getter call per row
+func TestBagCache(t *testing.T) {
+	words := make(map[string]BagOfWords)
+	for j := 0; j < 100; j++ {
+		words[fmt.Sprintf("doc:%03d", j)] = BagOfWords{fmt.Sprintf("longerword%03d", j): {}}
+	}
+	for _, rows := range []int{0, 1 << 16} {
+		var mut sync.Mutex
+		var calls int
+		opts := NewDefaultOpts()
+		opts.BagCacheRows = rows
+		opts.Sync = false
+		idx, err := New(opts, words, func(pk string) BagOfWords {
+			mut.Lock()
+			calls++
+			mut.Unlock()
+			return words[pk]
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if rows > 0 && calls != 2*len(words) {
+			t.Fatalf("expected %d getter calls, got %d", 2*len(words), calls)
+		}
+		if rows == 0 && calls <= 2*len(words) {
+			t.Fatalf("expected more than %d getter calls without the cache, got %d", 2*len(words), calls)
+		}
+		var found bool
+		for pk := range idx.Lookup("word042", false, true) {
+			found = found || pk == "doc:042"
+		}
+		if !found {
+			t.Fatal("expected doc:042")
+		}
+	}
+}
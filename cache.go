@@ -0,0 +1,126 @@
+package fulltext
+
+import "container/list"
+import "context"
+import "sync"
+import "time"
+
+type cacheKey struct {
+	word         string
+	exact, dedup bool
+	coverage     float64
+}
+
+type cacheEntry struct {
+	key  cacheKey
+	keys []string
+}
+
+// queryCache is a thread safe LRU of complete Lookup result sets
+type queryCache struct {
+	mut        sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[cacheKey]*list.Element
+}
+
+func newQueryCache(maxEntries int) *queryCache {
+	return &queryCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[cacheKey]*list.Element),
+	}
+}
+
+func (c *queryCache) get(key cacheKey) ([]string, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).keys, true
+}
+
+func (c *queryCache) put(key cacheKey, keys []string) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry).keys = keys
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, keys: keys})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *queryCache) purge() {
+	c.mut.Lock()
+	c.order.Init()
+	clear(c.entries)
+	c.mut.Unlock()
+}
+
+// WithCache enables a built in LRU cache of up to maxEntries complete Lookup result sets, keyed by (word, exact, dedup).
+// The cache is invalidated whenever the index is mutated, maxEntries <= 0 disables it. Lookups limited by
+// LookupOpts.MaxCandidates or MaxShardProbes bypass the cache.
+// WithCache is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) WithCache(maxEntries int) *Index {
+	if maxEntries <= 0 {
+		i.cache = nil
+	} else {
+		i.cache = newQueryCache(maxEntries)
+	}
+	return i
+}
+
+// cachedLookup serves Lookup from the cache, memoizing result sets that were iterated to the end. The result sets are
+// cached before WithVisibility hides rows, which are hidden when served.
+func (i *Index) cachedLookup(ctx context.Context, key cacheKey, yield func(string) bool) {
+	var shown = func(pk string) bool {
+		return i.visible == nil || i.visible(pk)
+	}
+	if keys, ok := i.cache.get(key); ok {
+		if i.metrics != nil {
+			i.metrics.Lookup()
+		}
+		var yielded int
+		if i.queryLog != nil {
+			defer func(begun time.Time) {
+				if latency := time.Since(begun); latency >= i.slowQuery {
+					i.queryLog.LogQuery(QueryRecord{Word: key.word, Exact: key.exact, Dedup: key.dedup,
+						Coverage: key.coverage, Hits: yielded, Latency: latency, Cached: true})
+				}
+			}(time.Now())
+		}
+		for _, pk := range keys {
+			if !shown(pk) {
+				continue
+			}
+			yielded++
+			if !yield(pk) {
+				return
+			}
+		}
+		return
+	}
+	var keys []string
+	var complete = true
+	i.lookupVisible(ctx, key.word, key.exact, key.dedup, key.coverage, nil, nil, nil, func(shard int, pos uint64, _ float64) bool {
+		pk := i.private[shard].key(pos)
+		keys = append(keys, pk)
+		if shown(pk) && !yield(pk) {
+			complete = false
+			return false
+		}
+		return true
+	})
+	if complete && ctx.Err() == nil {
+		i.cache.put(key, keys)
+	}
+}
@@ -0,0 +1,44 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestWithCache tests that cached results are served and invalidated on Append
+func TestWithCache(t *testing.T) {
+	idx := newTestIndex(t).WithCache(1)
+	count := func(word string) (n int) {
+		for range idx.Lookup(word, true, true) {
+			n++
+		}
+		return
+	}
+	if n := count("backend"); n != 2 {
+		t.Fatalf("expected 2 results, got %d", n)
+	}
+	if _, ok := idx.cache.get(cacheKey{word: "backend", exact: true, dedup: true}); !ok {
+		t.Fatal("expected complete result set to be cached")
+	}
+	if n := count("backend"); n != 2 {
+		t.Fatalf("expected 2 cached results, got %d", n)
+	}
+
+	for range idx.Lookup("golang", true, true) {
+		break
+	}
+	if _, ok := idx.cache.get(cacheKey{word: "golang", exact: true, dedup: true}); ok {
+		t.Fatal("expected partially iterated result set not to be cached")
+	}
+	count("python")
+	if _, ok := idx.cache.get(cacheKey{word: "backend", exact: true, dedup: true}); ok {
+		t.Fatal("expected least recently used entry to be evicted")
+	}
+
+	idx.Append(newTestIndex(t))
+	if _, ok := idx.cache.get(cacheKey{word: "python", exact: true, dedup: true}); ok {
+		t.Fatal("expected cache to be invalidated on Append")
+	}
+	if n := count("backend"); n != 4 {
+		t.Fatalf("expected 4 results after Append, got %d", n)
+	}
+}
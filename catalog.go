@@ -0,0 +1,118 @@
+package fulltext
+
+import "fmt"
+import "runtime"
+import "sort"
+import "sync"
+
+var ErrNoSuchNamespace = fmt.Errorf("no_such_namespace")
+
+// Catalog manages many named indexes, for example one per tenant or collection.
+// All methods are thread safe. Builds started through the catalog share a pool of N workers, N set by NewCatalog.
+type Catalog struct {
+	mut     sync.RWMutex
+	indexes map[string]*Index
+	builds  chan struct{}
+	workers chan struct{}
+}
+
+// NewCatalog creates a catalog running at most workers builds at once on a pool of workers building their shards
+// and buckets. Zero or negative means runtime.NumCPU().
+func NewCatalog(workers int) *Catalog {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &Catalog{
+		indexes: make(map[string]*Index),
+		builds:  make(chan struct{}, workers),
+		workers: make(chan struct{}, workers),
+	}
+}
+
+// BuildOpts returns a copy of opts building on the worker pool of the catalog, so the shards and buckets of every
+// build made with it, such as in Build, compete for the same N workers instead of each build spawning its own.
+// Opts can be nil.
+func (c *Catalog) BuildOpts(opts *NewOpts) *NewOpts {
+	if opts == nil || !opts.configured {
+		opts = NewDefaultOpts()
+	}
+	optsCopy := *opts
+	optsCopy.workers = c.workers
+	return &optsCopy
+}
+
+// Build runs build once fewer than N builds run, and stores the resulting index under namespace, replacing any
+// previous one. Typically build wraps a call to New with opts from BuildOpts.
+func (c *Catalog) Build(namespace string, build func() (*Index, error)) error {
+	c.builds <- struct{}{}
+	defer func() { <-c.builds }()
+	i, err := build()
+	if err != nil {
+		return err
+	}
+	c.Set(namespace, i)
+	return nil
+}
+
+// Set stores i under namespace, replacing any previous index
+func (c *Catalog) Set(namespace string, i *Index) {
+	c.mut.Lock()
+	c.indexes[namespace] = i
+	c.mut.Unlock()
+}
+
+// Get returns the index stored under namespace
+func (c *Catalog) Get(namespace string) (i *Index, ok bool) {
+	c.mut.RLock()
+	i, ok = c.indexes[namespace]
+	c.mut.RUnlock()
+	return
+}
+
+// Remove forgets the index stored under namespace
+func (c *Catalog) Remove(namespace string) {
+	c.mut.Lock()
+	delete(c.indexes, namespace)
+	c.mut.Unlock()
+}
+
+// Namespaces returns the sorted names of all stored indexes
+func (c *Catalog) Namespaces() (names []string) {
+	c.mut.RLock()
+	for name := range c.indexes {
+		names = append(names, name)
+	}
+	c.mut.RUnlock()
+	sort.Strings(names)
+	return
+}
+
+// Serialize serializes the index stored under namespace to JSON
+func (c *Catalog) Serialize(namespace string) ([]byte, error) {
+	i, ok := c.Get(namespace)
+	if !ok {
+		return nil, ErrNoSuchNamespace
+	}
+	return i.Serialize()
+}
+
+// Deserialize deserializes JSON data and stores the index under namespace
+func (c *Catalog) Deserialize(namespace string, data []byte) error {
+	i := new(Index)
+	if err := i.Deserialize(data); err != nil {
+		return err
+	}
+	c.Set(namespace, i)
+	return nil
+}
+
+// Lookup iterates the index stored under namespace, see Index.Lookup. Unknown namespaces yield nothing.
+func (c *Catalog) Lookup(namespace, word string, exact, dedup bool) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		i, ok := c.Get(namespace)
+		if !ok {
+			return
+		}
+		i.Lookup(word, exact, dedup)(yield)
+	}
+}
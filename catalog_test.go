@@ -0,0 +1,107 @@
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCatalogNamespaces tests building, looking up and serializing per namespace
+func TestCatalogNamespaces(t *testing.T) {
+	c := NewCatalog(2)
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			data := map[string][]string{
+				"doc:1": {[]string{"alpha", "bravo", "charlie", "delta"}[n], "shared"},
+			}
+			err := c.Build(fmt.Sprint("tenant", n), func() (*Index, error) {
+				return New(nil, data, nil)
+			})
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	if names := c.Namespaces(); len(names) != 4 {
+		t.Fatalf("expected 4 namespaces, got %v", names)
+	}
+	count := 0
+	for range c.Lookup("tenant2", "charlie", true, true) {
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 result, got %d", count)
+	}
+	count = 0
+	for range c.Lookup("tenant1", "charlie", true, true) {
+		count++
+	}
+	if count != 0 {
+		t.Fatalf("expected no results across namespaces, got %d", count)
+	}
+
+	data, err := c.Serialize("tenant3")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := c.Deserialize("copy", data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := c.Serialize("missing"); !errors.Is(err, ErrNoSuchNamespace) {
+		t.Fatalf("expected ErrNoSuchNamespace, got %v", err)
+	}
+}
+
+// TestCatalogWorkerPool tests that the buckets of builds made with BuildOpts share the workers of the catalog
+func TestCatalogWorkerPool(t *testing.T) {
+	c := NewCatalog(1)
+	var running, most atomic.Int32
+	getter := func(pk string) BagOfWords {
+		now := running.Add(1)
+		defer running.Add(-1)
+		for seen := most.Load(); now > seen && !most.CompareAndSwap(seen, now); seen = most.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		return BagOfWords{"international" + pk: {}}
+	}
+	data := make(map[string]struct{})
+	for n := 0; n < 20; n++ {
+		data[fmt.Sprintf("doc:%02d", n)] = struct{}{}
+	}
+	// the getter is called from every bucket build at once, the pool serializes them
+	opts := NewDefaultOpts()
+	opts.Sync = false
+	opts.BagCacheRows = 0
+	var wg sync.WaitGroup
+	for n := 0; n < 2; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			err := c.Build(fmt.Sprint("tenant", n), func() (*Index, error) {
+				return New(c.BuildOpts(opts), data, getter)
+			})
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		}(n)
+	}
+	wg.Wait()
+	if most.Load() != 1 {
+		t.Fatalf("expected a single worker building at once, got %d", most.Load())
+	}
+	count := 0
+	for range c.Lookup("tenant1", "international", false, true) {
+		count++
+	}
+	if count != len(data) {
+		t.Fatalf("expected %d results, got %d", len(data), count)
+	}
+}
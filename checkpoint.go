@@ -0,0 +1,81 @@
+package fulltext
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "sort"
+
+// checkpointPattern names the checkpoint file of every finished shard within NewOpts.CheckpointDir
+const checkpointPattern = "shard-%08d.ftx"
+
+// checkpoint persists the finished shard numbered shard into dir, renaming a complete file into place
+// so a crash never leaves a partial checkpoint behind
+func (p *index) checkpoint(dir string, shard int) error {
+	s := *p
+	s.Checksum = s.checksum()
+	name := filepath.Join(dir, fmt.Sprintf(checkpointPattern, shard))
+	if err := os.WriteFile(name+".tmp", s.appendProto(nil), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+// ResumeBuild continues a build by New with opts.CheckpointDir set to checkpointDir, which crashed or was stopped.
+// The shards finished before are loaded from their checkpoints, and only the rows of data they do not hold are built,
+// checkpointing the new shards into the same directory. Opts, data and getter should be the ones of the interrupted build.
+// The checkpoints are not removed, delete checkpointDir once the resulting index is serialized.
+func ResumeBuild[V struct{} | BagOfWords | []string | map[string]int | map[string]float32](checkpointDir string, opts *NewOpts, data map[string]V, getter func(primaryKey string) BagOfWords) (*Index, error) {
+	names, err := filepath.Glob(filepath.Join(checkpointDir, "shard-*.ftx"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	done := new(Index)
+	var next int
+	for _, name := range names {
+		var shard int
+		if _, err := fmt.Sscanf(filepath.Base(name), checkpointPattern, &shard); err != nil {
+			continue
+		}
+		raw, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		var p index
+		if err := p.unmarshalProto(raw); err != nil {
+			return nil, err
+		}
+		done.private = append(done.private, p)
+		next = max(next, shard+1)
+	}
+	if err := done.loaded(); err != nil {
+		return nil, err
+	}
+	built := make(map[string]struct{})
+	for curr := range done.private {
+		for pos := uint64(1); pos <= done.private[curr].Rows; pos++ {
+			built[done.private[curr].key(pos)] = struct{}{}
+		}
+	}
+	rest := make(map[string]V, len(data))
+	for pk, v := range data {
+		if _, ok := built[pk]; !ok {
+			rest[pk] = v
+		}
+	}
+	if opts == nil || opts.configured == false {
+		// defaults
+		opts = NewDefaultOpts()
+	}
+	var optsCopy = *opts
+	optsCopy.CheckpointDir = checkpointDir
+	optsCopy.checkpointFrom = next
+	i := new(Index)
+	if len(rest) > 0 {
+		if i, err = New(&optsCopy, rest, getter); err != nil {
+			return nil, err
+		}
+	}
+	i.private = append(done.private, i.private...)
+	return i, nil
+}
@@ -0,0 +1,71 @@
+package fulltext
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestResumeBuild tests that a resumed build reuses the checkpointed shards and builds only the missing rows
+func TestResumeBuild(t *testing.T) {
+	data := make(map[string][]string)
+	for j := 0; j < 40; j++ {
+		data[fmt.Sprintf("doc:%03d", j)] = []string{"common", fmt.Sprintf("word%03d", j)}
+	}
+	dir := t.TempDir()
+	opts := NewDefaultOpts()
+	opts.TargetShardRows = 10
+	opts.CheckpointDir = dir
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	names, _ := filepath.Glob(filepath.Join(dir, "shard-*.ftx"))
+	if len(names) != len(idx.private) {
+		t.Fatalf("expected %d checkpoints, got %d", len(idx.private), len(names))
+	}
+	// the build crashed before finishing the last two shards
+	lost := idx.private[len(idx.private)-2].Rows + idx.private[len(idx.private)-1].Rows
+	for _, name := range names[len(names)-2:] {
+		os.Remove(name)
+	}
+
+	var calls uint64
+	getter := func(pk string) BagOfWords {
+		calls++
+		bag := make(BagOfWords)
+		for _, word := range data[pk] {
+			bag[word] = struct{}{}
+		}
+		return bag
+	}
+	resumed, err := ResumeBuild(dir, opts, data, getter)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var rows uint64
+	for _, p := range resumed.private {
+		rows += p.Rows
+	}
+	if rows != 40 {
+		t.Fatalf("expected 40 rows, got %d", rows)
+	}
+	if calls == 0 || calls > lost*8 {
+		t.Fatalf("expected only the %d lost rows to be fetched, got %d calls", lost, calls)
+	}
+	for j := 0; j < 40; j++ {
+		want := fmt.Sprintf("doc:%03d", j)
+		var found bool
+		for pk := range resumed.Lookup(fmt.Sprintf("word%03d", j), true, true) {
+			found = found || pk == want
+		}
+		if !found {
+			t.Fatalf("expected %s to be found", want)
+		}
+	}
+	names, _ = filepath.Glob(filepath.Join(dir, "shard-*.ftx"))
+	if len(names) != len(resumed.private) {
+		t.Fatalf("expected %d checkpoints after resuming, got %d", len(resumed.private), len(names))
+	}
+}
@@ -0,0 +1,89 @@
+package cluster
+
+import "encoding/json"
+import "net/http"
+import "sync"
+import "time"
+import "github.com/neurlang/fulltext"
+
+// Stats summarizes a served index, see AdminHandler
+type Stats struct {
+	Shards        int       `json:"shards"`
+	Rows          uint64    `json:"rows"` // rows not deleted
+	Bytes         int       `json:"bytes"`
+	Generation    uint64    `json:"generation"`
+	BuildID       string    `json:"build_id,omitempty"`
+	BuiltAt       time.Time `json:"built_at,omitzero"`
+	SkewFlags     []string  `json:"skew_flags,omitempty"`
+	CompactNeeded bool      `json:"compact_needed,omitempty"`
+}
+
+// AdminHandler serves read-only introspection of i over HTTP, so a running index can be inspected without a debugger:
+//
+//	GET /healthz  responds ok
+//	GET /stats    the Stats of the index as JSON
+//	GET /shards   the size of every shard as JSON, see fulltext.Index.Skew
+//	GET /explain  the fulltext.Explanation of a lookup of the word query parameter as JSON, exact=1 for exact lookups
+//
+// Mount it under a prefix with http.StripPrefix. Lock is held while an endpoint reads the index, so the index can be
+// mutated while it is served by mutators holding the same lock, such as the RLocker of the sync.RWMutex the mutators
+// lock. Lock can be nil when the index is not mutated while it is served.
+func AdminHandler(i *fulltext.Index, lock sync.Locker) http.Handler {
+	if lock == nil {
+		lock = nopLocker{}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		skew := i.Skew()
+		meta := i.Meta()
+		generation := i.Generation()
+		lock.Unlock()
+		stats := Stats{
+			Shards:        len(skew.Shards),
+			Generation:    generation,
+			BuildID:       meta.BuildID,
+			BuiltAt:       meta.BuiltAt,
+			SkewFlags:     skew.Flags,
+			CompactNeeded: skew.CompactNeeded,
+		}
+		for _, s := range skew.Shards {
+			stats.Rows += s.Rows - s.Deleted
+			stats.Bytes += s.Bytes
+		}
+		writeJSON(w, stats)
+	})
+	mux.HandleFunc("GET /shards", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		skew := i.Skew()
+		lock.Unlock()
+		writeJSON(w, skew.Shards)
+	})
+	mux.HandleFunc("GET /explain", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("word") == "" {
+			http.Error(w, "missing word", http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		explanation := i.Explain(q.Get("word"), q.Get("exact") == "1")
+		lock.Unlock()
+		writeJSON(w, explanation)
+	})
+	return mux
+}
+
+// nopLocker locks nothing, for indexes not mutated while served
+type nopLocker struct{}
+
+func (nopLocker) Lock()   {}
+func (nopLocker) Unlock() {}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,119 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/neurlang/fulltext"
+)
+
+// TestAdminHandler tests the health, stats, shards and explain endpoints
+func TestAdminHandler(t *testing.T) {
+	opts := fulltext.NewDefaultOpts()
+	opts.BuildID = "build-1"
+	idx, err := fulltext.New(opts, map[string][]string{"doc:1": {"golang"}, "doc:2": {"rust"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	srv := httptest.NewServer(http.StripPrefix("/admin", AdminHandler(idx, nil)))
+	t.Cleanup(srv.Close)
+	get := func(path string, status int, v any) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("expected status %d for %s, got %s", status, path, resp.Status)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if v != nil {
+			if err := json.Unmarshal(body, v); err != nil {
+				t.Fatalf("expected JSON from %s, got %v", path, err)
+			}
+		}
+	}
+	get("/admin/healthz", http.StatusOK, nil)
+	var stats Stats
+	get("/admin/stats", http.StatusOK, &stats)
+	if stats.Rows != 2 || stats.BuildID != "build-1" || stats.Shards == 0 || stats.Bytes == 0 {
+		t.Fatalf("expected 2 rows of build-1, got %+v", stats)
+	}
+	var shards []fulltext.ShardSize
+	get("/admin/shards", http.StatusOK, &shards)
+	if len(shards) != stats.Shards {
+		t.Fatalf("expected %d shards, got %d", stats.Shards, len(shards))
+	}
+	var explanation fulltext.Explanation
+	get("/admin/explain?word=golang&exact=1", http.StatusOK, &explanation)
+	if explanation.Word != "golang" || !explanation.Exact || len(explanation.Shards) != stats.Shards {
+		t.Fatalf("expected an exact explanation of golang, got %+v", explanation)
+	}
+	get("/admin/explain", http.StatusBadRequest, nil)
+	resp, err := http.Post(srv.URL+"/admin/stats", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected read-only endpoints, got %s", resp.Status)
+	}
+}
+
+// TestAdminHandlerMutated tests reading an index under the lock of its mutators, counting only the rows not deleted
+func TestAdminHandlerMutated(t *testing.T) {
+	data := make(map[string][]string)
+	for n := 0; n < 20; n++ {
+		data[fmt.Sprintf("doc:%02d", n)] = []string{"golang"}
+	}
+	idx, err := fulltext.New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var mu sync.RWMutex
+	srv := httptest.NewServer(AdminHandler(idx, mu.RLocker()))
+	t.Cleanup(srv.Close)
+	stats := func() (s Stats) {
+		resp, err := http.Get(srv.URL + "/stats")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+			t.Fatalf("expected JSON, got %v", err)
+		}
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 0; n < 5; n++ {
+			mu.Lock()
+			idx.Delete(fmt.Sprintf("doc:%02d", n))
+			mu.Unlock()
+		}
+		mu.Lock()
+		err := idx.Update("doc:05", fulltext.BagOfWords{"rust": {}})
+		mu.Unlock()
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}()
+	for n := 0; n < 5; n++ {
+		stats()
+		resp, err := http.Get(srv.URL + "/explain?word=golang")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		resp.Body.Close()
+	}
+	<-done
+	if s := stats(); s.Rows != 15 {
+		t.Fatalf("expected 15 rows not deleted, got %+v", s)
+	}
+}
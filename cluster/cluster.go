@@ -0,0 +1,190 @@
+// package cluster turns fulltext indexes into a shardable search tier: Handler serves a local index over HTTP,
+// and a Coordinator fans lookups out to the shard servers, merging and deduplicating their streams.
+// AdminHandler serves read-only introspection endpoints next to it.
+//
+//	http.Handle("/lookup", cluster.Handler(idx))
+//	http.Handle("/admin/", http.StripPrefix("/admin", cluster.AdminHandler(idx, nil)))
+//
+//	coord := cluster.NewCoordinator("http://shard0:8080/lookup", "http://shard1:8080/lookup")
+//	for pk := range coord.Lookup(ctx, "golang", true, true) {
+//		...
+//	}
+package cluster
+
+import "bufio"
+import "context"
+import "encoding/json"
+import "fmt"
+import "net/http"
+import "net/url"
+import "sync"
+import "github.com/neurlang/fulltext"
+
+var ErrAllReplicasFailed = fmt.Errorf("all_replicas_failed")
+
+// Node is a remote shard server. HTTPNode talks to Handler, other transports such as gRPC implement Node themselves.
+type Node interface {
+	// Lookup streams the matching primary keys of the node to yield, stopping early when yield returns false
+	Lookup(ctx context.Context, word string, exact, dedup bool, yield func(primaryKey string) bool) error
+}
+
+// Handler serves Lookup of i over HTTP. The query parameters are word, exact and dedup,
+// the response streams one JSON encoded primary key per line.
+func Handler(i *fulltext.Index) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		word := q.Get("word")
+		exact, dedup := q.Get("exact") == "1", q.Get("dedup") == "1"
+		w.Header().Set("Content-Type", "application/jsonl")
+		enc := json.NewEncoder(w)
+		for pk := range i.Lookup(word, exact, dedup) {
+			if r.Context().Err() != nil || enc.Encode(pk) != nil {
+				break
+			}
+		}
+	})
+}
+
+// HTTPNode is a shard server reached over HTTP at URL, which is served by Handler
+type HTTPNode struct {
+	URL string
+
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+// Lookup requests the matching primary keys from the shard server
+func (n *HTTPNode) Lookup(ctx context.Context, word string, exact, dedup bool, yield func(primaryKey string) bool) error {
+	u, err := url.Parse(n.URL)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("word", word)
+	if exact {
+		q.Set("exact", "1")
+	}
+	if dedup {
+		q.Set("dedup", "1")
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cluster: %s: %s", n.URL, resp.Status)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var pk string
+		if err := json.Unmarshal(scanner.Bytes(), &pk); err != nil {
+			return err
+		}
+		if !yield(pk) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// Coordinator fans lookups out to every shard and merges the results
+type Coordinator struct {
+	// Shards lists the replicas of every shard. Replicas are tried in order until one completes the lookup.
+	Shards [][]Node
+
+	// OnFailure is called, possibly concurrently, for every shard whose replicas all failed. The other shards are still merged. Optional.
+	OnFailure func(shard int, err error)
+}
+
+// NewCoordinator creates a coordinator over one single replica HTTP shard per url
+func NewCoordinator(urls ...string) *Coordinator {
+	c := new(Coordinator)
+	for _, u := range urls {
+		c.Shards = append(c.Shards, []Node{&HTTPNode{URL: u}})
+	}
+	return c
+}
+
+// Lookup fans the lookup out to all shards in parallel. With dedup, every primary key is yielded once across shards.
+// A failing replica is retried on the next one, keys it already streamed are not yielded again.
+func (c *Coordinator) Lookup(ctx context.Context, word string, exact, dedup bool) func(yield func(primaryKey string) bool) {
+	return func(yield func(primaryKey string) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		keys := make(chan string)
+		var wg sync.WaitGroup
+		for shard, replicas := range c.Shards {
+			wg.Add(1)
+			go func(shard int, replicas []Node) {
+				defer wg.Done()
+				if err := c.lookupShard(ctx, replicas, word, exact, dedup, keys); err != nil && ctx.Err() == nil && c.OnFailure != nil {
+					c.OnFailure(shard, err)
+				}
+			}(shard, replicas)
+		}
+		go func() {
+			wg.Wait()
+			close(keys)
+		}()
+		var seen map[string]struct{}
+		if dedup {
+			seen = make(map[string]struct{})
+		}
+		for pk := range keys {
+			if seen != nil {
+				if _, ok := seen[pk]; ok {
+					continue
+				}
+				seen[pk] = struct{}{}
+			}
+			if !yield(pk) {
+				cancel()
+				for range keys {
+					// drain so the shard goroutines exit
+				}
+				return
+			}
+		}
+	}
+}
+
+// lookupShard streams the keys of the first replica completing the lookup into keys
+func (c *Coordinator) lookupShard(ctx context.Context, replicas []Node, word string, exact, dedup bool, keys chan<- string) error {
+	var streamed map[string]struct{}
+	var err error = ErrAllReplicasFailed
+	for replica, node := range replicas {
+		err = node.Lookup(ctx, word, exact, dedup, func(pk string) bool {
+			if replica > 0 {
+				if _, ok := streamed[pk]; ok {
+					return true
+				}
+			}
+			if len(replicas) > 1 {
+				if streamed == nil {
+					streamed = make(map[string]struct{})
+				}
+				streamed[pk] = struct{}{}
+			}
+			select {
+			case keys <- pk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/neurlang/fulltext"
+)
+
+func newServer(t *testing.T, data map[string][]string) *httptest.Server {
+	idx, err := fulltext.New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	srv := httptest.NewServer(Handler(idx))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func collect(c *Coordinator, word string) (keys []string) {
+	for pk := range c.Lookup(context.Background(), word, true, true) {
+		keys = append(keys, pk)
+	}
+	sort.Strings(keys)
+	return
+}
+
+// TestCoordinatorLookup tests merging and deduplicating results of several shard servers
+func TestCoordinatorLookup(t *testing.T) {
+	a := newServer(t, map[string][]string{"doc:1": {"golang"}, "doc:2": {"rust"}})
+	b := newServer(t, map[string][]string{"doc:3": {"golang"}, "doc:1": {"golang"}})
+	c := NewCoordinator(a.URL, b.URL)
+	if keys := collect(c, "golang"); fmt.Sprint(keys) != "[doc:1 doc:3]" {
+		t.Fatalf("expected [doc:1 doc:3], got %v", keys)
+	}
+}
+
+// TestCoordinatorFailure tests that failing shards are reported and replicas take over
+func TestCoordinatorFailure(t *testing.T) {
+	a := newServer(t, map[string][]string{"doc:1": {"golang"}})
+	b := newServer(t, map[string][]string{"doc:2": {"golang"}})
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	var failed []int
+	c := NewCoordinator(a.URL, down.URL)
+	c.Shards = append(c.Shards, []Node{&HTTPNode{URL: down.URL}, &HTTPNode{URL: b.URL}})
+	c.OnFailure = func(shard int, err error) {
+		failed = append(failed, shard)
+	}
+	if keys := collect(c, "golang"); fmt.Sprint(keys) != "[doc:1 doc:2]" {
+		t.Fatalf("expected [doc:1 doc:2], got %v", keys)
+	}
+	if fmt.Sprint(failed) != "[1]" {
+		t.Fatalf("expected shard 1 to fail, got %v", failed)
+	}
+}
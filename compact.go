@@ -0,0 +1,157 @@
+package fulltext
+
+// Compact merges the shards holding fewer than targetShardRows rows, such as the small shards added by Append and
+// Update, into new shards of targetShardRows rows, restoring the shard sizes lookups are tuned for. The words of the
+// merged rows are read from source, rows missing from source or deleted are dropped, and stored payloads, facets,
+// tokens, term frequencies, weights and term payloads are carried over. The merged shards are built with the options
+// of the last shard, see Update.
+// Compact advances the index generation, replicas must reload the compacted index instead of applying deltas.
+// Compact is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) Compact(targetShardRows int, source RowSource) error {
+	if targetShardRows <= 0 {
+		return &OptsError{Field: "TargetShardRows", Err: ErrOutOfRange}
+	}
+	var keep []index
+	var small []int
+	for curr := range i.private {
+		if i.private[curr].Rows >= uint64(targetShardRows) {
+			keep = append(keep, i.private[curr])
+		} else {
+			small = append(small, curr)
+		}
+	}
+	if len(small) < 2 {
+		return nil
+	}
+	// live maps the keys of the merged rows to their latest undeleted row
+	live := make(map[string]row)
+	for _, curr := range small {
+		for pos := uint64(1); pos <= i.private[curr].Rows; pos++ {
+			if !i.deletedAt(curr, pos) {
+				live[i.private[curr].key(pos)] = row{curr, pos}
+			}
+		}
+	}
+	rows := make(map[string]BagOfWords, len(live))
+	source(func(pk string, words BagOfWords) bool {
+		if _, ok := live[pk]; ok {
+			rows[pk] = words
+		}
+		return true
+	})
+	opts := i.opts()
+	opts.TargetShardRows = targetShardRows
+	opts.Payload = func(pk string) []byte {
+		r := live[pk]
+		return i.private[r.shard].payload(r.pos)
+	}
+	opts.Tokens = func(pk string) []string {
+		r := live[pk]
+		if p := &i.private[r.shard]; r.pos <= uint64(len(p.Tokens)) {
+			return p.Tokens[r.pos-1]
+		}
+		return nil
+	}
+	opts.Facets = func(pk string) map[string]string {
+		r := live[pk]
+		values := make(map[string]string)
+		for name := range i.private[r.shard].Facets {
+			if value := i.private[r.shard].facet(name, r.pos); value != "" {
+				values[name] = value
+			}
+		}
+		return values
+	}
+	if err := i.carryWords(opts, small, live, rows); err != nil {
+		return err
+	}
+	merged, err := New(opts, rows, nil)
+	if err != nil {
+		return err
+	}
+	// the tombstones of the merged shards move to the last shard, as they may hide rows of the kept shards
+	deleted := make(map[string]uint64)
+	for _, curr := range small {
+		for pk, generation := range i.private[curr].Deleted {
+			deleted[pk] = max(deleted[pk], generation)
+		}
+	}
+	generation := i.generation + 1
+	for curr := range merged.private {
+		merged.private[curr].Generation = generation
+	}
+	i.private = append(keep, merged.private...)
+	if len(deleted) > 0 {
+		p := &i.private[len(i.private)-1]
+		if p.Deleted == nil {
+			p.Deleted = make(map[string]uint64, len(deleted))
+		}
+		for pk, generation := range deleted {
+			p.Deleted[pk] = max(p.Deleted[pk], generation)
+		}
+		p.Checksum = 0
+	}
+	i.retrack()
+	i.generation = generation
+	if i.cache != nil {
+		i.cache.purge()
+	}
+	return nil
+}
+
+// carryWords sets the Frequencies, Weights and TermPayloads of opts to carry the values of the words of the live rows
+// over from the small shards, when any of them recorded such values
+func (i *Index) carryWords(opts *NewOpts, small []int, live map[string]row, rows map[string]BagOfWords) error {
+	var frequencies, weights, termPayloads bool
+	for _, curr := range small {
+		p := &i.private[curr]
+		frequencies = frequencies || len(p.Frequencies) >= 2
+		weights = weights || len(p.Weights) >= 2
+		termPayloads = termPayloads || len(p.TermPayloads) >= 2
+	}
+	if !frequencies && !weights && !termPayloads {
+		return nil
+	}
+	normalize, err := opts.normalizer()
+	if err != nil {
+		return err
+	}
+	words := func(pk string) BagOfWords {
+		if normalize == nil {
+			return rows[pk]
+		}
+		return normalize(rows[pk])
+	}
+	opts.normalizedWords = true
+	if frequencies {
+		opts.Frequencies = func(pk string) map[string]int {
+			r := live[pk]
+			values := make(map[string]int)
+			for word := range words(pk) {
+				values[word] = i.private[r.shard].frequency(r.pos, word)
+			}
+			return values
+		}
+	}
+	if weights {
+		opts.Weights = func(pk string) map[string]float32 {
+			r := live[pk]
+			values := make(map[string]float32)
+			for word := range words(pk) {
+				values[word] = float32(i.private[r.shard].weight(r.pos, word))
+			}
+			return values
+		}
+	}
+	if termPayloads {
+		opts.TermPayloads = func(pk string) map[string]uint16 {
+			r := live[pk]
+			values := make(map[string]uint16)
+			for word := range words(pk) {
+				values[word] = i.private[r.shard].termPayload(r.pos, word)
+			}
+			return values
+		}
+	}
+	return nil
+}
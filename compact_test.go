@@ -0,0 +1,122 @@
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestCompact tests merging the small shards of appends and updates, keeping deletions, updates and payloads
+func TestCompact(t *testing.T) {
+	store := map[string]BagOfWords{}
+	opts := NewDefaultOpts()
+	opts.Payload = func(pk string) []byte { return []byte("payload of " + pk) }
+	names := []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel"}
+	for n, name := range names {
+		store[fmt.Sprintf("doc:%d", n)] = BagOfWords{"common": {}, name: {}}
+	}
+	idx := new(Index)
+	for n := 0; n < 8; n++ {
+		pk := fmt.Sprintf("doc:%d", n)
+		small, err := New(opts, map[string]BagOfWords{pk: store[pk]}, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		idx.Append(small)
+	}
+	idx.Delete("doc:3")
+	delete(store, "doc:3")
+	store["doc:5"] = BagOfWords{"common": {}, "updated": {}}
+	if err := idx.Update("doc:5", store["doc:5"]); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	source := func(yield func(string, BagOfWords) bool) {
+		for pk, words := range store {
+			if !yield(pk, words) {
+				return
+			}
+		}
+	}
+	if err := idx.Compact(0, source); !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+	shards := len(idx.private)
+	if err := idx.Compact(4, source); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(idx.private) >= shards {
+		t.Fatalf("expected fewer than %d shards, got %d", shards, len(idx.private))
+	}
+	if keys := lookupAll(idx, "common"); len(keys) != 7 {
+		t.Fatalf("expected 7 rows, got %v", keys)
+	}
+	if keys := lookupAll(idx, "delta"); len(keys) != 0 {
+		t.Fatalf("expected deleted row to stay deleted, got %v", keys)
+	}
+	if keys := lookupAll(idx, "foxtrot"); len(keys) != 0 {
+		t.Fatalf("expected stale word to stop matching, got %v", keys)
+	}
+	if keys := lookupAll(idx, "updated"); len(keys) != 1 || keys[0] != "doc:5" {
+		t.Fatalf("expected [doc:5], got %v", keys)
+	}
+	var payloads []string
+	for pk, payload := range idx.LookupWithPayload("hotel", true, true) {
+		payloads = append(payloads, pk+": "+string(payload))
+	}
+	if len(payloads) != 1 || payloads[0] != "doc:7: payload of doc:7" {
+		t.Fatalf("expected the payload of doc:7, got %q", payloads)
+	}
+	if err := idx.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+// TestCompactWordValues tests that term frequencies, weights and term payloads of merged rows are carried over
+func TestCompactWordValues(t *testing.T) {
+	store := map[string]BagOfWords{}
+	opts := NewDefaultOpts()
+	opts.ASCIIFold = true
+	opts.Frequencies = func(pk string) map[string]int { return map[string]int{"común": len(pk)} }
+	opts.Weights = func(pk string) map[string]float32 { return map[string]float32{"común": 2.5} }
+	opts.TermPayloads = func(pk string) map[string]uint16 { return map[string]uint16{"común": 0b101} }
+	idx := new(Index)
+	for n := 0; n < 6; n++ {
+		pk := fmt.Sprintf("doc:%d", n)
+		store[pk] = BagOfWords{"común": {}, fmt.Sprintf("word%d", n): {}}
+		small, err := New(opts, map[string]BagOfWords{pk: store[pk]}, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		idx.Append(small)
+	}
+	source := func(yield func(string, BagOfWords) bool) {
+		for pk, words := range store {
+			if !yield(pk, words) {
+				return
+			}
+		}
+	}
+	if err := idx.Compact(4, source); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var rows int
+	for pk, frequency := range idx.LookupFrequency("comun") {
+		if frequency != len(pk) {
+			t.Fatalf("expected frequency %d of %s, got %d", len(pk), pk, frequency)
+		}
+		rows++
+	}
+	for pk, weight := range idx.LookupWeighted("comun") {
+		if weight != 2.5 {
+			t.Fatalf("expected weight 2.5 of %s, got %v", pk, weight)
+		}
+	}
+	for pk, payload := range idx.LookupTermPayload("comun") {
+		if payload != 0b101 {
+			t.Fatalf("expected term payload 0b101 of %s, got %b", pk, payload)
+		}
+	}
+	if rows != 6 {
+		t.Fatalf("expected 6 rows, got %d", rows)
+	}
+}
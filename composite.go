@@ -0,0 +1,53 @@
+package fulltext
+
+import "encoding/binary"
+import "fmt"
+
+var ErrMalformedKey = fmt.Errorf("malformed_composite_key")
+
+// keyEnd terminates every encoded composite key. No uvarint length is this single byte, and keys ending with it are
+// never ambiguous to pad on the right with another byte.
+const keyEnd = 0xff
+
+// EncodeKey encodes the tuple primary key of a row, such as (tenant, id), into the canonical primary key string the
+// index stores, every part prefixed by its length. Equal tuples encode to equal strings. Encoded keys vary in size
+// with their parts, build them with NewOpts.KeyPadding, such as &KeyPadding{} padding them with zeros on the right.
+func EncodeKey(tuple ...string) string {
+	var buf []byte
+	for _, part := range tuple {
+		buf = binary.AppendUvarint(buf, uint64(len(part)))
+		buf = append(buf, part...)
+	}
+	return string(append(buf, keyEnd))
+}
+
+// DecodeKey decodes a primary key encoded by EncodeKey into its tuple, ErrMalformedKey is returned for other keys.
+func DecodeKey(primaryKey string) ([]string, error) {
+	var tuple = []string{}
+	for rest := primaryKey; ; {
+		if rest == string([]byte{keyEnd}) {
+			return tuple, nil
+		}
+		n, size := binary.Uvarint([]byte(rest))
+		if size <= 0 || n > uint64(len(rest)-size) {
+			return nil, ErrMalformedKey
+		}
+		tuple = append(tuple, rest[size:size+int(n)])
+		rest = rest[size+int(n):]
+	}
+}
+
+// LookupTuple is Lookup yielding the decoded tuples of rows keyed by EncodeKey, skipping rows with other keys.
+func (i *Index) LookupTuple(word string, exact, dedup bool) func(yield func(tuple []string) bool) {
+	return func(yield func([]string) bool) {
+		for pk := range i.Lookup(word, exact, dedup) {
+			tuple, err := DecodeKey(pk)
+			if err != nil {
+				continue
+			}
+			if !yield(tuple) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// TestCompositeKeys tests that tuple keys of varying sizes round trip through the index
+func TestCompositeKeys(t *testing.T) {
+	for _, tuple := range [][]string{{}, {""}, {"acme", "42"}, {"a\xff", "", string(make([]byte, 300))}} {
+		decoded, err := DecodeKey(EncodeKey(tuple...))
+		if err != nil || !slices.Equal(decoded, tuple) {
+			t.Fatalf("expected %q, got %q and %v", tuple, decoded, err)
+		}
+	}
+	for _, pk := range []string{"", "doc:1", "\x05ab\xff", EncodeKey("x") + "\x00"} {
+		if _, err := DecodeKey(pk); !errors.Is(err, ErrMalformedKey) {
+			t.Fatalf("expected ErrMalformedKey for %q, got %v", pk, err)
+		}
+	}
+	opts := NewDefaultOpts()
+	opts.KeyPadding = &KeyPadding{}
+	idx, err := New(opts, map[string][]string{
+		EncodeKey("acme", "1"):        {"golang", "backend"},
+		EncodeKey("acme", "22"):       {"rust"},
+		EncodeKey("initech", "33333"): {"backend"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var tuples []string
+	for tuple := range idx.LookupTuple("backend", true, true) {
+		tuples = append(tuples, fmt.Sprint(tuple))
+	}
+	slices.Sort(tuples)
+	if want := []string{"[acme 1]", "[initech 33333]"}; !slices.Equal(tuples, want) {
+		t.Fatalf("expected %v, got %v", want, tuples)
+	}
+}
@@ -0,0 +1,51 @@
+package fulltext
+
+// CostEstimate is the predicted work of a lookup, see EstimateCost
+type CostEstimate struct {
+	// Shards is the number of shards the lookup probes, BucketProbes the number of shingles it probes in their buckets
+	Shards       int
+	BucketProbes int
+	// Candidates is the number of row positions the filters hold for the probed shingles, each resolved by the lookup
+	Candidates uint64
+}
+
+// EstimateCost predicts the work of Lookup(word, exact, false) without running it, so query routers can reject or
+// reroute expensive queries, such as short subword lookups over a huge corpus. Shards are routed as by the lookup,
+// and the shingle counts are read from the filters without resolving any row, a fraction of the cost of the lookup.
+func (i *Index) EstimateCost(word string, exact bool) (c CostEstimate) {
+	var words = []string{word}
+	if len(i.middleware) > 0 {
+		words = i.rewrite(word)
+	}
+	for _, word := range words {
+		for curr := range i.private {
+			p := &i.private[curr]
+			minWord := p.minWord()
+			query := p.query(word)
+			if len(query) < minWord || p.Rows == 0 || !p.routable(query, minWord, exact, false) {
+				continue
+			}
+			c.Shards++
+			stride := p.stride()
+			for bucket := min(p.Maxword-minWord, len(p.Buckets)-1); bucket >= 0; bucket-- {
+				if bucket%stride != 0 {
+					continue
+				}
+				first, last := len(query)-minWord, 0
+				if exact {
+					if bucket > first {
+						continue
+					}
+					first, last = bucket, bucket
+				}
+				for t := first; t >= last; t-- {
+					c.BucketProbes++
+					if count := p.count(bucket, query[t:t+minWord]); count <= p.Rows {
+						c.Candidates += count
+					}
+				}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,39 @@
+package fulltext
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestEstimateCost tests that the estimate matches the candidates of a lookup and ranks subword lookups costlier
+func TestEstimateCost(t *testing.T) {
+	data := make(map[string][]string)
+	for n := 0; n < 500; n++ {
+		data[fmt.Sprintf("doc:%04d", n)] = []string{"alpha", fmt.Sprintf("bravo%d", n)}
+	}
+	idx, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var candidates uint64
+	for range idx.Lookup("alpha", true, false) {
+		candidates++
+	}
+	var shards int
+	for _, p := range idx.private {
+		if p.Rows > 0 {
+			shards++
+		}
+	}
+	exact := idx.EstimateCost("alpha", true)
+	if exact.Shards != shards || exact.Candidates != candidates {
+		t.Fatalf("expected %d candidates in %d shards, got %+v", candidates, shards, exact)
+	}
+	subword := idx.EstimateCost("bra", false)
+	if subword.BucketProbes <= exact.BucketProbes || subword.Candidates < 500 {
+		t.Fatalf("expected a subword lookup costlier than %+v, got %+v", exact, subword)
+	}
+	if c := idx.EstimateCost("al", true); c != (CostEstimate{}) {
+		t.Fatalf("expected no cost of a word shorter than a shingle, got %+v", c)
+	}
+}
@@ -0,0 +1,310 @@
+package fulltext
+
+import "bytes"
+import "encoding/json"
+import "fmt"
+
+var ErrDeltaGap = fmt.Errorf("delta_generation_gap")
+
+// Generation returns the generation of the index, which Append, Delete and ApplyDelta advance
+func (i *Index) Generation() uint64 {
+	return i.generation
+}
+
+// Delete hides the row with primaryKey from lookups in all shards present so far. Rows appended later are not affected.
+// The tombstone is stored in every shard holding the row. The first Delete or Update decodes the keys of every shard
+// into a key table, later ones look the row up per shard.
+// Delete is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) Delete(primaryKey string) {
+	i.generation++
+	i.tombstone(primaryKey, i.generation)
+	if i.cache != nil {
+		i.cache.purge()
+	}
+}
+
+// Update replaces the words of the row with primaryKey, deleting the row and appending a shard holding its new words,
+// so stale words stop matching and new words match at once without a rebuild. The shard is built with the options
+// of the last shard, every update adds a shard, merged again by Compact. The stored payload and facets of the row are
+// carried over. Its tokens, term frequencies, weights and term payloads describe the replaced words and are dropped,
+// so the updated row is not found by LookupNear and yields the defaults of rows without them, and Terms keeps counting
+// the replaced words until the index is rebuilt.
+// Update is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) Update(primaryKey string, newWords BagOfWords) error {
+	var overlay *Index
+	if len(newWords) > 0 {
+		opts := i.opts()
+		if r, ok := i.live(primaryKey); ok {
+			p := &i.private[r.shard]
+			if payload := p.payload(r.pos); payload != nil {
+				opts.Payload = func(string) []byte { return payload }
+			}
+			if len(p.Facets) > 0 {
+				values := make(map[string]string)
+				for name := range p.Facets {
+					if value := p.facet(name, r.pos); value != "" {
+						values[name] = value
+					}
+				}
+				opts.Facets = func(string) map[string]string { return values }
+			}
+		}
+		var err error
+		if overlay, err = New(opts, map[string]BagOfWords{primaryKey: newWords}, nil); err != nil {
+			return err
+		}
+	}
+	i.Delete(primaryKey)
+	if overlay != nil {
+		i.Append(overlay)
+	}
+	return nil
+}
+
+// opts returns build options reproducing the word transformations of the last shard holding rows
+func (i *Index) opts() *NewOpts {
+	for curr := len(i.private) - 1; curr >= 0; curr-- {
+		if p := &i.private[curr]; p.Rows > 0 {
+			return p.opts()
+		}
+	}
+	return NewDefaultOpts()
+}
+
+// opts returns build options reproducing the word transformations of the shard, and the other options of its build
+// recorded in its stats, such as StoreTerms and the false positive settings. The callbacks are not set.
+func (p *index) opts() *NewOpts {
+	opts := NewDefaultOpts()
+	var stats BuildStats
+	if len(p.Stats) > 0 && json.Unmarshal(p.Stats, &stats) == nil {
+		opts = stats.Opts.NewOpts()
+		opts.TargetShardRows = 0
+		opts.ShardBuildBudget = 0
+		opts.RandomHashSeed = false
+		opts.CheckpointDir = ""
+	}
+	opts.MinWordLength = byte(p.minWord())
+	opts.MaxWordLength = p.Truncate
+	opts.ASCIIFold = p.Fold
+	opts.Analyzer = p.Analyzer
+	opts.HashSeed = p.Seed
+	opts.BuildID = p.BuildID
+	opts.ShingleStride = p.Stride
+	opts.MaxBucketDepth = p.Depth
+	opts.KeyPadding = keyPadding(p.Padding)
+	opts.HashInputs = len(p.InputHash) > 0
+	opts.IndexUnstemmed = p.Unstemmed
+	opts.DualCase = p.DualCase
+	if p.Backend == mapBackendName {
+		opts.MapShardRows = int(p.Rows)
+	} else {
+		opts.FilterBackend = p.Backend
+	}
+	return opts
+}
+
+// tombstone records the deletion of primaryKey at generation in every shard holding the key, or in the last shard when
+// none does, so it is serialized with the index and kept by loads of any subset of the shards holding the row
+func (i *Index) tombstone(primaryKey string, generation uint64) {
+	if len(i.private) == 0 {
+		i.private = append(i.private, index{Version: 3, Pk: quaternaryBackend{}.New(nil, 0, 0)})
+	}
+	var recorded bool
+	for curr := range i.private {
+		if p := &i.private[curr]; p.Generation <= generation && p.find(primaryKey) > 0 {
+			p.addTombstone(primaryKey, generation)
+			i.track(p)
+			recorded = true
+		}
+	}
+	if !recorded {
+		p := &i.private[len(i.private)-1]
+		p.addTombstone(primaryKey, generation)
+		i.track(p)
+	}
+}
+
+// find returns the position of the first row with primaryKey in the shard, 0 if there is none
+func (p *index) find(primaryKey string) uint64 {
+	if p.keys == nil {
+		p.keys = make(map[string]uint64, p.Rows)
+		for j := p.Rows; j >= 1; j-- {
+			p.keys[p.key(j)] = j
+		}
+	}
+	return p.keys[primaryKey]
+}
+
+// live returns the latest row with primaryKey not deleted, ok is false if there is none
+func (i *Index) live(primaryKey string) (r row, ok bool) {
+	for curr := len(i.private) - 1; curr >= 0; curr-- {
+		if pos := i.private[curr].find(primaryKey); pos > 0 && !i.deletedAt(curr, pos) {
+			return row{curr, pos}, true
+		}
+	}
+	return row{}, false
+}
+
+// Keys iterates the primary keys of the rows not deleted, shard by shard. Keys appended again without deleting their
+// older rows are yielded once per row.
+func (i *Index) Keys() func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		for curr := range i.private {
+			p := &i.private[curr]
+			for pos := uint64(1); pos <= p.Rows; pos++ {
+				if !i.deletedAt(curr, pos) && !yield(p.key(pos)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// addTombstone records the deletion of primaryKey at generation in the shard
+func (p *index) addTombstone(primaryKey string, generation uint64) {
+	if p.Deleted == nil {
+		p.Deleted = make(map[string]uint64)
+	}
+	p.Deleted[primaryKey] = max(p.Deleted[primaryKey], generation)
+	p.Checksum = 0
+}
+
+// track updates the generation and the tombstones of the index from shard p
+func (i *Index) track(p *index) {
+	i.generation = max(i.generation, p.Generation)
+	for pk, generation := range p.Deleted {
+		if i.deleted == nil {
+			i.deleted = make(map[string]uint64)
+		}
+		i.deleted[pk] = max(i.deleted[pk], generation)
+		i.generation = max(i.generation, generation)
+	}
+}
+
+// retrack rebuilds the generation and the tombstones of the index from all shards
+func (i *Index) retrack() {
+	i.generation = 0
+	i.deleted = nil
+	for curr := range i.private {
+		i.track(&i.private[curr])
+	}
+}
+
+// deletedAt reports whether row pos of shard was deleted after the shard was added
+func (i *Index) deletedAt(shard int, pos uint64) bool {
+	generation, ok := i.deleted[i.private[shard].key(pos)]
+	return ok && generation >= i.private[shard].Generation
+}
+
+// WithVisibility hides the rows whose primary key visible rejects from every lookup, such as keys set in a deleted-keys
+// bitmap, as a lighter weight alternative to Delete needing neither tombstones nor rebuilds. Visible is called from
+// the goroutine iterating the lookup. Result sets are cached before visible hides rows and filtered when served, so
+// rows become visible again as soon as visible accepts them. Nil shows all rows.
+// WithVisibility is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) WithVisibility(visible func(primaryKey string) bool) *Index {
+	i.visible = visible
+	return i
+}
+
+// DiffSince returns the shards and tombstones added after generation, in the protobuf wire format.
+// A replica at generation or later catches up by passing the delta to ApplyDelta instead of reloading the full index.
+func (i *Index) DiffSince(generation uint64) ([]byte, error) {
+	var buf []byte
+	buf = appendProtoVarint(buf, 1, generation)
+	buf = appendProtoVarint(buf, 2, i.generation)
+	for _, p := range i.private {
+		if p.Generation <= generation {
+			continue
+		}
+		p.Deleted = nil // shipped as tombstones below
+		p.Checksum = p.checksum()
+		buf = appendProtoBytes(buf, 3, p.appendProto(nil))
+	}
+	for _, pk := range sortedTerms(i.deleted) {
+		if i.deleted[pk] > generation {
+			buf = appendProtoBytes(buf, 4, appendTombstone(nil, pk, i.deleted[pk]))
+		}
+	}
+	return buf, nil
+}
+
+// ApplyDelta applies a delta produced by DiffSince, skipping the parts the index already has.
+// ErrDeltaGap is returned when the delta starts after the generation of the index.
+// ApplyDelta is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) ApplyDelta(data []byte) error {
+	if err := i.bounds().size(len(data)); err != nil {
+		return err
+	}
+	data = bytes.Clone(data) // shards keep subslices of the buffer
+	var since, generation uint64
+	var shards []index
+	var deleted = make(map[string]uint64)
+	err := walkProto(data, func(field, wire uint64, num uint64, raw []byte) error {
+		switch field {
+		case 1:
+			since = num
+		case 2:
+			generation = num
+		case 3:
+			var p index
+			if err := p.unmarshalProto(raw); err != nil {
+				return err
+			}
+			shards = append(shards, p)
+		case 4:
+			pk, generation, err := parseTombstone(raw)
+			if err != nil {
+				return err
+			}
+			deleted[pk] = generation
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if since > i.generation {
+		return ErrDeltaGap
+	}
+	delta := Index{private: shards, limits: i.limits}
+	if err := delta.loaded(); err != nil {
+		return err
+	}
+	current := i.generation
+	for _, p := range shards {
+		if p.Generation > current {
+			i.private = append(i.private, p)
+		}
+	}
+	for _, pk := range sortedTerms(deleted) {
+		if deleted[pk] > current {
+			i.tombstone(pk, deleted[pk])
+		}
+	}
+	i.retrack()
+	i.generation = max(i.generation, generation)
+	if i.cache != nil {
+		i.cache.purge()
+	}
+	return nil
+}
+
+// appendTombstone encodes a Tombstone message of fulltext.proto
+func appendTombstone(buf []byte, pk string, generation uint64) []byte {
+	buf = appendProtoBytes(buf, 1, []byte(pk))
+	return appendProtoVarint(buf, 2, generation)
+}
+
+// parseTombstone decodes a Tombstone message of fulltext.proto
+func parseTombstone(raw []byte) (pk string, generation uint64, err error) {
+	err = walkProto(raw, func(field, wire uint64, num uint64, raw []byte) error {
+		switch field {
+		case 1:
+			pk = string(raw)
+		case 2:
+			generation = num
+		}
+		return nil
+	})
+	return
+}
@@ -0,0 +1,237 @@
+package fulltext
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+func lookupAll(idx *Index, word string) (keys []string) {
+	for pk := range idx.Lookup(word, true, true) {
+		keys = append(keys, pk)
+	}
+	sort.Strings(keys)
+	return
+}
+
+// TestDelete tests that deleted keys are hidden, also from Keys and after a serialization round trip
+func TestDelete(t *testing.T) {
+	idx := newTestIndex(t)
+	idx.Delete("doc:1")
+	if keys := lookupAll(idx, "backend"); len(keys) != 1 || keys[0] != "doc:2" {
+		t.Fatalf("expected [doc:2], got %v", keys)
+	}
+	var keys []string
+	for pk := range idx.Keys() {
+		keys = append(keys, pk)
+	}
+	if sort.Strings(keys); len(keys) != 2 || keys[0] != "doc:2" || keys[1] != "doc:3" {
+		t.Fatalf("expected [doc:2 doc:3], got %v", keys)
+	}
+	if err := idx.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data, _ := idx.SerializeProto()
+	var loaded Index
+	if err := loaded.DeserializeProto(data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keys := lookupAll(&loaded, "backend"); len(keys) != 1 || loaded.Generation() != idx.Generation() {
+		t.Fatalf("expected [doc:2] at generation %d, got %v at %d", idx.Generation(), keys, loaded.Generation())
+	}
+}
+
+// TestDeletePartialLoad tests that loading only the shard holding a deleted row keeps it hidden
+func TestDeletePartialLoad(t *testing.T) {
+	idx, err := New(nil, map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+		"doc:3": {"python", "scripting"},
+		"doc:4": {"java", "backend"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.Delete("doc:2")
+	data, err := idx.SerializeSharded()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for curr := range idx.private {
+		if idx.private[curr].find("doc:2") == 0 {
+			continue
+		}
+		if curr == len(idx.private)-1 {
+			t.Fatalf("expected doc:2 outside the last shard")
+		}
+		var part Index
+		if err := part.DeserializeShards(data, []int{curr}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for _, pk := range lookupAll(&part, "backend") {
+			if pk == "doc:2" {
+				t.Fatalf("expected doc:2 hidden in shard %d loaded alone", curr)
+			}
+		}
+		return
+	}
+	t.Fatalf("expected a shard holding doc:2")
+}
+
+// TestDeleteKeyTable tests that the key table of every shard finds the first row of each key, also in appended shards
+func TestDeleteKeyTable(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	idx.Delete("doc:000")
+	more, err := New(nil, map[string][]string{"doc:001": {"changed"}, "doc:100": {"added"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.Append(more)
+	idx.Delete("doc:001")
+	for curr := range idx.private {
+		p := &idx.private[curr]
+		for pos := uint64(1); pos <= p.Rows; pos++ {
+			if found := p.find(p.key(pos)); found == 0 || found > pos {
+				t.Fatalf("expected row %d of shard %d found, got %d", pos, curr, found)
+			}
+		}
+		if p.find("doc:999") != 0 {
+			t.Fatalf("expected doc:999 missing from shard %d", curr)
+		}
+	}
+	if keys := lookupAll(idx, "changed"); len(keys) != 0 {
+		t.Fatalf("expected the appended doc:001 deleted, got %v", keys)
+	}
+	if keys := lookupAll(idx, "added"); len(keys) != 1 {
+		t.Fatalf("expected [doc:100], got %v", keys)
+	}
+}
+
+// TestUpdate tests that updated rows match their new words only
+func TestUpdate(t *testing.T) {
+	idx := newTestIndex(t)
+	if err := idx.Update("doc:1", BagOfWords{"haskell": {}, "functional": {}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keys := lookupAll(idx, "golang"); len(keys) != 0 {
+		t.Fatalf("expected stale word to stop matching, got %v", keys)
+	}
+	if keys := lookupAll(idx, "haskell"); len(keys) != 1 || keys[0] != "doc:1" {
+		t.Fatalf("expected [doc:1], got %v", keys)
+	}
+	if err := idx.Update("doc:1", BagOfWords{"golang": {}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keys := lookupAll(idx, "haskell"); len(keys) != 0 {
+		t.Fatalf("expected stale word to stop matching, got %v", keys)
+	}
+	if keys := lookupAll(idx, "golang"); len(keys) != 1 || keys[0] != "doc:1" {
+		t.Fatalf("expected [doc:1], got %v", keys)
+	}
+}
+
+// TestUpdateCarriesRowData tests that updated rows keep their payload and facets and the build options of the index,
+// and drop the data describing their replaced words
+func TestUpdateCarriesRowData(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+	}
+	opts := NewDefaultOpts()
+	opts.StoreTerms = true
+	opts.Payload = func(pk string) []byte { return []byte("payload of " + pk) }
+	opts.Facets = func(pk string) map[string]string { return map[string]string{"lang": data[pk][0]} }
+	opts.Tokens = func(pk string) []string { return data[pk] }
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := idx.Update("doc:1", BagOfWords{"haskell": {}, "backend": {}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for pk, payload := range idx.LookupWithPayload("haskell", true, true) {
+		if pk != "doc:1" || string(payload) != "payload of doc:1" {
+			t.Fatalf("expected the payload of doc:1, got %q for %s", payload, pk)
+		}
+	}
+	if _, counts := idx.LookupFaceted("haskell", true); counts["lang"]["golang"] != 1 {
+		t.Fatalf("expected the facet of doc:1 carried over, got %v", counts)
+	}
+	terms := make(map[string]uint64)
+	for term, count := range idx.Terms() {
+		terms[term] = count
+	}
+	if terms["haskell"] != 1 || terms["golang"] != 1 {
+		t.Fatalf("expected haskell counted and golang still counted, got %v", terms)
+	}
+	for pk := range idx.LookupNear("haskell", "backend", 1) {
+		t.Fatalf("expected no tokens for the updated row, got %s", pk)
+	}
+}
+
+// TestWithVisibility tests that keys rejected by the visibility predicate are hidden, also from cached result sets
+func TestWithVisibility(t *testing.T) {
+	idx := newTestIndex(t).WithCache(16)
+	deleted := map[string]bool{}
+	idx.WithVisibility(func(pk string) bool { return !deleted[pk] })
+	if keys := lookupAll(idx, "backend"); len(keys) != 2 {
+		t.Fatalf("expected [doc:1 doc:2], got %v", keys)
+	}
+	deleted["doc:1"] = true
+	if keys := lookupAll(idx, "backend"); len(keys) != 1 || keys[0] != "doc:2" {
+		t.Fatalf("expected [doc:2], got %v", keys)
+	}
+	deleted["doc:1"] = false
+	if keys := lookupAll(idx, "backend"); len(keys) != 2 {
+		t.Fatalf("expected doc:1 visible again from the cache, got %v", keys)
+	}
+	deleted["doc:2"] = true
+	if keys := lookupAll(idx, "backend"); len(keys) != 1 || keys[0] != "doc:1" {
+		t.Fatalf("expected [doc:1], got %v", keys)
+	}
+	if keys := lookupAll(idx.WithVisibility(nil), "backend"); len(keys) != 2 {
+		t.Fatalf("expected [doc:1 doc:2], got %v", keys)
+	}
+}
+
+// TestDiffSince tests syncing a replica with deltas
+func TestDiffSince(t *testing.T) {
+	primary := newTestIndex(t)
+	data, _ := primary.Serialize()
+	var replica Index
+	if err := replica.Deserialize(data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	since := replica.Generation()
+
+	more, err := New(nil, map[string][]string{"doc:4": {"backend"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	primary.Append(more)
+	primary.Delete("doc:2")
+
+	delta, err := primary.DiffSince(since)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(delta) >= len(data) {
+		t.Fatalf("expected a delta smaller than the index, got %d of %d bytes", len(delta), len(data))
+	}
+	if err := replica.ApplyDelta(delta); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keys := lookupAll(&replica, "backend"); len(keys) != 2 || keys[0] != "doc:1" || keys[1] != "doc:4" {
+		t.Fatalf("expected [doc:1 doc:4], got %v", keys)
+	}
+	if err := replica.ApplyDelta(delta); err != nil || len(replica.private) != len(primary.private) {
+		t.Fatalf("expected reapplying to be a no-op, got %v", err)
+	}
+
+	primary.Delete("doc:1")
+	primary.Delete("doc:3")
+	delta, _ = primary.DiffSince(primary.Generation() - 1)
+	if err := replica.ApplyDelta(delta); !errors.Is(err, ErrDeltaGap) {
+		t.Fatalf("expected ErrDeltaGap, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package fulltext
+
+// DropBuckets frees the buckets of the word offsets at depth and beyond in every shard, shrinking an index under
+// memory pressure. Exact lookups keep finding every matching row, subword lookups only find subwords within the first
+// depth offsets of the words, see NewOpts.MaxBucketDepth. The dropped buckets are rebuilt by building the rows again.
+// DropBuckets is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) DropBuckets(depth int) error {
+	if depth < 1 {
+		return &OptsError{Field: "MaxBucketDepth", Err: ErrOutOfRange}
+	}
+	for curr := range i.private {
+		p := &i.private[curr]
+		if p.Depth > 0 && p.Depth <= depth {
+			continue
+		}
+		p.Depth = depth
+		if len(p.Buckets) > depth {
+			clear(p.Buckets[depth:])
+			p.Buckets = p.Buckets[:depth:depth]
+		}
+		if len(p.Counts) > depth {
+			clear(p.Counts[depth:])
+			p.Counts = p.Counts[:depth:depth]
+		}
+	}
+	if i.cache != nil {
+		i.cache.purge()
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package fulltext
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestMaxBucketDepth tests that a depth limited index is smaller, keeps exact lookups and loses deep subwords
+func TestMaxBucketDepth(t *testing.T) {
+	data := make(map[string]BagOfWords)
+	for j := 0; j < 300; j++ {
+		data[fmt.Sprintf("doc:%04d", j)] = BagOfWords{fmt.Sprintf("%04dinternational", j): {}}
+	}
+	build := func(depth int) *Index {
+		opts := NewDefaultOpts()
+		opts.MaxBucketDepth = depth
+		idx, err := New(opts, data, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return idx
+	}
+	bucketBytes := func(idx *Index) (n int) {
+		for _, p := range idx.private {
+			for b := range p.Buckets {
+				n += len(p.Buckets[b]) + len(p.Counts[b])
+			}
+		}
+		return
+	}
+	lookup := func(idx *Index, word string, exact bool) (keys []string) {
+		for key := range idx.Lookup(word, exact, true) {
+			keys = append(keys, key)
+		}
+		return
+	}
+	full, limited := build(0), build(6)
+	if bucketBytes(limited)*2 > bucketBytes(full) {
+		t.Fatalf("expected limited buckets well below %d bytes, got %d", bucketBytes(full), bucketBytes(limited))
+	}
+	serialized, _ := limited.SerializeProto()
+	var loaded Index
+	if err := loaded.DeserializeProto(serialized); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	dropped := build(0)
+	if err := dropped.DropBuckets(6); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if bucketBytes(dropped)*2 > bucketBytes(full) {
+		t.Fatalf("expected dropped buckets well below %d bytes, got %d", bucketBytes(full), bucketBytes(dropped))
+	}
+	for _, idx := range []*Index{&loaded, dropped} {
+		for _, j := range []int{0, 7, 42, 299} {
+			pk := fmt.Sprintf("doc:%04d", j)
+			for _, q := range []struct {
+				word  string
+				exact bool
+			}{
+				{fmt.Sprintf("%04dinternational", j), true},
+				{fmt.Sprintf("%04din", j), false},
+			} {
+				var found bool
+				for _, key := range lookup(idx, q.word, q.exact) {
+					found = found || key == pk
+				}
+				if !found {
+					t.Errorf("expected %s for %q exact=%v", pk, q.word, q.exact)
+				}
+			}
+		}
+		if keys := lookup(idx, "national", false); len(keys) > 30 {
+			t.Errorf("expected at most a few false positive deep subword matches, got %d", len(keys))
+		}
+	}
+	if keys := lookup(full, "national", false); len(keys) != 300 {
+		t.Errorf("expected 300 deep subword matches in the full index, got %d", len(keys))
+	}
+	if err := full.DropBuckets(0); err == nil {
+		t.Errorf("expected an error for depth 0")
+	}
+}
@@ -0,0 +1,77 @@
+package fulltext
+
+import "sort"
+
+// DiffReport lists the rows findable by their words in one index but not the other, see Diff
+type DiffReport struct {
+	// Rows and Terms count the rows and distinct words of the source, Pairs the (word, row) pairs looked up
+	Rows  int
+	Terms int
+	Pairs int
+	// OnlyInA and OnlyInB hold the pairs found by only one of the indexes, Missing those found by neither,
+	// sorted by word and primary key
+	OnlyInA []DiffEntry
+	OnlyInB []DiffEntry
+	Missing []DiffEntry
+}
+
+// DiffEntry is a row whose primary key an exact lookup of Term should find
+type DiffEntry struct {
+	PrimaryKey string
+	Term       string
+}
+
+// Diff looks every word of every row of source up exactly in a and b, reporting the rows one index finds by the word
+// and the other does not, such as to validate a rebuild or a migration between format versions before swapping it in.
+// Source should hold the rows both indexes were built from. Only the rows of source are compared, so false positives,
+// which differ between builds, are not reported. Rows deleted or hidden in an index are not findable in it.
+func Diff(a, b *Index, source RowSource) (r DiffReport) {
+	terms := make(map[string]map[string]struct{})
+	counts := make(map[string]uint64)
+	source(func(pk string, words BagOfWords) bool {
+		r.Rows++
+		for word := range words {
+			if terms[word] == nil {
+				terms[word] = make(map[string]struct{})
+			}
+			terms[word][pk] = struct{}{}
+			counts[word]++
+		}
+		return true
+	})
+	r.Terms = len(terms)
+	for _, term := range sortedTerms(counts) {
+		inA, inB := findable(a, term, terms[term]), findable(b, term, terms[term])
+		keys := make([]string, 0, len(terms[term]))
+		for pk := range terms[term] {
+			keys = append(keys, pk)
+		}
+		sort.Strings(keys)
+		for _, pk := range keys {
+			r.Pairs++
+			entry := DiffEntry{PrimaryKey: pk, Term: term}
+			_, foundA := inA[pk]
+			_, foundB := inB[pk]
+			switch {
+			case foundA && !foundB:
+				r.OnlyInA = append(r.OnlyInA, entry)
+			case !foundA && foundB:
+				r.OnlyInB = append(r.OnlyInB, entry)
+			case !foundA && !foundB:
+				r.Missing = append(r.Missing, entry)
+			}
+		}
+	}
+	return r
+}
+
+// findable returns the keys of rows an exact deduplicated lookup of term finds in i
+func findable(i *Index, term string, rows map[string]struct{}) map[string]struct{} {
+	found := make(map[string]struct{})
+	for pk := range i.Lookup(term, true, true) {
+		if _, ok := rows[pk]; ok {
+			found[pk] = struct{}{}
+		}
+	}
+	return found
+}
@@ -0,0 +1,46 @@
+package fulltext
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestDiff tests that rows findable by a word in only one of two builds of the same rows are reported
+func TestDiff(t *testing.T) {
+	data := make(map[string]BagOfWords)
+	for n := 0; n < 100; n++ {
+		data[fmt.Sprintf("doc:%02d", n)] = BagOfWords{"common": {}, fmt.Sprintf("word%02d", n): {}}
+	}
+	a, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	b, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	source := func(yield func(string, BagOfWords) bool) {
+		for pk, words := range data {
+			if !yield(pk, words) {
+				return
+			}
+		}
+	}
+	r := Diff(a, b, source)
+	if r.Rows != 100 || r.Terms != 101 || r.Pairs != 200 {
+		t.Fatalf("expected 100 rows, 101 terms and 200 pairs, got %+v", r)
+	}
+	if len(r.OnlyInA) != 0 || len(r.OnlyInB) != 0 || len(r.Missing) != 0 {
+		t.Fatalf("expected no differences between rebuilds, got %+v", r)
+	}
+	b.Delete("doc:07")
+	r = Diff(a, b, source)
+	want := []DiffEntry{{"doc:07", "common"}, {"doc:07", "word07"}}
+	if fmt.Sprint(r.OnlyInA) != fmt.Sprint(want) || len(r.OnlyInB) != 0 {
+		t.Fatalf("expected %v only in a, got %+v", want, r)
+	}
+	r = Diff(b, b, source)
+	if fmt.Sprint(r.Missing) != fmt.Sprint(want) {
+		t.Fatalf("expected %v missing, got %+v", want, r)
+	}
+}
@@ -0,0 +1,62 @@
+package fulltext
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// TestDualCase tests case insensitive and case sensitive lookups of one index, also after serialization
+func TestDualCase(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"GoLang"},
+		"doc:2": {"golang"},
+		"doc:3": {"GOLANG", "Backend"},
+	}
+	opts := NewDefaultOpts()
+	opts.DualCase = true
+	opts.StoreTerms = true
+	built, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf, err := built.SerializeProto()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var idx Index
+	if err := idx.DeserializeProto(buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	plain, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, tc := range []struct {
+		idx       *Index
+		word      string
+		sensitive bool
+		want      string
+	}{
+		{&idx, "golang", false, "[doc:1 doc:2 doc:3]"},
+		{&idx, "GOLANG", false, "[doc:1 doc:2 doc:3]"},
+		{&idx, "GoLang", true, "[doc:1]"},
+		{&idx, "GOLANG", true, "[doc:3]"},
+		{&idx, "backend", true, "[]"},
+		{plain, "golang", true, "[doc:2]"},
+	} {
+		keys := []string{}
+		for pk := range tc.idx.LookupWith(tc.word, &LookupOpts{Exact: true, GlobalDedup: true, CaseSensitive: tc.sensitive}) {
+			keys = append(keys, pk)
+		}
+		sort.Strings(keys)
+		if got := fmt.Sprint(keys); got != tc.want {
+			t.Fatalf("expected %s looking up %q case sensitive %v, got %s", tc.want, tc.word, tc.sensitive, got)
+		}
+	}
+	for term := range idx.Terms() {
+		if marked(term) {
+			t.Fatalf("expected no marked terms, got %q", term)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package fulltext
+
+import "fmt"
+
+var ErrDuplicateKey = fmt.Errorf("duplicate_key")
+
+// DuplicateKeyError names the primary key added twice under DuplicateError, it wraps ErrDuplicateKey
+type DuplicateKeyError struct {
+	PrimaryKey string
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("fulltext: primary key %q: %v", e.PrimaryKey, ErrDuplicateKey)
+}
+
+func (e *DuplicateKeyError) Unwrap() error {
+	return ErrDuplicateKey
+}
+
+// DuplicatePolicy decides what adding a row with a primary key already added does, see NewOpts.Duplicates
+type DuplicatePolicy byte
+
+const (
+	// DuplicateOverwrite replaces the words of the row added before
+	DuplicateOverwrite DuplicatePolicy = iota
+	// DuplicateSkip keeps the words of the row added before, dropping the duplicate
+	DuplicateSkip
+	// DuplicateError rejects the duplicate with a *DuplicateKeyError
+	DuplicateError
+)
+
+// duplicates returns the duplicate policy of opts, which can be nil
+func (opts *NewOpts) duplicates() DuplicatePolicy {
+	if opts == nil {
+		return DuplicateOverwrite
+	}
+	return opts.Duplicates
+}
+
+// addRow adds the row with primaryKey to rows, resolving a duplicate by the policy of opts
+func (opts *NewOpts) addRow(rows map[string]BagOfWords, primaryKey string, words BagOfWords) error {
+	_, exists := rows[primaryKey]
+	add, err := opts.resolve(primaryKey, exists)
+	if add {
+		rows[primaryKey] = words
+	}
+	return err
+}
+
+// resolve reports whether the row with primaryKey is added, resolving a row added before when exists by the policy
+// of opts
+func (opts *NewOpts) resolve(primaryKey string, exists bool) (bool, error) {
+	if !exists {
+		return true, nil
+	}
+	switch opts.duplicates() {
+	case DuplicateSkip:
+		return false, nil
+	case DuplicateError:
+		return false, &DuplicateKeyError{PrimaryKey: primaryKey}
+	}
+	return true, nil
+}
@@ -0,0 +1,110 @@
+package fulltext
+
+import quaternary "github.com/neurlang/quaternary/v1"
+import "math"
+import "math/bits"
+import "runtime"
+import "strconv"
+import "time"
+
+// EstimateReport is the predicted footprint of an index, see EstimateSize
+type EstimateReport struct {
+	Shards int
+	// PkBytes, BucketBytes and BloomBytes break MemoryBytes down by filter
+	PkBytes     uint64
+	BucketBytes uint64
+	BloomBytes  uint64
+	// MemoryBytes is the size of the filters held in memory, DiskBytes the size of SerializeProto
+	MemoryBytes uint64
+	DiskBytes   uint64
+	// BuildTime is the time New spends building filters, excluding the getter
+	BuildTime time.Duration
+}
+
+// estimateSample is the number of filter entries built to calibrate EstimateSize
+const estimateSample = 4096
+
+// estimateKeyBytes is the primary key size EstimateSize assumes
+const estimateKeyBytes = 16
+
+// EstimateSize predicts the footprint and build time of an index of rowCount rows holding avgWordsPerRow words
+// of avgWordLen bytes each, without building it. Small filters are built with opts to calibrate the prediction
+// on this machine, and primary keys are assumed to be 16 bytes. Opts can be nil.
+func EstimateSize(rowCount, avgWordsPerRow, avgWordLen int, opts *NewOpts) EstimateReport {
+	if opts == nil || opts.configured == false {
+		// defaults
+		opts = NewDefaultOpts()
+	}
+	var r EstimateReport
+	if rowCount <= 0 {
+		return r
+	}
+	shardRows := min(opts.shardRows(rowCount), rowCount)
+	r.Shards = (rowCount + shardRows - 1) / shardRows
+	logrows := byte(bits.Len64(uint64(shardRows)))
+	minWord := int(opts.MinWordLength)
+	wordLen := avgWordLen
+	if opts.MaxWordLength > 0 {
+		wordLen = min(wordLen, opts.MaxWordLength)
+	}
+
+	pkBytes, pkTime := calibratePk()
+	r.PkBytes = uint64(pkBytes * float64(rowCount))
+	var buildTime float64 = pkTime * float64(rowCount)
+
+	// every word holds a shingle per bucket offset, each row position is a bucket entry and the
+	// distinct shingles of a shard bucket are its count entries
+	entries := float64(shardRows) * float64(avgWordsPerRow)
+	distinct := math.Min(entries, math.Pow(26, float64(minWord)))
+	buckets := max(0, wordLen-minWord+1)
+	positionBytes, positionTime := calibrate(logrows, 0)
+	type calibration struct{ bytes, nanos float64 }
+	counts := make(map[byte]calibration)
+	stride := max(1, int(opts.ShingleStride))
+	for offset := 0; offset < buckets; offset += stride {
+		functions := opts.falsePositiveFunctions(offset)
+		c, ok := counts[functions]
+		if !ok {
+			c.bytes, c.nanos = calibrate(logrows, functions)
+			counts[functions] = c
+		}
+		r.BucketBytes += uint64(float64(r.Shards) * (positionBytes*entries + c.bytes*distinct))
+		buildTime += float64(r.Shards) * (positionTime*entries + c.nanos*distinct)
+	}
+	if buckets > 0 {
+		shingles := math.Min(entries*float64(buckets), math.Pow(26, float64(minWord)))
+		r.BloomBytes = uint64(float64(r.Shards) * shingles * float64(opts.BloomBitsPerShingle) / 8)
+	}
+	r.MemoryBytes = r.PkBytes + r.BucketBytes + r.BloomBytes
+	// every filter is a length delimited protobuf field, next to a few scalar fields per shard
+	filters := uint64(2 + 2*max(0, wordLen-minWord+1))
+	r.DiskBytes = r.MemoryBytes + uint64(r.Shards)*(filters*6+32)
+	r.BuildTime = time.Duration(buildTime / float64(runtime.GOMAXPROCS(0)))
+	return r
+}
+
+// calibrate returns the bytes and the nanoseconds per entry of a filter of values of bitLimit bits
+func calibrate(bitLimit, falsePositiveFunctions byte) (bytes, nanos float64) {
+	m := make(map[string]uint64, estimateSample)
+	for j := 0; j < estimateSample; j++ {
+		m[strconv.Itoa(j)] = uint64(j) & (1<<bitLimit - 1)
+	}
+	begun := time.Now()
+	f := quaternary.New(m, bitLimit, falsePositiveFunctions)
+	return float64(len(f)) / estimateSample, float64(time.Since(begun).Nanoseconds()) / estimateSample
+}
+
+// calibratePk returns the bytes and the nanoseconds per row of a primary key filter
+func calibratePk() (bytes, nanos float64) {
+	m := make(map[int]string, estimateSample)
+	for j := 1; j <= estimateSample; j++ {
+		key := strconv.Itoa(j)
+		for len(key) < estimateKeyBytes {
+			key = "0" + key
+		}
+		m[j] = key
+	}
+	begun := time.Now()
+	f := quaternary.New(m, estimateKeyBytes*8, 0)
+	return float64(len(f)) / estimateSample, float64(time.Since(begun).Nanoseconds()) / estimateSample
+}
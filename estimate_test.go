@@ -0,0 +1,41 @@
+package fulltext
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+// TestEstimateSize tests that the estimate is within a factor of two of a real build
+func TestEstimateSize(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	word := func() string {
+		b := make([]byte, 8)
+		for n := range b {
+			b[n] = byte('a' + rnd.IntN(26))
+		}
+		return string(b)
+	}
+	data := make(map[string][]string)
+	for j := 0; j < 4000; j++ {
+		data[fmt.Sprintf("doc:%012d", j)] = []string{word(), word(), word()}
+	}
+	idx, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	serialized, _ := idx.SerializeProto()
+	r := EstimateSize(len(data), 3, 8, nil)
+	if r.Shards != len(idx.private) {
+		t.Fatalf("expected %d shards, got %d", len(idx.private), r.Shards)
+	}
+	if r.DiskBytes < uint64(len(serialized))/2 || r.DiskBytes > uint64(len(serialized))*2 {
+		t.Fatalf("expected about %d bytes, got %d", len(serialized), r.DiskBytes)
+	}
+	if r.MemoryBytes != r.PkBytes+r.BucketBytes+r.BloomBytes || r.BuildTime <= 0 {
+		t.Fatalf("expected a consistent report, got %+v", r)
+	}
+	if r := EstimateSize(0, 3, 8, nil); r.MemoryBytes != 0 {
+		t.Fatalf("expected an empty estimate, got %+v", r)
+	}
+}
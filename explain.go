@@ -0,0 +1,123 @@
+package fulltext
+
+import "context"
+import "fmt"
+import "sort"
+import "strings"
+
+// Explanation describes how a lookup of Word walked the index, see Explain
+type Explanation struct {
+	Word  string
+	Exact bool
+	// Words are the words looked up after the query middleware, see WithQueryMiddleware
+	Words  []string
+	Shards []ShardExplanation
+}
+
+// ShardExplanation describes the lookup of one of the Words in one shard
+type ShardExplanation struct {
+	Shard int
+	// Query is the word after the analyzer, folding and truncation of the shard
+	Query string
+	// Skipped names why the shard was not probed: "short_query", "empty", "bloom" or "" when it was probed
+	Skipped string
+	// Postings reports that the rows were read from the postings of the query, see NewOpts.RoaringPostings,
+	// instead of probing the buckets
+	Postings bool
+	Probes   []BucketProbe
+	Hits     []ExplainedHit
+}
+
+// BucketProbe is one count filter probe of a bucket with a query shingle
+type BucketProbe struct {
+	Bucket int
+	Term   string
+	// Count is the estimate returned by the count filter, Discarded the number of positions rejected as false
+	Count     uint64
+	Positions []uint64
+	Discarded int
+}
+
+// ExplainedHit is a candidate row, Yielded when the lookup yielded it: it matched enough shingles for a deduplicated
+// Lookup, is not deleted and is visible, see WithVisibility
+type ExplainedHit struct {
+	Pos     uint64
+	Key     string
+	Matches int
+	Yielded bool
+}
+
+// Explain runs a deduplicated lookup of word, recording the shards and buckets probed, the count estimates returned
+// by the filters and the positions resolved to keys, for debugging misses and false positives. The lookup is the one
+// of Lookup, with the query middleware, postings, deletes and visibility, but bypassing the cache.
+func (i *Index) Explain(word string, exact bool) *Explanation {
+	e := &Explanation{Word: word, Exact: exact}
+	yielded := make(map[row]struct{})
+	i.lookupVisible(context.Background(), word, exact, true, 0, nil, i.visible, e, func(shard int, pos uint64, _ float64) bool {
+		yielded[row{shard, pos}] = struct{}{}
+		return true
+	})
+	for n := range e.Shards {
+		s := &e.Shards[n]
+		for h := range s.Hits {
+			_, s.Hits[h].Yielded = yielded[row{s.Shard, s.Hits[h].Pos}]
+		}
+		sort.Slice(s.Hits, func(a, b int) bool { return s.Hits[a].Pos < s.Hits[b].Pos })
+	}
+	return e
+}
+
+// probed records a count filter probe of bucket with term, nil records nothing
+func (s *ShardExplanation) probed(bucket int, term string, count uint64) {
+	if s != nil {
+		s.Probes = append(s.Probes, BucketProbe{Bucket: bucket, Term: term, Count: count})
+	}
+}
+
+// resolved records a position of the last probe in shard p, 0 for a count rejected as false
+func (s *ShardExplanation) resolved(p *index, pos uint64) {
+	if s == nil {
+		return
+	}
+	probe := &s.Probes[len(s.Probes)-1]
+	if pos == 0 || pos > p.Rows {
+		probe.Discarded++
+		return
+	}
+	probe.Positions = append(probe.Positions, pos)
+}
+
+// candidate records the row at pos of shard p, which matched that many shingles
+func (s *ShardExplanation) candidate(p *index, pos uint64, matches int) {
+	if s != nil {
+		s.Hits = append(s.Hits, ExplainedHit{Pos: pos, Key: p.key(pos), Matches: matches})
+	}
+}
+
+// String renders the explanation as indented text
+func (e *Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "lookup %q exact=%v\n", e.Word, e.Exact)
+	if len(e.Words) != 1 || e.Words[0] != e.Word {
+		fmt.Fprintf(&b, "  rewritten to %q\n", e.Words)
+	}
+	for _, s := range e.Shards {
+		if s.Skipped != "" {
+			fmt.Fprintf(&b, "  shard %d: skipped (%s)\n", s.Shard, s.Skipped)
+			continue
+		}
+		if s.Postings {
+			fmt.Fprintf(&b, "  shard %d: query %q from postings\n", s.Shard, s.Query)
+		} else {
+			fmt.Fprintf(&b, "  shard %d: query %q\n", s.Shard, s.Query)
+		}
+		for _, probe := range s.Probes {
+			fmt.Fprintf(&b, "    bucket %d %q: count %d, positions %v, discarded %d\n",
+				probe.Bucket, probe.Term, probe.Count, probe.Positions, probe.Discarded)
+		}
+		for _, hit := range s.Hits {
+			fmt.Fprintf(&b, "    row %d %q: %d matches, yielded=%v\n", hit.Pos, hit.Key, hit.Matches, hit.Yielded)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package fulltext
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestExplain tests that the explanation agrees with Lookup and names skipped shards
+func TestExplain(t *testing.T) {
+	idx, err := New(nil, map[string][]string{"doc:1": {"golang", "backend"}, "doc:2": {"rust", "backend"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	python, err := New(nil, map[string][]string{"doc:3": {"python", "scripting"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.Append(python)
+	e := idx.Explain("backend", true)
+	var yielded []string
+	var skipped int
+	for _, s := range e.Shards {
+		if s.Skipped != "" {
+			skipped++
+		}
+		for _, hit := range s.Hits {
+			if hit.Yielded {
+				yielded = append(yielded, hit.Key)
+			}
+		}
+	}
+	var want int
+	for range idx.Lookup("backend", true, true) {
+		want++
+	}
+	if len(yielded) != want || want != 2 {
+		t.Fatalf("expected %d yielded keys, got %v", want, yielded)
+	}
+	if skipped == 0 {
+		t.Fatal("expected the python shard to be skipped")
+	}
+	if out := e.String(); !strings.Contains(out, `"bac"`) || !strings.Contains(out, "yielded=true") {
+		t.Fatalf("expected probes and hits in the rendering, got\n%s", out)
+	}
+}
+
+// TestExplainLookupPath tests that the explanation yields what Lookup yields in depth limited and strided shards,
+// with postings, deletes, visibility and query middleware
+func TestExplainLookupPath(t *testing.T) {
+	data := make(map[string][]string)
+	for n := 0; n < 40; n++ {
+		data[fmt.Sprintf("doc:%02d", n)] = []string{fmt.Sprintf("international%02d", n), "common"}
+	}
+	shallow := NewDefaultOpts()
+	shallow.MaxBucketDepth = 4
+	strided := NewDefaultOpts()
+	strided.ShingleStride = 2
+	postings := NewDefaultOpts()
+	postings.RoaringPostings = 0.5
+	for _, opts := range []*NewOpts{shallow, strided, postings} {
+		idx, err := New(opts, data, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		idx.Delete("doc:03")
+		idx.WithVisibility(func(pk string) bool { return pk != "doc:04" })
+		idx.WithQueryMiddleware(ExpandSynonyms(map[string][]string{"usual": {"common"}}))
+		for _, word := range []string{"international07", "usual", "common"} {
+			var want []string
+			for pk := range idx.Lookup(word, true, true) {
+				want = append(want, pk)
+			}
+			var yielded []string
+			e := idx.Explain(word, true)
+			for _, s := range e.Shards {
+				for _, hit := range s.Hits {
+					if hit.Yielded {
+						yielded = append(yielded, hit.Key)
+					}
+				}
+			}
+			sort.Strings(want)
+			sort.Strings(yielded)
+			if fmt.Sprint(yielded) != fmt.Sprint(want) {
+				t.Fatalf("expected %v explained for %s, got %v\n%s", want, word, yielded, e)
+			}
+		}
+		if e := idx.Explain("common", true); opts == postings && !strings.Contains(e.String(), "from postings") {
+			t.Fatalf("expected the postings explained, got\n%s", e)
+		}
+	}
+}
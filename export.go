@@ -0,0 +1,140 @@
+package fulltext
+
+import "bufio"
+import "encoding/json"
+import "fmt"
+import "io"
+import "sort"
+import "strings"
+
+var ErrNoTerms = fmt.Errorf("no_terms")
+
+// Posting is a term with the sorted primary keys of the rows its lookup finds, written by ExportPostings
+type Posting struct {
+	Term     string   `json:"term"`
+	Postings []string `json:"postings"`
+}
+
+// postings resolves the posting list of every stored term by an exact deduplicated lookup, so the lists
+// reflect what this index returns, false positives included. ErrNoTerms is returned without NewOpts.StoreTerms.
+func (i *Index) postings() ([]Posting, error) {
+	var postings []Posting
+	for term := range i.Terms() {
+		p := Posting{Term: term, Postings: []string{}}
+		for pk := range i.LookupWith(term, &LookupOpts{Exact: true, GlobalDedup: true}) {
+			p.Postings = append(p.Postings, pk)
+		}
+		sort.Strings(p.Postings)
+		postings = append(postings, p)
+	}
+	if postings == nil {
+		return nil, ErrNoTerms
+	}
+	return postings, nil
+}
+
+// ExportPostings writes the raw posting lists of the stored terms as JSON lines, one Posting per term in lexicographic order,
+// so results can be compared against other engines. The index must be built with NewOpts.StoreTerms.
+func (i *Index) ExportPostings(w io.Writer) error {
+	postings, err := i.postings()
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, p := range postings {
+		if err := enc.Encode(p); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ExportDocuments inverts the posting lists into documents written as JSON lines of {"id": primaryKey, field: "term term ..."},
+// the bulk input of bleve and similar engines, so the same rows can be indexed there. The index must be built with NewOpts.StoreTerms.
+func (i *Index) ExportDocuments(w io.Writer, field string) error {
+	postings, err := i.postings()
+	if err != nil {
+		return err
+	}
+	docs := make(map[string][]string)
+	for _, p := range postings {
+		for _, pk := range p.Postings {
+			docs[pk] = append(docs[pk], p.Term)
+		}
+	}
+	pks := make([]string, 0, len(docs))
+	for pk := range docs {
+		pks = append(pks, pk)
+	}
+	sort.Strings(pks)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, pk := range pks {
+		if err := enc.Encode(map[string]string{"id": pk, field: strings.Join(docs[pk], " ")}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// DumpEntry is a (term, row) pair written by Dump
+type DumpEntry struct {
+	Term       string `json:"term"`
+	PrimaryKey string `json:"pk"`
+	// Found reports whether the exact lookup of Term yields the row, Verified whether the row of the source holds a word
+	// starting with Term, which exact lookups match by prefix, so a found but unverified pair is a false positive and
+	// a verified pair not found is missing from the index
+	Found    bool `json:"found"`
+	Verified bool `json:"verified"`
+}
+
+// Dump writes every (term, row) pair the index claims to contain as JSON lines, one DumpEntry per pair ordered by term
+// and primary key, so data teams can audit the probabilistic index. The terms are the words of the rows of source,
+// each resolved by an exact lookup as by ExportPostings, and every yielded row is verified against source. Rows of
+// source the lookup misses are written too, with Found unset. Unlike ExportPostings, NewOpts.StoreTerms is not needed.
+func (i *Index) Dump(w io.Writer, source RowSource) error {
+	rows := make(map[string]map[string]struct{})
+	source(func(pk string, words BagOfWords) bool {
+		for word := range words {
+			if rows[word] == nil {
+				rows[word] = make(map[string]struct{})
+			}
+			rows[word][pk] = struct{}{}
+		}
+		return true
+	})
+	terms := make([]string, 0, len(rows))
+	for term := range rows {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, term := range terms {
+		entries := make(map[string]*DumpEntry, len(rows[term]))
+		for pk := range i.LookupWith(term, &LookupOpts{Exact: true, GlobalDedup: true}) {
+			entries[pk] = &DumpEntry{Term: term, PrimaryKey: pk, Found: true}
+		}
+		// the terms starting with term follow it in sorted order
+		for n := sort.SearchStrings(terms, term); n < len(terms) && strings.HasPrefix(terms[n], term); n++ {
+			for pk := range rows[terms[n]] {
+				if entries[pk] == nil {
+					entries[pk] = &DumpEntry{Term: term, PrimaryKey: pk}
+				}
+				entries[pk].Verified = true
+			}
+		}
+		pks := make([]string, 0, len(entries))
+		for pk := range entries {
+			pks = append(pks, pk)
+		}
+		sort.Strings(pks)
+		for _, pk := range pks {
+			if err := enc.Encode(entries[pk]); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,76 @@
+package fulltext
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestExportPostings tests exporting the posting lists and the inverted documents of the stored terms
+func TestExportPostings(t *testing.T) {
+	data := map[string]BagOfWords{
+		"doc:1": {"golang": {}, "backend": {}},
+		"doc:2": {"backend": {}},
+	}
+	opts := NewDefaultOpts()
+	opts.StoreTerms = true
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := idx.ExportPostings(&buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var p Posting
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[0]), &p) != nil {
+		t.Fatalf("expected 2 postings, got %q", buf.String())
+	}
+	if p.Term != "backend" || len(p.Postings) != 2 || p.Postings[0] != "doc:1" {
+		t.Fatalf("expected backend in doc:1 and doc:2, got %+v", p)
+	}
+
+	buf.Reset()
+	if err := idx.ExportDocuments(&buf, "body"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "{\"body\":\"backend golang\",\"id\":\"doc:1\"}\n{\"body\":\"backend\",\"id\":\"doc:2\"}\n"; buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+
+	idx, _ = New(nil, data, nil)
+	if err := idx.ExportPostings(&buf); !errors.Is(err, ErrNoTerms) {
+		t.Fatalf("expected ErrNoTerms, got %v", err)
+	}
+}
+
+// TestDump tests that pairs are written with whether the index finds them and whether the source holds them
+func TestDump(t *testing.T) {
+	idx, err := New(nil, map[string]BagOfWords{
+		"doc:1": {"golang": {}, "backend": {}},
+		"doc:2": {"backend": {}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	source := func(yield func(string, BagOfWords) bool) {
+		_ = yield("doc:1", BagOfWords{"golang": {}, "backend": {}}) && yield("doc:2", BagOfWords{"rust": {}, "gol": {}})
+	}
+	var buf bytes.Buffer
+	if err := idx.Dump(&buf, source); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := `{"term":"backend","pk":"doc:1","found":true,"verified":true}
+{"term":"backend","pk":"doc:2","found":true,"verified":false}
+{"term":"gol","pk":"doc:1","found":true,"verified":true}
+{"term":"gol","pk":"doc:2","found":false,"verified":true}
+{"term":"golang","pk":"doc:1","found":true,"verified":true}
+{"term":"rust","pk":"doc:2","found":false,"verified":true}
+`
+	if buf.String() != want {
+		t.Fatalf("expected %s, got %s", want, buf.String())
+	}
+}
@@ -0,0 +1,58 @@
+package fulltext
+
+import "sort"
+
+// addFacets records the facet values of row pos, facets missing from a row hold ""
+func (p *index) addFacets(pos int, values map[string]string) {
+	for name, value := range values {
+		if p.Facets == nil {
+			p.Facets = make(map[string][]string)
+		}
+		column := p.Facets[name]
+		for len(column) < pos {
+			column = append(column, "")
+		}
+		column[pos-1] = value
+		p.Facets[name] = column
+	}
+}
+
+// facet returns the value of facet name for row pos
+func (p *index) facet(name string, pos uint64) string {
+	column := p.Facets[name]
+	if pos == 0 || pos > uint64(len(column)) {
+		return ""
+	}
+	return column[pos-1]
+}
+
+// LookupFaceted collects the primary keys matching word, see Lookup with dedup, along with the number of hits per facet value.
+// Facets are only available when the index was built with NewOpts.Facets. Counts can (in rare cases) include false positives.
+func (i *Index) LookupFaceted(word string, exact bool) (keys []string, counts map[string]map[string]int) {
+	counts = make(map[string]map[string]int)
+	i.lookup(word, exact, true, func(shard int, pos uint64) bool {
+		keys = append(keys, i.private[shard].key(pos))
+		for name := range i.private[shard].Facets {
+			value := i.private[shard].facet(name, pos)
+			if value == "" {
+				continue
+			}
+			if counts[name] == nil {
+				counts[name] = make(map[string]int)
+			}
+			counts[name][value]++
+		}
+		return true
+	})
+	sort.Strings(keys)
+	return
+}
+
+func sortedFacets(facets map[string][]string) []string {
+	var names = make([]string, 0, len(facets))
+	for name := range facets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
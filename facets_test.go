@@ -0,0 +1,42 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestLookupFaceted tests per facet value hit counts, also after a protobuf round trip
+func TestLookupFaceted(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+		"doc:3": {"golang", "frontend"},
+	}
+	facets := map[string]map[string]string{
+		"doc:1": {"category": "server", "author": "ann"},
+		"doc:2": {"category": "server", "author": "bob"},
+		"doc:3": {"category": "client"},
+	}
+	opts := NewDefaultOpts()
+	opts.Facets = func(pk string) map[string]string { return facets[pk] }
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	serialized, _ := idx.SerializeProto()
+	var loaded Index
+	if err := loaded.DeserializeProto(serialized); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	keys, counts := loaded.LookupFaceted("backend", true)
+	if len(keys) != 2 || keys[0] != "doc:1" || keys[1] != "doc:2" {
+		t.Fatalf("expected doc:1 and doc:2, got %v", keys)
+	}
+	if counts["category"]["server"] != 2 || counts["author"]["ann"] != 1 || counts["author"]["bob"] != 1 {
+		t.Fatalf("unexpected counts %v", counts)
+	}
+	_, counts = loaded.LookupFaceted("golang", true)
+	if counts["category"]["server"] != 1 || counts["category"]["client"] != 1 || len(counts["author"]) != 1 {
+		t.Fatalf("unexpected counts %v", counts)
+	}
+}
@@ -0,0 +1,102 @@
+package fulltext
+
+import "sort"
+
+// FieldIndex indexes several named fields, such as "title" and "body", of the same primary keys
+type FieldIndex struct {
+	fields map[string]*Index
+}
+
+// NewFieldIndex creates one index per field, getters returning the words of each field in the row with primaryKey. Opts can be nil.
+func NewFieldIndex[V struct{} | BagOfWords | []string | map[string]int | map[string]float32](opts *NewOpts, data map[string]V, getters map[string]func(primaryKey string) BagOfWords) (*FieldIndex, error) {
+	if len(getters) == 0 {
+		return nil, ErrNilGetter
+	}
+	f := &FieldIndex{fields: make(map[string]*Index, len(getters))}
+	for name, getter := range getters {
+		if getter == nil {
+			return nil, ErrNilGetter
+		}
+		i, err := New(opts, data, getter)
+		if err != nil {
+			return nil, err
+		}
+		f.fields[name] = i
+	}
+	return f, nil
+}
+
+// Field returns the index of the named field, nil if there is no such field
+func (f *FieldIndex) Field(name string) *Index {
+	return f.fields[name]
+}
+
+// Fields returns the sorted field names
+func (f *FieldIndex) Fields() (names []string) {
+	for name := range f.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// LookupScored looks word up in every field, yielding each primary key once with the sum of the boosts of the
+// fields it matched, see LookupOpts.Boosts, times the weight of word in the field, see NewOpts.Weights.
+// Keys are yielded by descending score, ties by key. Opts can be nil.
+func (f *FieldIndex) LookupScored(word string, opts *LookupOpts) func(yield func(primaryKey string, score float64) bool) {
+	return func(yield func(string, float64) bool) {
+		results := f.scores(word, opts)
+		for _, r := range results {
+			if !yield(r.key, r.score) {
+				return
+			}
+		}
+	}
+}
+
+type scored struct {
+	key   string
+	score float64
+}
+
+// scores collects the boosted scores of every primary key matching word, sorted by descending score
+func (f *FieldIndex) scores(word string, opts *LookupOpts) []scored {
+	totals := f.totals(word, opts)
+	results := make([]scored, 0, len(totals))
+	for pk, score := range totals {
+		results = append(results, scored{key: pk, score: score})
+	}
+	sort.Slice(results, func(a, b int) bool { return worse(results[b], results[a]) })
+	return results
+}
+
+// totals sums the boosts of the fields matching word per primary key, times the word weights of weighted fields.
+// Fields weighing 0 are not searched.
+func (f *FieldIndex) totals(word string, opts *LookupOpts) map[string]float64 {
+	if opts == nil {
+		opts = new(LookupOpts)
+	}
+	fieldOpts := *opts
+	fieldOpts.GlobalDedup = true
+	totals := make(map[string]float64)
+	for _, name := range f.Fields() {
+		if opts.boost(name) != 0 {
+			f.add(totals, name, word, &fieldOpts)
+		}
+	}
+	return totals
+}
+
+// add adds the boost of the named field times the word weight to the totals of the keys matching word in the field
+func (f *FieldIndex) add(totals map[string]float64, name, word string, opts *LookupOpts) {
+	boost := opts.boost(name)
+	if i := f.fields[name]; i.weighted() {
+		for pk, weight := range i.weights(word, opts) {
+			totals[pk] += boost * weight
+		}
+		return
+	}
+	for pk := range f.fields[name].LookupWith(word, opts) {
+		totals[pk] += boost
+	}
+}
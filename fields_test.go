@@ -0,0 +1,100 @@
+package fulltext
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestLookupScored tests that boosted fields rank their matches first
+func TestLookupScored(t *testing.T) {
+	titles := map[string]BagOfWords{
+		"doc:1": {"golang": {}},
+		"doc:2": {"rust": {}},
+		"doc:3": {"python": {}},
+	}
+	bodies := map[string]BagOfWords{
+		"doc:1": {"backend": {}},
+		"doc:2": {"golang": {}, "backend": {}},
+		"doc:3": {"golang": {}},
+	}
+	f, err := NewFieldIndex(nil, titles, map[string]func(string) BagOfWords{
+		"title": func(pk string) BagOfWords { return titles[pk] },
+		"body":  func(pk string) BagOfWords { return bodies[pk] },
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var results []string
+	for pk, score := range f.LookupScored("golang", &LookupOpts{Exact: true, Boosts: map[string]float64{"title": 3}}) {
+		results = append(results, fmt.Sprintf("%s=%g", pk, score))
+	}
+	if fmt.Sprint(results) != "[doc:1=3 doc:2=1 doc:3=1]" {
+		t.Fatalf("expected [doc:1=3 doc:2=1 doc:3=1], got %v", results)
+	}
+	if fmt.Sprint(f.Fields()) != "[body title]" {
+		t.Fatalf("expected [body title], got %v", f.Fields())
+	}
+}
+
+// TestTopK tests that only the k best scored keys are yielded in score order
+func TestTopK(t *testing.T) {
+	words := map[string]BagOfWords{
+		"doc:1": {"golang": {}},
+		"doc:2": {"golang": {}},
+		"doc:3": {"rust": {}},
+	}
+	titles := map[string]BagOfWords{"doc:2": {"golang": {}}}
+	f, err := NewFieldIndex(nil, words, map[string]func(string) BagOfWords{
+		"title": func(pk string) BagOfWords { return titles[pk] },
+		"body":  func(pk string) BagOfWords { return words[pk] },
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var results []string
+	for pk, score := range f.TopK("golang", 1, &LookupOpts{Exact: true, Boosts: map[string]float64{"title": 2}}) {
+		results = append(results, fmt.Sprintf("%s=%g", pk, score))
+	}
+	if fmt.Sprint(results) != "[doc:2=3]" {
+		t.Fatalf("expected [doc:2=3], got %v", results)
+	}
+	results = nil
+	for pk, score := range f.TopK("golang", 1<<60, &LookupOpts{Exact: true}) {
+		results = append(results, fmt.Sprintf("%s=%g", pk, score))
+	}
+	if fmt.Sprint(results) != "[doc:2=2 doc:1=1]" {
+		t.Fatalf("expected every match for a huge k, got %v", results)
+	}
+	results = nil
+	for pk, score := range f.TopK("golang", 1<<60, &LookupOpts{Exact: true, Boosts: map[string]float64{"title": 0, "body": 2}}) {
+		results = append(results, fmt.Sprintf("%s=%g", pk, score))
+	}
+	if fmt.Sprint(results) != "[doc:1=2 doc:2=2]" {
+		t.Fatalf("expected the boosted body matches of a single field, got %v", results)
+	}
+}
+
+// TestTopKPruned tests that summing several fields keeps the k best keys after dropping hopeless ones
+func TestTopKPruned(t *testing.T) {
+	fields := map[string]map[string]BagOfWords{
+		"title": {"doc:1": {"golang": {}}, "doc:2": {"golang": {}}},
+		"body":  {"doc:2": {"golang": {}}, "doc:3": {"golang": {}}, "doc:4": {"golang": {}}},
+		"tags":  {"doc:4": {"golang": {}}, "doc:5": {"golang": {}}},
+	}
+	getters := make(map[string]func(string) BagOfWords)
+	for name, words := range fields {
+		getters[name] = func(pk string) BagOfWords { return words[pk] }
+	}
+	data := map[string]struct{}{"doc:1": {}, "doc:2": {}, "doc:3": {}, "doc:4": {}, "doc:5": {}}
+	f, err := NewFieldIndex(nil, data, getters)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var results []string
+	for pk, score := range f.TopK("golang", 2, &LookupOpts{Exact: true, Boosts: map[string]float64{"body": 4}}) {
+		results = append(results, fmt.Sprintf("%s=%g", pk, score))
+	}
+	if fmt.Sprint(results) != "[doc:2=5 doc:4=5]" {
+		t.Fatalf("expected [doc:2=5 doc:4=5], got %v", results)
+	}
+}
@@ -0,0 +1,43 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestFoldASCII tests folding of accented characters
+func TestFoldASCII(t *testing.T) {
+	for in, want := range map[string]string{
+		"résumé": "resume",
+		"Straße": "Strasse",
+		"Łódź":   "Lodz",
+		"plain":  "plain",
+		"日本語":    "日本語",
+	} {
+		if got := FoldASCII(in); got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+// TestLookupASCIIFold tests that folded indexes match accented and unaccented queries
+func TestLookupASCIIFold(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"résumé", "café"},
+		"doc:2": {"resume", "coffee"},
+	}
+	opts := NewDefaultOpts()
+	opts.ASCIIFold = true
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, query := range []string{"resume", "résumé"} {
+		results := make(map[string]struct{})
+		for pk := range idx.Lookup(query, true, true) {
+			results[pk] = struct{}{}
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results for %s, got %d", query, len(results))
+		}
+	}
+}
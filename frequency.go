@@ -0,0 +1,60 @@
+package fulltext
+
+// frequencyBits bounds the recorded term frequencies, higher frequencies saturate at 255
+const frequencyBits = 8
+
+// addFrequencies records the term frequencies of row pos, normalized like its words
+func (p *index) addFrequencies(pos int, frequencies map[string]int, normalize func(BagOfWords) BagOfWords) {
+	if p.frequencies == nil {
+		p.frequencies = make(map[string]uint64)
+	}
+	for word, n := range frequencies {
+		words := BagOfWords{word: {}}
+		if normalize != nil {
+			words = normalize(words)
+		}
+		for word := range words {
+			key := p.counterKey(p.salted(word), uint64(pos))
+			p.frequencies[key] = min(p.frequencies[key]+uint64(max(n, 0)), 1<<frequencyBits-1)
+		}
+	}
+}
+
+// buildFrequencies turns the recorded term frequencies into the shard filter
+func (p *index) buildFrequencies() {
+	if len(p.frequencies) > 0 {
+		p.Frequencies = p.backend().New(numbers(p.frequencies, frequencyBits), frequencyBits, 0)
+	}
+	p.frequencies = nil
+}
+
+// frequency returns the frequency of word in row pos, 1 in shards without recorded frequencies
+func (p *index) frequency(pos uint64, word string) int {
+	if len(p.Frequencies) < 2 {
+		return 1
+	}
+	return int(p.backend().GetNum(p.Frequencies, frequencyBits, p.counterKey(p.salted(word), pos)))
+}
+
+// LookupFrequency iterates the primary keys of rows containing word like an exact deduplicated Lookup, yielding
+// how many times the row holds word, as recorded with NewOpts.Frequencies or map[string]int data. Frequencies are
+// recorded per whole word, so word should be a complete word, and rows without recorded frequencies yield 1.
+func (i *Index) LookupFrequency(word string) func(yield func(primaryKey string, frequency int) bool) {
+	return func(yield func(string, int) bool) {
+		i.lookup(word, true, true, func(shard int, pos uint64) bool {
+			p := &i.private[shard]
+			return yield(p.key(pos), p.frequency(pos, p.query(word)))
+		})
+	}
+}
+
+// LookupMinFrequency iterates like LookupFrequency the primary keys of rows holding word at least n times
+func (i *Index) LookupMinFrequency(word string, n int) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		for pk, frequency := range i.LookupFrequency(word) {
+			if frequency >= n && !yield(pk) {
+				return
+			}
+		}
+	}
+}
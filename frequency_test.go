@@ -0,0 +1,58 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestLookupFrequency tests that term frequencies survive serialization and filter rows by their minimum frequency
+func TestLookupFrequency(t *testing.T) {
+	data := map[string]map[string]int{
+		"doc:1": {"golang": 3, "backend": 1},
+		"doc:2": {"golang": 1, "rust": 2},
+		"doc:3": {"python": 300},
+	}
+	idx, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	serialized, err := idx.SerializeProto()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var loaded Index
+	if err := loaded.DeserializeProto(serialized); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	frequencies := make(map[string]int)
+	for pk, tf := range loaded.LookupFrequency("golang") {
+		frequencies[pk] = tf
+	}
+	if frequencies["doc:1"] != 3 || frequencies["doc:2"] != 1 {
+		t.Fatalf("unexpected frequencies %v", frequencies)
+	}
+	for pk, tf := range loaded.LookupFrequency("python") {
+		if pk == "doc:3" && tf != 255 {
+			t.Errorf("expected saturated frequency 255, got %d", tf)
+		}
+	}
+	var frequent []string
+	for pk := range loaded.LookupMinFrequency("golang", 2) {
+		frequent = append(frequent, pk)
+	}
+	if len(frequent) != 1 || frequent[0] != "doc:1" {
+		t.Fatalf("expected only doc:1, got %v", frequent)
+	}
+}
+
+// TestLookupFrequencyWithout tests that rows indexed without frequencies yield 1
+func TestLookupFrequencyWithout(t *testing.T) {
+	idx, err := New(nil, map[string][]string{"doc:1": {"golang"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for pk, tf := range idx.LookupFrequency("golang") {
+		if pk == "doc:1" && tf != 1 {
+			t.Errorf("expected frequency 1, got %d", tf)
+		}
+	}
+}
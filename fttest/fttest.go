@@ -0,0 +1,99 @@
+// package fttest supports testing code built on fulltext indexes, such as measuring the false positive rate
+// of an index against the rows it was built from, to tune NewOpts.FalsePositiveFunctions empirically:
+//
+//	report := fttest.MeasureFalsePositiveRate(idx, source, []string{"golang", "backend"})
+//	fmt.Println(report.Rate)
+//	for _, b := range report.Buckets {
+//		fmt.Println(b.Bucket, b.Rate)
+//	}
+package fttest
+
+import "strings"
+import "github.com/neurlang/fulltext"
+
+// Report is the false positive rate observed by MeasureFalsePositiveRate
+type Report struct {
+	Queries int
+	// Results counts the keys yielded by the exact deduplicated lookups, FalsePositives those whose row lacks the query
+	Results        int
+	FalsePositives int
+	Rate           float64
+	// Buckets breaks the rate of the resolved positions down by bucket offset, ascending
+	Buckets []BucketReport
+}
+
+// BucketReport is the false positive rate of the positions resolved by the count filter of one bucket offset
+type BucketReport struct {
+	Bucket         int
+	Positions      int
+	FalsePositives int
+	Rate           float64
+}
+
+// MeasureFalsePositiveRate looks every sample query up exactly in idx, comparing the yielded keys and the positions
+// resolved per bucket against the rows of truth, which should be the rows idx was built from with their words as indexed.
+// A key is a false positive when no word of its row starts with the query, a position when no word holds the probed
+// shingle at the bucket offset.
+func MeasureFalsePositiveRate(idx *fulltext.Index, truth fulltext.RowSource, sampleQueries []string) Report {
+	rows := make(map[string][]string)
+	truth(func(pk string, words fulltext.BagOfWords) bool {
+		for word := range words {
+			rows[pk] = append(rows[pk], word)
+		}
+		return true
+	})
+	var r Report
+	var buckets []BucketReport
+	for _, query := range sampleQueries {
+		r.Queries++
+		for _, s := range idx.Explain(query, true).Shards {
+			keys := make(map[uint64]string, len(s.Hits))
+			for _, hit := range s.Hits {
+				keys[hit.Pos] = hit.Key
+				if hit.Yielded {
+					r.Results++
+					if !holds(rows[hit.Key], s.Query, 0) {
+						r.FalsePositives++
+					}
+				}
+			}
+			for _, probe := range s.Probes {
+				for len(buckets) <= probe.Bucket {
+					buckets = append(buckets, BucketReport{Bucket: len(buckets)})
+				}
+				b := &buckets[probe.Bucket]
+				for _, pos := range probe.Positions {
+					b.Positions++
+					if !holds(rows[keys[pos]], probe.Term, probe.Bucket) {
+						b.FalsePositives++
+					}
+				}
+			}
+		}
+	}
+	r.Rate = rate(r.FalsePositives, r.Results)
+	for _, b := range buckets {
+		if b.Positions > 0 {
+			b.Rate = rate(b.FalsePositives, b.Positions)
+			r.Buckets = append(r.Buckets, b)
+		}
+	}
+	return r
+}
+
+// holds reports whether any word has term at offset
+func holds(words []string, term string, offset int) bool {
+	for _, word := range words {
+		if len(word) >= offset && strings.HasPrefix(word[offset:], term) {
+			return true
+		}
+	}
+	return false
+}
+
+func rate(falsePositives, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(falsePositives) / float64(total)
+}
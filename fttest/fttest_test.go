@@ -0,0 +1,45 @@
+package fttest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/neurlang/fulltext"
+)
+
+// TestMeasureFalsePositiveRate tests that an accurate index measures fewer false positives than an inaccurate one
+func TestMeasureFalsePositiveRate(t *testing.T) {
+	data := make(map[string]fulltext.BagOfWords)
+	for j := 0; j < 2000; j++ {
+		data[fmt.Sprintf("doc:%05d", j)] = fulltext.BagOfWords{fmt.Sprintf("word%dx", j%500): {}}
+	}
+	source := func(yield func(string, fulltext.BagOfWords) bool) {
+		for pk, words := range data {
+			if !yield(pk, words) {
+				return
+			}
+		}
+	}
+	queries := []string{"word1x", "word42x", "word499x", "missing"}
+	measure := func(functions byte) Report {
+		opts := fulltext.NewDefaultOpts()
+		opts.FalsePositiveFunctions = functions
+		idx, err := fulltext.New(opts, data, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return MeasureFalsePositiveRate(idx, source, queries)
+	}
+	accurate, inaccurate := measure(10), measure(0)
+	if accurate.Queries != len(queries) || accurate.Results < 12 {
+		t.Fatalf("expected the 12 true rows among the results, got %+v", accurate)
+	}
+	if accurate.Rate > inaccurate.Rate {
+		t.Fatalf("expected fewer false positives with more functions, got %v > %v", accurate.Rate, inaccurate.Rate)
+	}
+	for _, b := range inaccurate.Buckets {
+		if b.Rate < 0 || b.Rate > 1 || b.FalsePositives > b.Positions {
+			t.Fatalf("unexpected bucket report %+v", b)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package fttest
+
+import "fmt"
+import "strings"
+import "github.com/neurlang/fulltext"
+
+// SeedCorpus returns small indexes serialized in every format, seeding fuzz targets of deserializers
+func SeedCorpus() [][]byte {
+	rows := map[string]fulltext.BagOfWords{
+		"doc:1": {"golang": {}, "backend": {}},
+		"doc:2": {"rust": {}, "backend": {}},
+		"doc:3": {"python": {}, "scripting": {}},
+	}
+	var corpus [][]byte
+	for _, configure := range []func(opts *fulltext.NewOpts){
+		func(opts *fulltext.NewOpts) {},
+		func(opts *fulltext.NewOpts) { opts.StoreTerms, opts.HashSeed = true, 42 },
+	} {
+		opts := fulltext.NewDefaultOpts()
+		configure(opts)
+		idx, err := fulltext.New(opts, rows, nil)
+		if err != nil {
+			panic(err)
+		}
+		idx.Delete("doc:2")
+		for _, serialize := range []func() ([]byte, error){idx.Serialize, idx.SerializeProto, idx.SerializeSharded} {
+			data, err := serialize()
+			if err != nil {
+				panic(err)
+			}
+			corpus = append(corpus, data)
+		}
+	}
+	return corpus
+}
+
+// Rows derives the rows of an index from arbitrary fuzz input: every line is a row, holding its space separated words.
+// Keys are numbered with a common length, as New requires.
+func Rows(data []byte) map[string]fulltext.BagOfWords {
+	rows := make(map[string]fulltext.BagOfWords)
+	for j, line := range strings.Split(string(data), "\n") {
+		words := make(fulltext.BagOfWords)
+		for _, word := range strings.Fields(line) {
+			words[word] = struct{}{}
+		}
+		rows[fmt.Sprintf("%08d", j)] = words
+	}
+	return rows
+}
+
+// CheckIndexed verifies the lookup invariant of idx built from rows: an exact lookup of every indexed word yields
+// its row. Words shorter than minWordLength cannot be looked up and are skipped.
+func CheckIndexed(idx *fulltext.Index, rows map[string]fulltext.BagOfWords, minWordLength int) error {
+	for pk, words := range rows {
+		for word := range words {
+			if len(word) < minWordLength {
+				continue
+			}
+			var found bool
+			for key := range idx.Lookup(word, true, true) {
+				if key == pk {
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("fttest: word %q of row %s not found", word, pk)
+			}
+		}
+	}
+	return nil
+}
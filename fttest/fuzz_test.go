@@ -0,0 +1,47 @@
+package fttest
+
+import (
+	"testing"
+
+	"github.com/neurlang/fulltext"
+)
+
+// FuzzDeserialize tests that deserializing arbitrary bytes fails cleanly, and that every loaded index can be looked up
+func FuzzDeserialize(f *testing.F) {
+	for _, data := range SeedCorpus() {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, deserialize := range []func(idx *fulltext.Index, data []byte) error{
+			(*fulltext.Index).Deserialize,
+			(*fulltext.Index).DeserializeProto,
+			(*fulltext.Index).DeserializeSharded,
+		} {
+			var idx fulltext.Index
+			if deserialize(&idx, data) != nil {
+				continue
+			}
+			for range idx.Lookup("backend", false, true) {
+			}
+			for range idx.Lookup("backend", true, true) {
+			}
+		}
+	})
+}
+
+// FuzzLookup tests that every indexed word is found
+func FuzzLookup(f *testing.F) {
+	f.Add([]byte("golang backend\nrust backend\npython scripting"))
+	f.Add([]byte("ünïcödé wörds\n\nabc abcd abcde"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		rows := Rows(data)
+		opts := fulltext.NewDefaultOpts()
+		idx, err := fulltext.New(opts, rows, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := CheckIndexed(idx, rows, int(opts.MinWordLength)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package fttest
+
+import "embed"
+import "encoding/json"
+import "errors"
+import "fmt"
+import "io/fs"
+import "os"
+import "path"
+import "path/filepath"
+import "slices"
+import "strings"
+import "github.com/neurlang/fulltext"
+
+// Golden holds small indexes in every format version and serialization that shipped, under golden/, with the lookups
+// they answer: Version 1 and 2 as JSON written by the original Serialize, Version 3 as JSON, protobuf and sharded.
+// Passing it to CheckGolden verifies the installed release still reads them:
+//
+//	if err := fttest.CheckGolden(fttest.Golden, "golden"); err != nil {
+//		t.Fatal(err)
+//	}
+//
+//go:embed golden
+var Golden embed.FS
+
+// GoldenLookup is a lookup recorded by WriteGolden, with the keys it yielded sorted
+type GoldenLookup struct {
+	Word  string   `json:"word"`
+	Exact bool     `json:"exact"`
+	Dedup bool     `json:"dedup"`
+	Keys  []string `json:"keys"`
+}
+
+// goldenExt and lookupsExt are the extensions of a golden index and of the lookups recorded beside it
+const goldenExt = ".idx"
+const lookupsExt = ".golden.json"
+
+// WriteGolden stores the serialized index data as name.idx in dir, and the exact and subword deduplicated lookups
+// of words as name.golden.json beside it, so that indexes stored by an application can be committed and checked
+// by CheckGolden after upgrading this package. Data is any serialization LoadFS accepts.
+func WriteGolden(dir, name string, data []byte, words []string) error {
+	if err := os.WriteFile(filepath.Join(dir, name+goldenExt), data, 0o644); err != nil {
+		return err
+	}
+	idx, err := fulltext.LoadFS(os.DirFS(dir), name+goldenExt)
+	if err != nil {
+		return err
+	}
+	var lookups []GoldenLookup
+	for _, word := range words {
+		for _, exact := range []bool{true, false} {
+			lookups = append(lookups, GoldenLookup{Word: word, Exact: exact, Dedup: true, Keys: lookup(idx, word, exact, true)})
+		}
+	}
+	out, err := json.MarshalIndent(lookups, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name+lookupsExt), append(out, '\n'), 0o644)
+}
+
+// CheckGolden loads every golden index stored in dir of fsys by WriteGolden, validates it and repeats the lookups
+// recorded beside it, returning every index failing to load or yielding other keys than recorded joined in one error.
+// Lookups are compared including false positives, which a release reading the same bytes yields identically.
+func CheckGolden(fsys fs.FS, dir string) error {
+	names, err := fs.Glob(fsys, path.Join(dir, "*"+goldenExt))
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("fttest: no golden indexes in %s", dir)
+	}
+	var errs []error
+	for _, name := range names {
+		if err := checkGolden(fsys, name); err != nil {
+			errs = append(errs, fmt.Errorf("fttest: golden %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkGolden checks the golden index stored at name
+func checkGolden(fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, strings.TrimSuffix(name, goldenExt)+lookupsExt)
+	if err != nil {
+		return err
+	}
+	var lookups []GoldenLookup
+	if err := json.Unmarshal(data, &lookups); err != nil {
+		return err
+	}
+	idx, err := fulltext.LoadFS(fsys, name)
+	if err != nil {
+		return err
+	}
+	if err := idx.Validate(); err != nil {
+		return err
+	}
+	for _, l := range lookups {
+		if keys := lookup(idx, l.Word, l.Exact, l.Dedup); !slices.Equal(keys, l.Keys) {
+			return fmt.Errorf("lookup %q exact %v: expected %v, got %v", l.Word, l.Exact, l.Keys, keys)
+		}
+	}
+	return nil
+}
+
+// lookup returns the sorted keys a lookup of word yields
+func lookup(idx *fulltext.Index, word string, exact, dedup bool) []string {
+	keys := []string{}
+	for pk := range idx.Lookup(word, exact, dedup) {
+		keys = append(keys, pk)
+	}
+	slices.Sort(keys)
+	return keys
+}
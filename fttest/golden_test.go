@@ -0,0 +1,48 @@
+package fttest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neurlang/fulltext"
+)
+
+// TestCheckGolden tests that the golden indexes of every format version are still read and looked up as recorded
+func TestCheckGolden(t *testing.T) {
+	if err := CheckGolden(Golden, "golden"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWriteGolden tests that a recorded index passes CheckGolden, and fails it once its recorded lookups are changed
+func TestWriteGolden(t *testing.T) {
+	idx, err := fulltext.New(nil, map[string]fulltext.BagOfWords{
+		"doc:1": {"golang": {}, "backend": {}},
+		"doc:2": {"rust": {}, "backend": {}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data, err := idx.SerializeProto()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	dir := t.TempDir()
+	if err := WriteGolden(dir, "app", data, []string{"backend", "golang"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := CheckGolden(os.DirFS(dir), "."); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	lookups := `[{"word": "backend", "exact": true, "dedup": true, "keys": ["doc:1"]}]`
+	if err := os.WriteFile(filepath.Join(dir, "app.golden.json"), []byte(lookups), 0o644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := CheckGolden(os.DirFS(dir), "."); err == nil {
+		t.Fatalf("expected a lookup mismatch")
+	}
+	if err := CheckGolden(os.DirFS(t.TempDir()), "."); err == nil {
+		t.Fatalf("expected an error without golden indexes")
+	}
+}
@@ -0,0 +1,164 @@
+package fulltext
+
+import "encoding/json"
+import "sort"
+import "strings"
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+const geohashPrecision = 12
+
+// maxGeoCells bounds the number of geohash cells covering a bounding box
+const maxGeoCells = 64
+
+// Point is a latitude and longitude in degrees
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// BoundingBox is an inclusive latitude and longitude box in degrees. Boxes crossing the antimeridian have MinLon > MaxLon.
+type BoundingBox struct {
+	MinLat, MinLon, MaxLat, MaxLon float64
+}
+
+func (b BoundingBox) contains(p Point) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+	}
+	return p.Lon >= b.MinLon || p.Lon <= b.MaxLon
+}
+
+// GeoField is a sidecar index of one location per primary key, sorted by geohash for bounding box scans.
+type GeoField struct {
+	Hashes []string `json:"hashes"`
+	Keys   []string `json:"keys"`
+	Points []Point  `json:"points"`
+}
+
+// NewGeoField builds the sidecar from the location of each primary key
+func NewGeoField(points map[string]Point) *GeoField {
+	var g = &GeoField{
+		Hashes: make([]string, 0, len(points)),
+		Keys:   make([]string, 0, len(points)),
+		Points: make([]Point, 0, len(points)),
+	}
+	var hashes = make(map[string]string, len(points))
+	for pk, p := range points {
+		hashes[pk] = geohash(p, geohashPrecision)
+		g.Keys = append(g.Keys, pk)
+	}
+	sort.Slice(g.Keys, func(a, b int) bool {
+		if hashes[g.Keys[a]] != hashes[g.Keys[b]] {
+			return hashes[g.Keys[a]] < hashes[g.Keys[b]]
+		}
+		return g.Keys[a] < g.Keys[b]
+	})
+	for _, pk := range g.Keys {
+		g.Hashes = append(g.Hashes, hashes[pk])
+		g.Points = append(g.Points, points[pk])
+	}
+	return g
+}
+
+// Within iterates the primary keys located inside box
+func (g *GeoField) Within(box BoundingBox) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		for _, prefix := range geohashCover(box) {
+			for k := sort.SearchStrings(g.Hashes, prefix); k < len(g.Hashes) && strings.HasPrefix(g.Hashes[k], prefix); k++ {
+				if box.contains(g.Points[k]) && !yield(g.Keys[k]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Serialize serializes to JSON
+func (g *GeoField) Serialize() ([]byte, error) {
+	return json.Marshal(g)
+}
+
+// Deserialize deserializes from JSON
+func (g *GeoField) Deserialize(data []byte) error {
+	return json.Unmarshal(data, g)
+}
+
+// AttachGeo makes a location sidecar available to Search(...).Within(name, ...).
+// Sidecars are not part of the index serialization, persist them with GeoField.Serialize.
+// AttachGeo is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) AttachGeo(name string, g *GeoField) *Index {
+	if i.geo == nil {
+		i.geo = make(map[string]*GeoField)
+	}
+	i.geo[name] = g
+	return i
+}
+
+// geohash encodes p with the given number of base32 characters
+func geohash(p Point, precision int) string {
+	var lat = [2]float64{-90, 90}
+	var lon = [2]float64{-180, 180}
+	var out = make([]byte, 0, precision)
+	var even = true
+	var bit, ch int
+	for len(out) < precision {
+		var r *[2]float64
+		var v float64
+		if even {
+			r, v = &lon, p.Lon
+		} else {
+			r, v = &lat, p.Lat
+		}
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+		if bit++; bit == 5 {
+			out = append(out, geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return string(out)
+}
+
+// geohashCover returns distinct geohash prefixes whose cells together cover box, using the finest precision
+// that needs no more than maxGeoCells cells
+func geohashCover(box BoundingBox) []string {
+	if box.MinLon > box.MaxLon {
+		west := geohashCover(BoundingBox{MinLat: box.MinLat, MinLon: box.MinLon, MaxLat: box.MaxLat, MaxLon: 180})
+		east := geohashCover(BoundingBox{MinLat: box.MinLat, MinLon: -180, MaxLat: box.MaxLat, MaxLon: box.MaxLon})
+		return append(west, east...)
+	}
+	for precision := geohashPrecision; precision > 1; precision-- {
+		width := 360 / float64(uint64(1)<<((5*precision+1)/2))
+		height := 180 / float64(uint64(1)<<(5*precision/2))
+		cols := int((box.MaxLon+180)/width) - int((box.MinLon+180)/width) + 1
+		rows := int((box.MaxLat+90)/height) - int((box.MinLat+90)/height) + 1
+		if cols*rows > maxGeoCells {
+			continue
+		}
+		var seen = make(map[string]struct{})
+		var cells []string
+		for r := 0; r < rows; r++ {
+			lat := -90 + (float64(int((box.MinLat+90)/height)+r)+0.5)*height
+			for c := 0; c < cols; c++ {
+				lon := -180 + (float64(int((box.MinLon+180)/width)+c)+0.5)*width
+				cell := geohash(Point{Lat: min(lat, 90), Lon: min(lon, 180)}, precision)
+				if _, ok := seen[cell]; !ok {
+					seen[cell] = struct{}{}
+					cells = append(cells, cell)
+				}
+			}
+		}
+		return cells
+	}
+	return []string{""}
+}
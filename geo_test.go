@@ -0,0 +1,65 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestGeohash tests the encoding against a well known value
+func TestGeohash(t *testing.T) {
+	if got := geohash(Point{Lat: 57.64911, Lon: 10.40744}, 11); got != "u4pruydqqvj" {
+		t.Fatalf("expected u4pruydqqvj, got %s", got)
+	}
+}
+
+// TestSearchWithin tests restricting text matches to a bounding box
+func TestSearchWithin(t *testing.T) {
+	data := map[string][]string{
+		"poi:1": {"pizza", "napoli"},
+		"poi:2": {"pizza", "brooklyn"},
+		"poi:3": {"pizza", "roma"},
+		"poi:4": {"sushi", "roma"},
+	}
+	idx, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.AttachGeo("location", NewGeoField(map[string]Point{
+		"poi:1": {Lat: 40.85, Lon: 14.27},
+		"poi:2": {Lat: 40.68, Lon: -73.94},
+		"poi:3": {Lat: 41.90, Lon: 12.50},
+		"poi:4": {Lat: 41.89, Lon: 12.49},
+	}))
+
+	italy := BoundingBox{MinLat: 36, MinLon: 6, MaxLat: 47, MaxLon: 19}
+	results := make(map[string]struct{})
+	for pk := range idx.Search("pizza").Within("location", italy).Keys() {
+		results[pk] = struct{}{}
+	}
+	if _, ok := results["poi:2"]; ok || len(results) != 2 {
+		t.Fatalf("expected the two italian pizzerias, got %v", results)
+	}
+
+	rome := BoundingBox{MinLat: 41.8, MinLon: 12.4, MaxLat: 42.0, MaxLon: 12.6}
+	results = make(map[string]struct{})
+	for pk := range idx.Search("pizza").Within("location", rome).Where("rating", AtLeast(0)).Keys() {
+		results[pk] = struct{}{}
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected no results with an unattached field, got %v", results)
+	}
+	for pk := range idx.Search("pizza").Within("location", rome).Keys() {
+		results[pk] = struct{}{}
+	}
+	if _, ok := results["poi:3"]; !ok || len(results) != 1 {
+		t.Fatalf("expected poi:3, got %v", results)
+	}
+
+	pacific := BoundingBox{MinLat: -50, MinLon: 170, MaxLat: 50, MaxLon: -60}
+	results = make(map[string]struct{})
+	for pk := range idx.Search("pizza").Within("location", pacific).Keys() {
+		results[pk] = struct{}{}
+	}
+	if _, ok := results["poi:2"]; !ok || len(results) != 1 {
+		t.Fatalf("expected poi:2 across the antimeridian, got %v", results)
+	}
+}
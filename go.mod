@@ -2,10 +2,18 @@ module github.com/neurlang/fulltext
 
 go 1.24.7
 
-require github.com/neurlang/quaternary v0.2.3
+require (
+	github.com/mattn/go-sqlite3 v1.14.33
+	github.com/neurlang/quaternary v0.2.3
+	github.com/parquet-go/parquet-go v0.25.1
+)
 
 require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.3 // indirect
 	github.com/minio/sha256-simd v1.0.1 // indirect
-	golang.org/x/sys v0.0.0-20220704084225-05e143d24a9e // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	golang.org/x/sys v0.21.0 // indirect
 )
@@ -1,10 +1,20 @@
 // package fulltext implements a Fulltext Index data structure for Golang
 package fulltext
 
-import quaternary "github.com/neurlang/quaternary/v1"
+import "cmp"
+import "context"
+import "crypto/rand"
+import "encoding/binary"
 import "fmt"
+import "log/slog"
+import "os"
 import "reflect"
+import "strconv"
+import "strings"
 import "sync"
+import "sync/atomic"
+import "time"
+import "unicode/utf8"
 
 type BagOfWords = map[string]struct{}
 
@@ -18,10 +28,88 @@ type index struct {
 	Logrows byte     `json:"logrows"`
 	Maxword int      `json:"maxword"`
 	MinWord byte     `json:"minword"`
+
+	Truncate int                 `json:"truncate,omitempty"`
+	Fold     bool                `json:"fold,omitempty"`
+	Analyzer string              `json:"analyzer,omitempty"`
+	Facets   map[string][]string `json:"facets,omitempty"`
+	Payloads [][]byte            `json:"payloads,omitempty"`
+	Tokens   [][]string          `json:"tokens,omitempty"`
+	Seed     uint64              `json:"seed,omitempty"`
+	Bloom    []byte              `json:"bloom,omitempty"`
+	Terms    map[string]uint64   `json:"terms,omitempty"`
+	// Frequencies maps the words of every row to their term frequency
+	Frequencies []byte `json:"frequencies,omitempty"`
+	// Weights maps the words of every row to their weight, such as 3 for title words
+	Weights []byte `json:"weights,omitempty"`
+	// TermPayloads maps the words of every row to their payload, see NewOpts.TermPayloads
+	TermPayloads []byte `json:"term_payloads,omitempty"`
+	// BuildID and BuiltAt (in unix nanoseconds) stamp the build of the shard, see Meta
+	BuildID string `json:"build_id,omitempty"`
+	BuiltAt int64  `json:"built_at,omitempty"`
+	// Stride is the offset step between the indexed buckets, 0 = every offset
+	Stride byte `json:"stride,omitempty"`
+	// Depth is the number of word offsets with buckets, 0 = every offset, see NewOpts.MaxBucketDepth
+	Depth int `json:"depth,omitempty"`
+	// Backend names the registered FilterBackend of the filters, "" = quaternary, see NewOpts.FilterBackend
+	Backend string `json:"backend,omitempty"`
+	// Unstemmed marks shards indexing the unstemmed words too, see NewOpts.IndexUnstemmed
+	Unstemmed bool `json:"unstemmed,omitempty"`
+	// DualCase marks shards indexing the words lowercased and keeping their case, see NewOpts.DualCase
+	DualCase bool `json:"dual_case,omitempty"`
+	// Padding is the side, 'l' or 'r', and the byte the primary keys are padded with, see NewOpts.KeyPadding
+	Padding []byte `json:"padding,omitempty"`
+	// Stats holds the BuildStats of the build of the shard as JSON, with the distinct words of the shard, see Meta
+	Stats []byte `json:"stats,omitempty"`
+	// InputHash sums the hashes of the rows of the shard modulo 2^256, see NewOpts.HashInputs
+	InputHash []byte `json:"input_hash,omitempty"`
+	// Postings maps frequent words to the roaring bitmap of the rows matching them, see NewOpts.RoaringPostings
+	Postings map[string][]byte `json:"postings,omitempty"`
+	Checksum uint32            `json:"checksum,omitempty"`
+
+	// Generation is the index generation the shard was appended at, Deleted maps deleted keys to their generation
+	Generation uint64            `json:"generation,omitempty"`
+	Deleted    map[string]uint64 `json:"deleted,omitempty"`
+
+	// filters is the resolved Backend
+	filters FilterBackend
+	// shingles collects the bloom filter contents during build
+	shingles map[string]struct{}
+	// frequencies collects the term frequencies during build
+	frequencies map[string]uint64
+	// weights collects the word weights during build
+	weights map[string]uint64
+	// termPayloads collects the term payloads during build
+	termPayloads map[string]uint64
+	// documents counts the rows per word during build
+	documents map[string]uint64
+	// words collects the distinct words during build, counted into distinct by flush for the build stats
+	words    map[string]struct{}
+	distinct int
+	// keys maps the primary keys of the shard to their rows, decoded by the first find
+	keys map[string]uint64
+	// postings holds the decoded Postings, decoded once by buildPostings or when the shard is loaded
+	postings map[string]*bitmap
 }
 
 type Index struct {
 	private []index
+	numeric map[string]*NumericField
+	geo     map[string]*GeoField
+	cache   *queryCache
+	metrics Metrics
+	logger  *slog.Logger
+	tracer  Tracer
+	limits  *Limits
+
+	middleware []QueryMiddleware
+	visible    func(primaryKey string) bool
+	pinning    *pinPool
+	queryLog   QueryLog
+	slowQuery  time.Duration
+
+	generation uint64
+	deleted    map[string]uint64
 }
 
 func NewDefaultOpts() *NewOpts {
@@ -31,6 +119,9 @@ func NewDefaultOpts() *NewOpts {
 		MinWordLength:          3,
 		Sync:                   true,
 		MinShards:              3,
+		BloomBitsPerShingle:    8,
+		BagCacheRows:           1 << 16,
+		configured:             true,
 	}
 }
 
@@ -40,6 +131,10 @@ type NewOpts struct {
 	// Lower values (0-9) increase the false positive rate, cause more false positive problems.
 	FalsePositiveFunctions byte
 
+	// FalsePositiveFunctionsPerBucket overrides FalsePositiveFunctions per bucket offset, such as {10, 6, 3} to spend
+	// memory on the accuracy of bucket 0 which every lookup probes. Offsets past the end use the last entry.
+	FalsePositiveFunctionsPerBucket []byte
+
 	// BucketingExponent affects speed for large-scale indexes.
 	// Higher values are slower to generate the index. Lower values are slower to search.
 	BucketingExponent byte
@@ -53,29 +148,191 @@ type NewOpts struct {
 	// Sync calls getter from one thread only
 	Sync bool
 
+	// StoreTerms keeps the term dictionary with document counts, enabling Terms()
+	StoreTerms bool
+
+	// MaxWordLength bounds the indexed word length in bytes, and with it the number of buckets. 0 = unlimited.
+	// Longer words are truncated on a rune boundary (and so are queries), or skipped entirely with SkipLongWords.
+	MaxWordLength int
+
+	// SkipLongWords drops words longer than MaxWordLength instead of truncating them
+	SkipLongWords bool
+
+	// ASCIIFold maps accented characters to their base ASCII forms when indexing and when looking up
+	ASCIIFold bool
+
+	// Facets returns the categorical facet values (category, author, ...) of a row, enabling LookupFaceted
+	Facets func(primaryKey string) map[string]string
+
+	// Payload returns a small stored value (title, URL, ...) of a row, enabling LookupWithPayload
+	Payload func(primaryKey string) []byte
+
+	// Tokens returns the ordered tokens of a row, recording their positions to enable LookupNear
+	Tokens func(primaryKey string) []string
+
+	// Frequencies returns how many times each word occurs in a row, enabling LookupFrequency.
+	// It is set from the data when New is passed map[string]int values without a getter.
+	Frequencies func(primaryKey string) map[string]int
+
+	// Weights returns the weight of each word of a row, such as 3 for title words, ranking stronger matches
+	// first in scored lookups. It is set from the data when New is passed map[string]float32 values without a getter.
+	Weights func(primaryKey string) map[string]float32
+
+	// TermPayloads returns a small payload of each word of a row, such as a bitmask of the fields holding the word or
+	// its number of positions, enabling LookupTermPayload. Words normalizing alike OR their payloads.
+	TermPayloads func(primaryKey string) map[string]uint16
+
+	// BloomBitsPerShingle sizes the per shard bloom filter of shingles, letting Lookup skip shards
+	// that cannot contain the word. Default = 8, 0 disables the filter.
+	BloomBitsPerShingle byte
+
+	// Analyzer names a registered Analyzer run over every word when indexing and when looking up.
+	// Import the analyzer subpackage for the built in pipelines such as "english".
+	Analyzer string
+
+	// TargetShardRows flushes a shard once it holds this many rows, replacing the BucketingExponent split
+	// so the shard size does not need retuning as the corpus grows. 0 = use BucketingExponent.
+	TargetShardRows int
+
+	// ShardBuildBudget additionally flushes a shard once collecting its rows took this long,
+	// keeping shards small when the getter is slow. 0 = no budget.
+	ShardBuildBudget time.Duration
+
+	// Metrics receives the build duration, and the lookup counters of the built index, see Index.WithMetrics
+	Metrics Metrics
+
+	// Logger receives shard flushes, bucket construction timings and filter sizes at debug level during build,
+	// and the lookups of the built index, see Index.WithLogger
+	Logger *slog.Logger
+
+	// Tracer traces the build, and the lookups of the built index, see Index.WithTracer
+	Tracer Tracer
+
+	// HashSeed salts the filter keys, so two indexes of the same data built with different seeds do not share
+	// collision patterns. 0 = unsalted. RandomHashSeed draws a random seed instead, so adversaries cannot
+	// precompute terms colliding into pathological false positives.
+	HashSeed       uint64
+	RandomHashSeed bool
+
+	// GetterTimeout bounds every getter call, retrying a call not returning in time up to GetterRetries more
+	// times. New fails with ErrGetterTimeout once the retries are exhausted. 0 = wait forever.
+	// A hung call is abandoned, not interrupted, so its goroutine lives on until the getter returns.
+	GetterTimeout time.Duration
+	GetterRetries int
+
+	// CheckpointDir persists every shard into this directory once its buckets are finished, so an interrupted
+	// build can be continued by ResumeBuild. "" = no checkpoints.
+	CheckpointDir string
+
+	// BuildID stamps the built shards along with the build time, readable by Index.Meta after deserialization
+	BuildID string
+
+	// ShingleStride indexes only every k-th word offset, building a k times smaller index faster. Exact lookups probe
+	// the shingles at the indexed offsets, subword lookups need words of at least MinWordLength+ShingleStride-1
+	// bytes, so every alignment holds a shingle. Deduplicated lookups match rows holding every probed shingle, not
+	// all but one. 0 or 1 = every offset.
+	ShingleStride byte
+
+	// MaxBucketDepth builds the buckets of the first MaxBucketDepth word offsets only, fitting the index into a memory
+	// budget. Exact lookups keep finding every matching row, telling words apart by their first
+	// MaxBucketDepth+MinWordLength-1 bytes, while subword lookups only find subwords within those bytes.
+	// Index.DropBuckets lowers the depth of a built index. 0 = every offset.
+	MaxBucketDepth int
+
+	// BulkGetter returns the words of the rows with pks, in the same order, building the buckets of every shard
+	// from one call with the keys of the whole shard instead of a getter call per row and bucket, such as one scan
+	// of a column store. It is called for one shard at a time, without GetterTimeout. nil = use the getter.
+	BulkGetter func(pks []string) []BagOfWords
+
+	// BagCacheRows bounds the rows per shard whose words are kept while the buckets of the shard are built, so the
+	// bucket goroutines share one getter call per row. Rows past the bound are fetched per bucket. 0 = no cache.
+	BagCacheRows int
+
+	// RoaringPostings stores a roaring bitmap of the matching rows of every word held by at least this fraction of the
+	// rows of a shard, such as 0.1. Exact deduplicated lookups of those words read the bitmap instead of probing the
+	// filters, with the same results, and LookupQuery combines the rows as bitmaps. Like StoreTerms, the words are
+	// stored in plain. 0 = no postings.
+	RoaringPostings float64
+
+	// FilterBackend names a registered FilterBackend building and probing the filters of the shards instead of
+	// quaternary filters, see RegisterFilterBackend. "" = quaternary.
+	FilterBackend string
+
+	// MapShardRows builds the shards of up to this many rows, such as the last shard of a build and the shards of
+	// Update, with an exact sorted map instead of quaternary filters: faster to build, without false positives and
+	// small at this size. Only with the quaternary FilterBackend. 0 = never.
+	MapShardRows int
+
+	// KeyPadding pads the shorter primary keys of every shard to its longest key, lifting the requirement of a common
+	// key size, and strips the padding from the yielded keys. Keys starting (Left) or ending with the padding byte are
+	// rejected with ErrAmbiguousPadding. nil = keys must have a common size.
+	KeyPadding *KeyPadding
+	// Duplicates resolves a primary key added again to a Rebuilder, or yielded twice by the source of its rebuild.
+	// Default = DuplicateOverwrite.
+	Duplicates DuplicatePolicy
+	// HashInputs records a content hash of the primary keys and the words returned by the getter of every row,
+	// before any normalization, read back by Index.InputHash to verify the index against a dataset snapshot.
+	HashInputs bool
+	// IndexUnstemmed indexes the words of the Analyzer without stemming too, enabling LookupOpts.DisableStemming at the
+	// cost of a larger index. The Analyzer must implement UnstemmedAnalyzer.
+	IndexUnstemmed bool
+	// DualCase indexes every word lowercased and keeping its case, so lookups are case insensitive and
+	// LookupOpts.CaseSensitive lookups match the case, from a single index twice the size. Words lowercased by the
+	// Analyzer lose their case before.
+	DualCase bool
+	// checkpointFrom numbers the checkpoints of a resumed build after the existing ones
+	checkpointFrom int
+	// workers is the worker pool shared by the builds of a catalog, see Catalog.BuildOpts
+	workers chan struct{}
+	// normalizedWords marks the words of Frequencies, Weights and TermPayloads as normalized already, such as the
+	// values Compact carries over from the merged shards
+	normalizedWords bool
+
 	// detect badly configured opts
 	configured bool
 }
 
 var ErrNonuniform = fmt.Errorf("nonuniform_key_size")
 var ErrNilGetter = fmt.Errorf("nil_getter")
+var ErrGetterTimeout = fmt.Errorf("getter_timeout")
 
 // Append is O(1) but NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+// The appended shards start a new generation, see DiffSince.
 func (i *Index) Append(j *Index) *Index {
-	i.private = append(i.private, j.private...)
+	generation := i.generation + 1
+	for _, p := range j.private {
+		p.Generation = generation
+		if len(p.Deleted) > 0 {
+			deleted := make(map[string]uint64, len(p.Deleted))
+			for pk := range p.Deleted {
+				deleted[pk] = generation
+			}
+			p.Deleted = deleted
+		}
+		p.Checksum = 0
+		i.private = append(i.private, p)
+		i.track(&i.private[len(i.private)-1])
+	}
+	i.generation = generation
+	if i.cache != nil {
+		i.cache.purge()
+	}
 	return i
 }
 
 // New creates new full text index based on primary keys with common size of every string primary key.
 // Getter iterates the storage based on primary keys and returns the words in the row with primaryKey. Opts can be nil.
-func New[V struct{} | BagOfWords | []string](opts *NewOpts, data map[string]V, getter func(primaryKey string) BagOfWords) (i *Index, err error) {
+func New[V struct{} | BagOfWords | []string | map[string]int | map[string]float32](opts *NewOpts, data map[string]V, getter func(primaryKey string) BagOfWords) (i *Index, err error) {
 	var syncGetter = getter
+	var timedOut atomic.Bool
 	if opts == nil || opts.configured == false {
 		// defaults
 		opts = NewDefaultOpts()
 	}
-	for opts.BucketingExponent > 0 && (len(data)>>opts.BucketingExponent) < int(opts.MinShards) {
-		opts.BucketingExponent--
+	var optsCopy = *opts
+	opts = &optsCopy
+	if err := opts.Validate(); err != nil {
+		return nil, err
 	}
 	if getter == nil {
 		vType := reflect.TypeOf(data[""])
@@ -92,6 +349,40 @@ func New[V struct{} | BagOfWords | []string](opts *NewOpts, data map[string]V, g
 				return bag
 			}
 			syncGetter = getter // can be async always, we own the data
+		} else if vType == reflect.TypeOf(map[string]int(nil)) {
+			dataClone := data
+			frequencies := func(pk string) map[string]int {
+				return (interface{}(dataClone[pk])).(map[string]int)
+			}
+			getter = func(pk string) BagOfWords {
+				var frequency = frequencies(pk)
+				var bag = make(BagOfWords, len(frequency))
+				for v := range frequency {
+					bag[v] = struct{}{}
+				}
+				return bag
+			}
+			syncGetter = getter // can be async always, we own the data
+			if opts.Frequencies == nil {
+				opts.Frequencies = frequencies
+			}
+		} else if vType == reflect.TypeOf(map[string]float32(nil)) {
+			dataClone := data
+			weights := func(pk string) map[string]float32 {
+				return (interface{}(dataClone[pk])).(map[string]float32)
+			}
+			getter = func(pk string) BagOfWords {
+				var weight = weights(pk)
+				var bag = make(BagOfWords, len(weight))
+				for v := range weight {
+					bag[v] = struct{}{}
+				}
+				return bag
+			}
+			syncGetter = getter // can be async always, we own the data
+			if opts.Weights == nil {
+				opts.Weights = weights
+			}
 		} else {
 			dataClone := data
 			getter = func(pk string) BagOfWords {
@@ -99,102 +390,206 @@ func New[V struct{} | BagOfWords | []string](opts *NewOpts, data map[string]V, g
 			}
 			syncGetter = getter // can be async always, we own the data
 		}
-	} else if opts.Sync {
-		// must be sync (from one thread) because we are potentially calling into external resource
-		var mut sync.Mutex
-		syncGetter = func(pk string) (ret BagOfWords) {
-			mut.Lock()
-			ret = getter(pk)
-			mut.Unlock()
-			return
+	} else {
+		if opts.GetterTimeout > 0 {
+			getter = opts.deadline(getter, &timedOut)
+			syncGetter = getter
+		}
+		if opts.Sync {
+			// must be sync (from one thread) because we are potentially calling into external resource
+			var mut sync.Mutex
+			var rawGetter = getter
+			syncGetter = func(pk string) (ret BagOfWords) {
+				mut.Lock()
+				ret = rawGetter(pk)
+				mut.Unlock()
+				return
+			}
+		}
+	}
+	normalize, err := opts.normalizer()
+	if err != nil {
+		return nil, err
+	}
+	backend, ok := LookupFilterBackend(opts.FilterBackend)
+	if !ok {
+		return nil, ErrUnknownFilterBackend
+	}
+	var wordNormalize = normalize
+	if opts.normalizedWords {
+		wordNormalize = nil
+	}
+	var inputs = getter
+	if normalize != nil {
+		var rawGetter, rawSyncGetter = getter, syncGetter
+		getter = func(pk string) BagOfWords {
+			return normalize(rawGetter(pk))
+		}
+		syncGetter = func(pk string) BagOfWords {
+			return normalize(rawSyncGetter(pk))
+		}
+	}
+	if opts.CheckpointDir != "" {
+		if err := os.MkdirAll(opts.CheckpointDir, 0o755); err != nil {
+			return nil, err
 		}
 	}
 	var wg sync.WaitGroup
 	i = new(Index)
-	i.private = make([]index, (len(data)>>opts.BucketingExponent)+1, (len(data)>>opts.BucketingExponent)+1)
-	for current := range i.private {
-		i.private[current].Version = 2
-		i.private[current].MinWord = opts.MinWordLength
+	i.metrics = opts.Metrics
+	i.logger = opts.Logger
+	i.tracer = opts.Tracer
+	if opts.Tracer != nil {
+		_, span := opts.Tracer.Start(context.Background(), "fulltext.New")
+		defer func(rows int) {
+			span.SetAttribute("rows", rows)
+			span.SetAttribute("shards", len(i.private))
+			span.End()
+		}(len(data))
+	}
+	if opts.Logger != nil {
+		defer func(rows int, begun time.Time) {
+			if err == nil {
+				opts.Logger.Info("fulltext: index built", "rows", rows, "shards", len(i.private), "duration", time.Since(begun))
+			}
+		}(len(data), time.Now())
+	}
+	if opts.Metrics != nil {
+		defer func(rows int, begun time.Time) {
+			if err == nil {
+				opts.Metrics.Build(rows, len(i.private), time.Since(begun))
+			}
+		}(len(data), time.Now())
 	}
-	var ikeys = make(map[int]string, 1<<opts.BucketingExponent)
+	defer func(rows int, begun time.Time) {
+		if err == nil {
+			i.recordStats(opts, rows, time.Since(begun))
+		}
+	}(len(data), time.Now())
+	var seed = opts.HashSeed
+	if opts.RandomHashSeed {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		seed = binary.LittleEndian.Uint64(b[:]) | 1
+	}
+	var builtAt = time.Now().UnixNano()
+	var shards []*index
+	var p *index
+	var started time.Time
+	next := func() {
+		p = &index{Version: 3, MinWord: opts.MinWordLength, Fold: opts.ASCIIFold, Analyzer: opts.Analyzer, Seed: seed}
+		p.BuildID, p.BuiltAt = opts.BuildID, builtAt
+		p.Stride = opts.ShingleStride
+		p.Depth = opts.MaxBucketDepth
+		p.Backend, p.filters = opts.FilterBackend, backend
+		p.Padding = opts.KeyPadding.padding()
+		p.Unstemmed = opts.IndexUnstemmed
+		p.DualCase = opts.DualCase
+		if !opts.SkipLongWords {
+			p.Truncate = opts.MaxWordLength
+		}
+		shards = append(shards, p)
+		started = time.Now()
+	}
+	next()
+	target := opts.shardRows(len(data))
+	var ikeys = getKeys()
 	var keys_len int
-	var current int
-	countBag := make(map[string]uint64)
-	initialBag := make(map[string]uint64)
+	countBag := getBag()
+	initialBag := getBag()
 	for k := range data {
-		if keys_len == 0 {
+		if opts.KeyPadding != nil {
+			if opts.KeyPadding.ambiguous(k) {
+				return nil, ErrAmbiguousPadding
+			}
+		} else if keys_len == 0 {
 			keys_len = len(k)
 		} else if keys_len != len(k) {
 			return nil, ErrNonuniform
 		}
 		size := len(ikeys) + 1
 		ikeys[size] = k
-		bag := getter(k) // can be async here
+		bag := inputs(k) // can be async here
+		if opts.HashInputs {
+			p.addInputHash(k, bag)
+		}
+		if normalize != nil {
+			bag = normalize(bag)
+		}
+		if opts.Facets != nil {
+			p.addFacets(size, opts.Facets(k))
+		}
+		if opts.Frequencies != nil {
+			p.addFrequencies(size, opts.Frequencies(k), wordNormalize)
+		}
+		if opts.Weights != nil {
+			p.addWeights(size, opts.Weights(k), wordNormalize)
+		}
+		if opts.TermPayloads != nil {
+			p.addTermPayloads(size, bag, opts.TermPayloads(k), wordNormalize)
+		}
+		if opts.Payload != nil {
+			p.addPayload(size, opts.Payload(k))
+		}
+		if opts.Tokens != nil {
+			p.addTokens(size, opts.Tokens(k))
+		}
+		if opts.RoaringPostings > 0 {
+			p.addDocuments(bag)
+		}
+		if p.words == nil {
+			p.words = make(map[string]struct{})
+		}
 		for word := range bag {
-			if len(word) > i.private[current].Maxword {
-				i.private[current].Maxword = len(word)
+			p.words[word] = struct{}{}
+			if opts.StoreTerms && !marked(word) {
+				if p.Terms == nil {
+					p.Terms = make(map[string]uint64)
+				}
+				p.Terms[word]++
+			}
+			if len(word) > p.Maxword {
+				p.Maxword = len(word)
 			}
 			if len(word) < int(opts.MinWordLength) {
 				continue
 			}
-			for len(word)-int(opts.MinWordLength) >= len(i.private[current].Buckets) {
-				i.private[current].Buckets = append(i.private[current].Buckets, nil)
-				i.private[current].Counts = append(i.private[current].Counts, nil)
+			for len(word)-int(opts.MinWordLength) >= len(p.Buckets) && (p.Depth == 0 || len(p.Buckets) < p.Depth) {
+				p.Buckets = append(p.Buckets, nil)
+				p.Counts = append(p.Counts, nil)
 			}
-			wrd := word[0:int(opts.MinWordLength)]
-			countBag[wrd]++
-			cnt := countBag[wrd]
-			initialBag[wrd+fmt.Sprint(cnt)] = uint64(size)
+			if opts.BloomBitsPerShingle > 0 {
+				p.addShingles(word, int(opts.MinWordLength))
+			}
+			p.addPosition(countBag, initialBag, p.salted(word[0:int(opts.MinWordLength)]), uint64(size))
 		}
-		if (size >> opts.BucketingExponent) != 0 {
+		if size >= target || (opts.ShardBuildBudget > 0 && time.Since(started) >= opts.ShardBuildBudget) {
 			wg.Add(1)
-			go func(ikeys map[int]string, countBag map[string]uint64, initialBag map[string]uint64, current int) {
-				//println("flush", current)
-				i.private[current].Rows = uint64(len(ikeys))
-				for j := i.private[current].Rows; j > 0; j >>= 1 {
-					i.private[current].Logrows++
-				}
-				if i.private[current].Rows > 0 {
-					i.private[current].Pkbits = uint64(len(ikeys[1])) * 8
-				}
-				//println(i.private[current].Pkbits)
-				if i.private[current].Pkbits <= 255 {
-					i.private[current].Pk = quaternary.New(ikeys, byte(i.private[current].Pkbits), 0)
-				} else {
-					i.private[current].Pk = quaternary.New(ikeys, 0, 0)
-				}
-				i.private[current].Buckets[0] = quaternary.New(initialBag, i.private[current].Logrows, 0)
-				i.private[current].Counts[0] = quaternary.New(countBag, i.private[current].Logrows, opts.FalsePositiveFunctions)
+			go func(shard int, p *index, ikeys map[int]string, countBag map[string]uint64, initialBag map[string]uint64) {
+				release := opts.acquire()
+				p.flush(shard, ikeys, countBag, initialBag, opts)
+				release()
 				wg.Done()
-			}(ikeys, countBag, initialBag, current)
-			ikeys = make(map[int]string, 1<<opts.BucketingExponent)
-			countBag = make(map[string]uint64)
-			initialBag = make(map[string]uint64)
-			current++
+			}(len(shards)-1, p, ikeys, countBag, initialBag)
+			ikeys = getKeys()
+			countBag = getBag()
+			initialBag = getBag()
+			next()
 		}
 	}
 	data = nil
-	last := current
-	//println("flush", last, "last")
-	i.private[last].Rows = uint64(len(ikeys))
-	for j := i.private[last].Rows; j > 0; j >>= 1 {
-		i.private[last].Logrows++
-	}
-	if i.private[last].Rows > 0 {
-		i.private[last].Pkbits = uint64(len(ikeys[1])) * 8
-	}
-	if i.private[last].Pkbits <= 255 {
-		i.private[last].Pk = quaternary.New(ikeys, byte(i.private[last].Pkbits), 0)
-	} else {
-		i.private[last].Pk = quaternary.New(ikeys, 0, 0)
-	}
+	release := opts.acquire()
+	p.flush(len(shards)-1, ikeys, countBag, initialBag, opts)
+	release()
 	ikeys = nil
-	if len(i.private[last].Buckets) > 0 {
-		i.private[last].Buckets[0] = quaternary.New(initialBag, i.private[last].Logrows, 0)
-		i.private[last].Counts[0] = quaternary.New(countBag, i.private[last].Logrows, opts.FalsePositiveFunctions)
-	}
 	wg.Wait()
-	i.private = i.private[:last+1]
+	i.private = make([]index, len(shards))
+	for curr := range shards {
+		i.private[curr] = *shards[curr]
+	}
+	shards = nil
 	countBag = nil
 	initialBag = nil
 	var more bool
@@ -207,166 +602,704 @@ func New[V struct{} | BagOfWords | []string](opts *NewOpts, data map[string]V, g
 	if !more {
 		return
 	}
+	var checkpointErr error
+	var checkpointMut sync.Mutex
+	// finish builds the postings of a shard whose buckets are built, and checkpoints it
+	finish := func(curr int) {
+		if opts.RoaringPostings > 0 && !timedOut.Load() {
+			i.private[curr].buildPostings(opts.RoaringPostings)
+		}
+		if opts.CheckpointDir == "" || timedOut.Load() {
+			return
+		}
+		if err := i.private[curr].checkpoint(opts.CheckpointDir, opts.checkpointFrom+curr); err != nil {
+			checkpointMut.Lock()
+			checkpointErr = cmp.Or(checkpointErr, err)
+			checkpointMut.Unlock()
+		}
+	}
+	// pending counts the unfinished buckets of every shard, the last finished bucket finishes the shard
+	pending := make([]atomic.Int32, len(i.private))
 	wg = sync.WaitGroup{}
-	//println("Length", len(i.private))
 	for curr := range i.private {
-		//println("Maxword", i.private[curr].Maxword)
-		for q := 0; q+int(opts.MinWordLength) < i.private[curr].Maxword; q++ {
+		stride := i.private[curr].stride()
+		deepest := min(i.private[curr].Maxword-int(opts.MinWordLength), len(i.private[curr].Buckets)-1)
+		offsets := deepest / stride
+		if offsets <= 0 {
+			finish(curr)
+			continue
+		}
+		pending[curr].Store(int32(offsets))
+		var shardGetter = syncGetter
+		if opts.BulkGetter != nil {
+			if shardGetter, err = i.private[curr].bulk(opts.BulkGetter, normalize); err != nil {
+				wg.Wait()
+				return nil, err
+			}
+		} else if opts.BagCacheRows > 0 && offsets > 1 {
+			shardGetter = newBagCache(syncGetter, opts.BagCacheRows).get
+		}
+		for offset := stride; offset <= deepest; offset += stride {
 			wg.Add(1)
-			go func(curr, q int) {
-				countBag := make(map[string]uint64)
-				initialBag := make(map[string]uint64)
-				for j := uint64(1); j <= i.private[curr].Rows; j++ {
-					var k = string(quaternary.Get(i.private[curr].Pk, i.private[curr].Pkbits, j))
-					bag := syncGetter(k) // must be sync, firing from routines
-					for word := range bag {
-						//println("key:",k, word)
-						if len(word) <= int(opts.MinWordLength)+q {
-							continue
-						}
-						wrd := word[1+q : 1+int(opts.MinWordLength)+q]
-						countBag[wrd]++
-						cnt := countBag[wrd]
-						initialBag[wrd+fmt.Sprint(cnt)] = j
-					}
+			go func(curr, offset int) {
+				release := opts.acquire()
+				begun := time.Now()
+				i.private[curr].buildBucket(offset, opts.falsePositiveFunctions(offset), shardGetter) // must be sync, firing from routines
+				if opts.Logger != nil {
+					opts.Logger.Debug("fulltext: bucket built", "shard", curr, "offset", offset,
+						"bytes", len(i.private[curr].Buckets[offset])+len(i.private[curr].Counts[offset]), "duration", time.Since(begun))
+				}
+				if pending[curr].Add(-1) == 0 {
+					finish(curr)
 				}
-				i.private[curr].Buckets[1+q] = quaternary.New(initialBag, i.private[curr].Logrows, 0)
-				i.private[curr].Counts[1+q] = quaternary.New(countBag, i.private[curr].Logrows, opts.FalsePositiveFunctions)
+				release()
 				wg.Done()
-			}(curr, q)
+			}(curr, offset)
 		}
 	}
 	wg.Wait()
+	if timedOut.Load() {
+		return nil, ErrGetterTimeout
+	}
+	if checkpointErr != nil {
+		return nil, checkpointErr
+	}
 	return
 }
 
+// deadline wraps getter to give up on calls not returning within GetterTimeout after GetterRetries retries,
+// setting timedOut and returning no words instead
+func (opts *NewOpts) deadline(getter func(primaryKey string) BagOfWords, timedOut *atomic.Bool) func(primaryKey string) BagOfWords {
+	return func(pk string) BagOfWords {
+		for attempt := 0; attempt <= opts.GetterRetries && !timedOut.Load(); attempt++ {
+			result := make(chan BagOfWords, 1)
+			go func() {
+				result <- getter(pk)
+			}()
+			timer := time.NewTimer(opts.GetterTimeout)
+			select {
+			case bag := <-result:
+				timer.Stop()
+				return bag
+			case <-timer.C:
+			}
+		}
+		timedOut.Store(true)
+		return nil
+	}
+}
+
+// acquire takes a worker of the shared build pool, if any, returning the function releasing it
+func (opts *NewOpts) acquire() (release func()) {
+	if opts.workers == nil {
+		return func() {}
+	}
+	opts.workers <- struct{}{}
+	return func() { <-opts.workers }
+}
+
+// falsePositiveFunctions returns the false positive functions of the bucket at offset
+func (opts *NewOpts) falsePositiveFunctions(offset int) byte {
+	if len(opts.FalsePositiveFunctionsPerBucket) == 0 {
+		return opts.FalsePositiveFunctions
+	}
+	return opts.FalsePositiveFunctionsPerBucket[min(offset, len(opts.FalsePositiveFunctionsPerBucket)-1)]
+}
+
+// shardRows returns the number of rows collected into a shard before it is flushed
+func (opts *NewOpts) shardRows(rows int) int {
+	if opts.TargetShardRows > 0 {
+		target := opts.TargetShardRows
+		for target > 1 && rows/target < int(opts.MinShards) {
+			target >>= 1
+		}
+		return target
+	}
+	exponent := opts.BucketingExponent
+	for exponent > 0 && (rows>>exponent) < int(opts.MinShards) {
+		exponent--
+	}
+	return 1 << exponent
+}
+
+// flush builds the primary key filter, the first bucket and the bloom filter of a fully collected shard
+func (p *index) flush(shard int, ikeys map[int]string, countBag, initialBag map[string]uint64, opts *NewOpts) {
+	if opts.Logger != nil {
+		defer func(begun time.Time) {
+			opts.Logger.Debug("fulltext: shard flushed", "shard", shard, "rows", p.Rows,
+				"pk_bytes", len(p.Pk), "bloom_bytes", len(p.Bloom), "duration", time.Since(begun))
+		}(time.Now())
+	}
+	p.Rows = uint64(len(ikeys))
+	if opts.MapShardRows > 0 && p.Rows <= uint64(opts.MapShardRows) && opts.FilterBackend == "" {
+		p.Backend, p.filters = mapBackendName, mapBackend{}
+	}
+	var longest int
+	for _, key := range ikeys {
+		longest = max(longest, len(key))
+	}
+	keys := make(map[string][]byte, len(ikeys))
+	for j, key := range ikeys {
+		keys[rowKey(uint64(j))] = []byte(p.pad(key, longest))
+	}
+	for j := p.Rows; j > 0; j >>= 1 {
+		p.Logrows++
+	}
+	if p.Rows > 0 {
+		p.Pkbits = uint64(longest) * 8
+	}
+	if p.Pkbits <= 255 {
+		p.Pk = p.backend().New(keys, byte(p.Pkbits), 0)
+	} else {
+		p.Pk = p.backend().New(keys, 0, 0)
+	}
+	if len(p.Buckets) > 0 {
+		p.Buckets[0] = p.backend().New(numbers(initialBag, p.Logrows), p.Logrows, 0)
+		p.Counts[0] = p.backend().New(numbers(countBag, p.Logrows), p.Logrows, opts.falsePositiveFunctions(0))
+	}
+	p.buildBloom(opts.BloomBitsPerShingle)
+	p.buildFrequencies()
+	p.buildWeights()
+	p.buildTermPayloads()
+	p.distinct, p.words = len(p.words), nil
+	putKeys(ikeys)
+	putBag(countBag)
+	putBag(initialBag)
+}
+
+// buildBucket fills Buckets[offset] and Counts[offset] from the shingles starting at offset of every word in the shard
+func (p *index) buildBucket(offset int, falsePositiveFunctions byte, getter func(primaryKey string) BagOfWords) {
+	minWord := int(p.MinWord)
+	countBag := getBag()
+	initialBag := getBag()
+	defer putBag(countBag)
+	defer putBag(initialBag)
+	for j := uint64(1); j <= p.Rows; j++ {
+		var k = p.key(j)
+		bag := getter(k)
+		for word := range bag {
+			if len(word) < minWord+offset {
+				continue
+			}
+			p.addPosition(countBag, initialBag, p.salted(word[offset:offset+minWord]), j)
+		}
+	}
+	p.Buckets[offset] = p.backend().New(numbers(initialBag, p.Logrows), p.Logrows, 0)
+	p.Counts[offset] = p.backend().New(numbers(countBag, p.Logrows), p.Logrows, falsePositiveFunctions)
+}
+
+// addPosition records row pos under the shingle wrd of a bucket. Words of a row sharing the shingle record the row once,
+// so the count never exceeds the rows of the shard, which would overflow the count filter.
+func (p *index) addPosition(countBag, initialBag map[string]uint64, wrd string, pos uint64) {
+	if cnt := countBag[wrd]; cnt > 0 && initialBag[p.counterKey(wrd, cnt)] == pos {
+		return
+	}
+	countBag[wrd]++
+	initialBag[p.counterKey(wrd, countBag[wrd])] = pos
+}
+
+// truncate cuts word to at most n bytes, backing off to the start of the rune the cut would split
+func truncate(word string, n int) string {
+	for n > 0 && !utf8.RuneStart(word[n]) {
+		n--
+	}
+	return word[:n]
+}
+
+// normalizer returns the transformation applied to every bag of words during build, nil if words are indexed as they are
+func (opts *NewOpts) normalizer() (func(BagOfWords) BagOfWords, error) {
+	var analyzer Analyzer
+	if opts.Analyzer != "" {
+		var ok bool
+		if analyzer, ok = LookupAnalyzer(opts.Analyzer); !ok {
+			return nil, ErrUnknownAnalyzer
+		}
+	}
+	var stemless UnstemmedAnalyzer
+	if opts.IndexUnstemmed {
+		var ok bool
+		if stemless, ok = analyzer.(UnstemmedAnalyzer); !ok {
+			return nil, &OptsError{Field: "IndexUnstemmed", Err: ErrConflictingOpts}
+		}
+	}
+	if opts.MaxWordLength <= 0 && !opts.ASCIIFold && analyzer == nil && !opts.DualCase {
+		return nil, nil
+	}
+	return func(bag BagOfWords) BagOfWords {
+		var out = make(BagOfWords, len(bag))
+		// add adds word behind mark, truncated or skipped when too long
+		add := func(word, mark string) {
+			if mark != "" && word == "" {
+				return
+			}
+			if opts.MaxWordLength > 0 && len(word) > opts.MaxWordLength {
+				if opts.SkipLongWords {
+					return
+				}
+				word = truncate(word, opts.MaxWordLength)
+			}
+			out[mark+word] = struct{}{}
+		}
+		for text := range bag {
+			var words = []string{text}
+			if analyzer != nil {
+				words = analyzer.Analyze(text)
+			}
+			var stemmed = len(words)
+			if stemless != nil {
+				words = append(words, stemless.AnalyzeUnstemmed(text)...)
+			}
+			for k, word := range words {
+				if opts.ASCIIFold {
+					word = FoldASCII(word)
+				}
+				if opts.DualCase {
+					if k < stemmed {
+						add(word, caseMark)
+					}
+					word = strings.ToLower(word)
+				}
+				if k >= stemmed {
+					add(word, unstemmedMark)
+				} else {
+					add(word, "")
+				}
+			}
+		}
+		return out
+	}, nil
+}
+
+// query applies the build time word transformations of the shard to a looked up word. Words marked by
+// LookupOpts.DisableStemming are analyzed without stemming in shards indexing the unstemmed words, and words marked by
+// LookupOpts.CaseSensitive keep their case in shards indexed with NewOpts.DualCase.
+func (p *index) query(word string) string {
+	unstemmed, ok := strings.CutPrefix(word, unstemmedMark)
+	if ok {
+		word = unstemmed
+	}
+	cased, sensitive := strings.CutPrefix(word, caseMark)
+	if sensitive {
+		word = cased
+	}
+	ok = ok && p.Unstemmed
+	sensitive = sensitive && p.DualCase && !ok
+	if p.Analyzer != "" {
+		word = analyzeQuery(p.Analyzer, word, ok)
+	}
+	if p.Fold {
+		word = FoldASCII(word)
+	}
+	if p.DualCase && !sensitive {
+		word = strings.ToLower(word)
+	}
+	if p.Truncate > 0 && len(word) > p.Truncate {
+		word = truncate(word, p.Truncate)
+	}
+	// the unstemmed and the case sensitive words are indexed behind their marks, shards without them are looked up
+	// stemmed and as built
+	switch {
+	case word == "":
+	case ok:
+		word = unstemmedMark + word
+	case sensitive:
+		word = caseMark + word
+	}
+	return word
+}
+
+// minWord returns the shingle length of the shard, Version 1 shards always use 3
+func (p *index) minWord() int {
+	if p.Version <= 1 {
+		return 3
+	}
+	return int(p.MinWord)
+}
+
+// count returns the number of rows having term at the word offset of bucket, zero when the bucket is empty
+func (p *index) count(bucket int, term string) uint64 {
+	if bucket >= len(p.Buckets) {
+		return 0
+	}
+	if p.Version <= 1 {
+		if len(p.Buckets[bucket]) < 2 {
+			return 0
+		}
+		return p.backend().GetNum(p.Buckets[bucket], uint64(p.Logrows), term+"0")
+	}
+	if len(p.Counts[bucket]) < 2 {
+		return 0
+	}
+	return p.backend().GetNum(p.Counts[bucket], uint64(p.Logrows), p.salted(term))
+}
+
+// position returns the row of the c-th occurrence of term in bucket, 0 if none
+func (p *index) position(bucket int, term string, c uint64) uint64 {
+	return p.backend().GetNum(p.Buckets[bucket], uint64(p.Logrows), p.counterKey(p.salted(term), c))
+}
+
+// positions appends the rows of the occurrences 1 to count of term in bucket to into, 0 for unresolved occurrences.
+// The salted key is built once and only its counter is rewritten per occurrence, resolving them all in one pass.
+func (p *index) positions(bucket int, term string, count uint64, into []uint64) []uint64 {
+	filter, bits, backend := p.Buckets[bucket], uint64(p.Logrows), p.backend()
+	key := p.salted(term)
+	if p.Version <= 2 {
+		for c := uint64(1); c <= count; c++ {
+			into = append(into, backend.GetNum(filter, bits, p.counterKey(key, c)))
+		}
+		return into
+	}
+	buf := make([]byte, len(key)+8)
+	copy(buf, key)
+	for c := uint64(1); c <= count; c++ {
+		binary.LittleEndian.PutUint64(buf[len(key):], c)
+		into = append(into, backend.GetNum(filter, bits, string(buf)))
+	}
+	return into
+}
+
+// counterKey appends the occurrence counter c to a bucket key, as fixed width binary since Version 3 and in decimal before
+func (p *index) counterKey(key string, c uint64) string {
+	if p.Version <= 2 {
+		return key + strconv.FormatUint(c, 10)
+	}
+	var counter [8]byte
+	binary.LittleEndian.PutUint64(counter[:], c)
+	var b strings.Builder
+	b.Grow(len(key) + len(counter))
+	b.WriteString(key)
+	b.Write(counter[:])
+	return b.String()
+}
+
+// salted prefixes a filter key with the hash seed of the shard, so colliding keys differ between seeds
+func (p *index) salted(key string) string {
+	if p.Seed == 0 {
+		return key
+	}
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], p.Seed)
+	var b strings.Builder
+	b.Grow(len(seed) + len(key))
+	b.Write(seed[:])
+	b.WriteString(key)
+	return b.String()
+}
+
 // Lookup iterates the fulltext search index based on a specific word with length of opts.MinWordLength characters or more.
 // Exact finds exact word matches (faster). Dedup hits each primary key exactly once (slower, but can be worth it if db is slow).
 // Iterator can (in rare cases) have false positives.
 func (i *Index) Lookup(word string, exact, dedup bool) func(yield func(primaryKey string) bool) {
-	return func(yield func(string) bool) {
-		var wg sync.WaitGroup
-		var yielded bool
-		var yieldMu sync.RWMutex
-		for curr := range i.private {
-			var minWord int
-			if i.private[curr].Version <= 1 {
-				minWord = 3
-			} else {
-				minWord = int(i.private[curr].MinWord)
+	return i.LookupContext(context.Background(), word, exact, dedup)
+}
+
+// key decodes the primary key of row pos
+func (p *index) key(pos uint64) string {
+	return p.unpad(string(p.backend().Get(p.Pk, p.Pkbits, rowKey(pos))))
+}
+
+// lookup calls hit with the shard and row of every candidate until hit returns false, see Lookup.
+// Shards are probed concurrently, but hit is only called from the calling goroutine.
+func (i *Index) lookup(word string, exact, dedup bool, hit func(shard int, pos uint64) bool) {
+	i.lookupContext(context.Background(), word, exact, dedup, 0, hit)
+}
+
+// lookupContext is lookup stopping early once ctx is done, with the spans of the lookup parented under ctx.
+// A positive coverage replaces the deduplicated match threshold by that fraction of the query shingles, see LookupOpts.MinCoverage.
+func (i *Index) lookupContext(ctx context.Context, word string, exact, dedup bool, coverage float64, hit func(shard int, pos uint64) bool) {
+	i.lookupCoverage(ctx, word, exact, dedup, coverage, func(shard int, pos uint64, _ float64) bool {
+		return hit(shard, pos)
+	})
+}
+
+// lookupCoverage is lookupContext passing hit the fraction of the query shingles a deduplicated row matched, 0 without dedup.
+// The words rewritten from word by the query middleware are probed together.
+func (i *Index) lookupCoverage(ctx context.Context, word string, exact, dedup bool, coverage float64, hit func(shard int, pos uint64, score float64) bool) {
+	i.lookupWithin(ctx, word, exact, dedup, coverage, nil, hit)
+}
+
+// lookupWithin is lookupCoverage aborting once the lookup exceeds limit, which records the error. Limit can be nil.
+func (i *Index) lookupWithin(ctx context.Context, word string, exact, dedup bool, coverage float64, limit *budget, hit func(shard int, pos uint64, score float64) bool) {
+	i.lookupVisible(ctx, word, exact, dedup, coverage, limit, i.visible, nil, hit)
+}
+
+// lookupVisible is lookupWithin hiding the rows visible rejects instead of those of WithVisibility, nil hides none.
+// The shards skipped and probed are recorded in explain, see Explain, nil records nothing.
+func (i *Index) lookupVisible(ctx context.Context, word string, exact, dedup bool, coverage float64, limit *budget, visible func(primaryKey string) bool, explain *Explanation, hit func(shard int, pos uint64, score float64) bool) {
+	if limit != nil {
+		limit.err = nil
+	}
+	if i.metrics != nil {
+		i.metrics.Lookup()
+	}
+	var probed, yielded int
+	if i.queryLog != nil {
+		defer func(begun time.Time) {
+			if latency := time.Since(begun); latency >= i.slowQuery {
+				i.queryLog.LogQuery(QueryRecord{Word: word, Exact: exact, Dedup: dedup, Coverage: coverage,
+					Shards: probed, Hits: yielded, Latency: latency})
+			}
+		}(time.Now())
+		var counted = hit
+		hit = func(shard int, pos uint64, score float64) bool {
+			yielded++
+			return counted(shard, pos, score)
+		}
+	}
+	if i.logger != nil {
+		defer func(begun time.Time) {
+			i.logger.Debug("fulltext: lookup", "word", word, "exact", exact, "dedup", dedup,
+				"shards_probed", probed, "duration", time.Since(begun))
+		}(time.Now())
+	}
+	if i.tracer != nil {
+		var span Span
+		ctx, span = i.tracer.Start(ctx, "fulltext.Lookup")
+		span.SetAttribute("word", word)
+		span.SetAttribute("exact", exact)
+		span.SetAttribute("dedup", dedup)
+		defer func() {
+			span.SetAttribute("shards_probed", probed)
+			span.End()
+		}()
+	}
+	if len(i.deleted) > 0 {
+		var live = hit
+		hit = func(shard int, pos uint64, score float64) bool {
+			return i.deletedAt(shard, pos) || live(shard, pos, score)
+		}
+	}
+	if visible != nil {
+		var shown = hit
+		hit = func(shard int, pos uint64, score float64) bool {
+			return !visible(i.private[shard].key(pos)) || shown(shard, pos, score)
+		}
+	}
+	var wg sync.WaitGroup
+	// shards push batches of hits into a bounded channel, merged by this goroutine, done is closed once hit stops the lookup
+	var hits = make(chan []scoredRow, hitBuffer)
+	var done = make(chan struct{})
+	var stopped = func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return ctx.Err() != nil
+		}
+	}
+	var send = func(batch []scoredRow) bool {
+		select {
+		case hits <- batch:
+			return true
+		case <-done:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+	var words = []string{word}
+	if len(i.middleware) > 0 {
+		words = i.rewrite(word)
+	}
+	// explained collects the shards of the explanation, each filled by its own probe
+	var explained []*ShardExplanation
+	var explainShard = func(shard int, query, skipped string) *ShardExplanation {
+		if explain == nil {
+			return nil
+		}
+		s := &ShardExplanation{Shard: shard, Query: query, Skipped: skipped}
+		explained = append(explained, s)
+		return s
+	}
+	if explain != nil {
+		explain.Words = words
+		defer func() {
+			for _, s := range explained {
+				explain.Shards = append(explain.Shards, *s)
 			}
-			if len(word) < minWord {
+		}()
+	}
+probe:
+	for _, word := range words {
+		for curr := range i.private {
+			var minWord = i.private[curr].minWord()
+			var query = i.private[curr].query(word)
+			if len(query) < minWord {
+				explainShard(curr, query, "short_query")
 				continue
 			}
 			if i.private[curr].Rows == 0 {
+				explainShard(curr, query, "empty")
+				continue
+			}
+			if !i.private[curr].routable(query, minWord, exact, dedup && coverage <= 0) {
+				explainShard(curr, query, "bloom")
 				continue
 			}
-			yieldMu.RLock()
-			if yielded {
-				yieldMu.RUnlock()
-				break
-			} else {
-				yieldMu.RUnlock()
+			if ctx.Err() != nil {
+				break probe
+			}
+			if limit != nil && limit.shardProbes > 0 && probed >= limit.shardProbes {
+				limit.err = &QueryLimitError{Limit: "MaxShardProbes", Max: limit.shardProbes}
+				break probe
 			}
+			if i.metrics != nil {
+				i.metrics.ShardProbe(curr)
+			}
+			probed++
 			wg.Add(1)
-			go func(current, minWord int) {
-				var uniq map[uint64]int
-				if dedup {
-					uniq = make(map[uint64]int)
+			current, word, shardExplain := curr, query, explainShard(curr, query, "")
+			i.spawn(current, func() {
+				defer wg.Done()
+				var buckets int
+				if i.tracer != nil {
+					_, span := i.tracer.Start(ctx, "fulltext.Shard")
+					span.SetAttribute("shard", current)
+					defer func() {
+						span.SetAttribute("buckets_probed", buckets)
+						span.End()
+					}()
 				}
-				for t := len(word) - minWord; t >= 0; t-- {
-					term := word[t : t+minWord]
-					var bucket int
-					if exact {
-						bucket = t
-					} else {
-						bucket = i.private[current].Maxword - minWord
-					}
-					for ; bucket >= 0; bucket-- {
-						if bucket >= len(i.private[current].Buckets) {
-							continue
-						}
-						yieldMu.RLock()
-						if yielded {
-							yieldMu.RUnlock()
-							wg.Done()
-							return
-						} else {
-							yieldMu.RUnlock()
-						}
-						var count uint64
-						if i.private[current].Version <= 1 {
-							if len(i.private[current].Buckets[bucket]) < 2 {
-								continue
-							}
-							count = quaternary.GetNum(i.private[current].Buckets[bucket], uint64(i.private[current].Logrows), term+"0")
-						} else {
-							if len(i.private[current].Counts[bucket]) < 2 {
-								continue
-							}
-							count = quaternary.GetNum(i.private[current].Counts[bucket], uint64(i.private[current].Logrows), term)
-						}
-						//println("Lookup:", string(term[:]) + "0", count)
-						if count == 0 {
-							continue
-						}
-						if count > i.private[current].Rows {
-							continue
-						}
-						//println(word, count, "results")
-						for c := uint64(1); c <= count; c++ {
-							pos := quaternary.GetNum(i.private[current].Buckets[bucket], uint64(i.private[current].Logrows), term+fmt.Sprint(c))
-							//println("Lookup:", string(term[:]) + fmt.Sprint(c), pos)
-							if pos == 0 {
-								//println("pos == 0")
-								continue
-							}
-							if pos > i.private[current].Rows {
-								//println("pos > rows")
-								continue
-							}
-							if dedup {
-								uniq[pos]++
-							} else {
-								//println(word, pos, "result")
-								var k = string(quaternary.Get(i.private[current].Pk, i.private[current].Pkbits, pos))
-								//println(string(term[:]), k, "yielded")
-								yieldMu.Lock()
-								if yielded || !yield(k) {
-									yielded = true
-									yieldMu.Unlock()
-									wg.Done()
-									return
-								} else {
-									yieldMu.Unlock()
-								}
-							}
-						}
-						if exact {
-							break
-						}
-					}
+				var falsePositive = func() {}
+				if i.metrics != nil {
+					falsePositive = func() { i.metrics.FalsePositive(current) }
 				}
-				if dedup {
-					for pos, v := range uniq {
-						if v+minWord >= len(word) {
-							var k = string(quaternary.Get(i.private[current].Pk, i.private[current].Pkbits, pos))
-							//println(string(term[:]), k, "yielded")
-							yieldMu.Lock()
-							if yielded || !yield(k) {
-								yielded = true
-								yieldMu.Unlock()
-								wg.Done()
-								return
-							} else {
-								yieldMu.Unlock()
-							}
-						}
+				var batch []scoredRow
+				buckets = i.private[current].probe(word, minWord, exact, dedup, coverage, func(pos uint64, score float64) bool {
+					batch = append(batch, scoredRow{row{current, pos}, score})
+					if len(batch) < hitBatch {
+						return true
 					}
+					full := batch
+					batch = nil
+					return send(full)
+				}, stopped, falsePositive, shardExplain)
+				if len(batch) > 0 && !stopped() {
+					send(batch)
 				}
-				wg.Done()
-			}(curr, minWord)
+			})
 		}
+	}
+	go func() {
 		wg.Wait()
+		close(hits)
+	}()
+	if limit != nil && limit.err != nil {
+		close(done)
+		for range hits {
+		}
+		return
 	}
+	// rows matched by several rewritten words are deduplicated
+	var seen map[row]struct{}
+	if dedup && len(words) > 1 {
+		seen = make(map[row]struct{})
+	}
+	var candidates int
+merge:
+	for batch := range hits {
+		for _, r := range batch {
+			if candidates++; limit != nil && limit.candidates > 0 && candidates > limit.candidates {
+				limit.err = &QueryLimitError{Limit: "MaxCandidates", Max: limit.candidates}
+				close(done)
+				break merge
+			}
+			if seen != nil {
+				if _, ok := seen[r.row]; ok {
+					continue
+				}
+				seen[r.row] = struct{}{}
+			}
+			if !hit(r.shard, r.pos, r.score) {
+				close(done)
+				break merge
+			}
+		}
+	}
+	// wait for the shards to stop, discarding what they pushed meanwhile
+	for range hits {
+	}
+}
+
+// probe sends the rows of the shard matching the query word, see lookupCoverage, returning the number of buckets probed.
+// Probing stops once send returns false or stopped returns true, falsePositive is called for every discarded filter answer.
+// The probes and candidate rows are recorded in explain, nil records nothing.
+func (p *index) probe(word string, minWord int, exact, dedup bool, coverage float64, send func(pos uint64, score float64) bool, stopped func() bool, falsePositive func(), explain *ShardExplanation) (buckets int) {
+	if exact && dedup && coverage <= 0 {
+		if posting := p.posting(word); posting != nil {
+			if explain != nil {
+				explain.Postings = true
+			}
+			posting.each(func(pos uint32) bool {
+				explain.candidate(p, uint64(pos), 1)
+				return !stopped() && send(uint64(pos), 1)
+			})
+			return
+		}
+	}
+	var uniq map[uint64]int
+	if dedup {
+		uniq = make(map[uint64]int)
+	}
+	var stride = p.stride()
+	var found []uint64
+	// every bucket is probed with all its shingles of word before moving on, keeping one filter hot in the cache
+	for bucket := min(p.Maxword-minWord, len(p.Buckets)-1); bucket >= 0; bucket-- {
+		if bucket%stride != 0 {
+			continue
+		}
+		first, last := len(word)-minWord, 0
+		if exact {
+			if bucket > first {
+				continue
+			}
+			first, last = bucket, bucket
+		}
+		for t := first; t >= last; t-- {
+			if stopped() {
+				return
+			}
+			buckets++
+			term := word[t : t+minWord]
+			count := p.count(bucket, term)
+			if count == 0 {
+				continue
+			}
+			explain.probed(bucket, term, count)
+			if count > p.Rows {
+				falsePositive()
+				explain.resolved(p, 0)
+				continue
+			}
+			found = p.positions(bucket, term, count, found[:0])
+			for _, pos := range found {
+				explain.resolved(p, pos)
+				if pos == 0 || pos > p.Rows {
+					falsePositive()
+					continue
+				}
+				if dedup {
+					uniq[pos]++
+				} else if !send(pos, 0) {
+					return
+				}
+			}
+		}
+	}
+	if dedup {
+		shingles := p.queryShingles(len(word), minWord, exact)
+		for pos, v := range uniq {
+			explain.candidate(p, pos, v)
+			if matched(v, shingles, coverage, stride > 1) && !send(pos, min(1, float64(v)/float64(shingles))) {
+				return
+			}
+		}
+	}
+	return
 }
+
+// hitBuffer bounds the batches of hits shards can push ahead of the yielder during a lookup
+const hitBuffer = 64
+
+// hitBatch is the number of hits a shard collects before pushing them to the yielder at once
+const hitBatch = 64
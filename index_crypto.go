@@ -0,0 +1,56 @@
+package fulltext
+
+import "bytes"
+import "crypto/aes"
+import "crypto/cipher"
+import "crypto/rand"
+import "fmt"
+
+var ErrDecryptionFailed = fmt.Errorf("decryption_failed")
+
+// encryptedMagic starts the authenticated header of encrypted indexes, followed by a format byte and the nonce
+var encryptedMagic = []byte("FTXE\x01")
+
+// SerializeEncrypted serializes to JSON and seals it with AES-GCM. The key must be 16, 24 or 32 bytes long.
+// The header is authenticated along with the ciphertext, so the vocabulary stays private on shared storage.
+func (idx *Index) SerializeEncrypted(key []byte) ([]byte, error) {
+	plain, err := idx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(encryptedMagic)+aead.NonceSize())
+	copy(header, encryptedMagic)
+	if _, err := rand.Read(header[len(encryptedMagic):]); err != nil {
+		return nil, err
+	}
+	return aead.Seal(header, header[len(encryptedMagic):], plain, header), nil
+}
+
+// DeserializeEncrypted opens data produced by SerializeEncrypted. Wrong keys and tampered data yield ErrDecryptionFailed.
+func (idx *Index) DeserializeEncrypted(data, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	headerLen := len(encryptedMagic) + aead.NonceSize()
+	if len(data) < headerLen || !bytes.HasPrefix(data, encryptedMagic) {
+		return ErrDecryptionFailed
+	}
+	plain, err := aead.Open(nil, data[len(encryptedMagic):headerLen], data[headerLen:], data[:headerLen])
+	if err != nil {
+		return ErrDecryptionFailed
+	}
+	return idx.Deserialize(plain)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
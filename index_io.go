@@ -1,25 +1,250 @@
 package fulltext
 
+import "encoding/binary"
 import "encoding/json"
 import "fmt"
+import "hash/crc32"
+import "runtime"
+import "sync"
 
 var ErrFormatVersionMismatch = fmt.Errorf("fulltext_format_version_mismatch")
+var ErrCorrupted = fmt.Errorf("fulltext_corrupted")
 
 // Serialize serializes to JSON
 func (idx *Index) Serialize() ([]byte, error) {
-	return json.Marshal(idx.private)
+	return json.Marshal(idx.checksummed())
 }
 
-// Deserialize deserializes from JSON
+// Deserialize deserializes from JSON, decoding the shards in parallel. Shards carrying a checksum are verified,
+// a mismatch is reported as a *ValidationError wrapping ErrCorrupted.
 func (idx *Index) Deserialize(data []byte) error {
-	err := json.Unmarshal(data, &(idx.private))
+	if err := idx.bounds().size(len(data)); err != nil {
+		return err
+	}
+	var raw []json.RawMessage
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	if max := idx.bounds().MaxShards; max > 0 && len(raw) > max {
+		return &LimitError{Shard: -1, Limit: "shards", Value: uint64(len(raw)), Max: uint64(max)}
+	}
+	shards := make([]index, len(raw))
+	err = parallel(len(raw), func(curr int) error {
+		return json.Unmarshal(raw[curr], &shards[curr])
+	})
 	if err != nil {
 		return err
 	}
-	for _, p := range idx.private {
-		if p.Version == 0 || p.Version > 2 {
+	idx.private = shards
+	return idx.loaded()
+}
+
+// parallel calls fn for every shard number below n on all cores, returning the error of the lowest failing shard
+func parallel(n int, fn func(curr int) error) error {
+	errs := make([]error, n)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := min(n, runtime.GOMAXPROCS(0)); w > 0; w-- {
+		wg.Add(1)
+		go func() {
+			for curr := range next {
+				errs[curr] = fn(curr)
+			}
+			wg.Done()
+		}()
+	}
+	for curr := 0; curr < n; curr++ {
+		next <- curr
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loaded checks the format versions, the structure and the checksums of freshly decoded shards
+func (idx *Index) loaded() error {
+	if err := idx.bounds().check(idx.private); err != nil {
+		return err
+	}
+	for curr := range idx.private {
+		if p := &idx.private[curr]; p.Version == 0 || p.Version > 3 {
 			return ErrFormatVersionMismatch
 		}
+		if err := idx.private[curr].resolveBackend(); err != nil {
+			err.Shard = curr
+			return err
+		}
+		if name := idx.private[curr].Analyzer; name != "" {
+			if _, ok := LookupAnalyzer(name); !ok {
+				return &ValidationError{Shard: curr, Field: "analyzer", Err: ErrUnknownAnalyzer}
+			}
+		}
+		if err := idx.private[curr].decodePostings(); err != nil {
+			err.Shard = curr
+			return err
+		}
+		// lookups index the filters by bucket and row without bounds checks, so malformed shards are never served
+		if err := idx.private[curr].validate(); err != nil {
+			err.Shard = curr
+			err.Err = fmt.Errorf("%w: %w", ErrCorrupted, err.Err)
+			return err
+		}
+	}
+	if err := idx.verify(); err != nil {
+		return err
 	}
+	idx.retrack()
 	return nil
 }
+
+// checksummed returns a copy of the shards with their checksums filled in
+func (idx *Index) checksummed() []index {
+	shards := make([]index, len(idx.private))
+	copy(shards, idx.private)
+	for curr := range shards {
+		shards[curr].Checksum = shards[curr].checksum()
+	}
+	return shards
+}
+
+// verify compares stored checksums against the shard contents
+func (idx *Index) verify() error {
+	return parallel(len(idx.private), func(curr int) error {
+		if idx.private[curr].Checksum == 0 {
+			return nil
+		}
+		if idx.private[curr].Checksum != idx.private[curr].checksum() {
+			return &ValidationError{Shard: curr, Field: "checksum", Err: ErrCorrupted}
+		}
+		return nil
+	})
+}
+
+// checksum computes the CRC32 of every shard field except the checksum itself
+func (p *index) checksum() uint32 {
+	var buf []byte
+	buf = append(buf, p.Version, p.Logrows, p.MinWord)
+	buf = binary.AppendUvarint(buf, p.Pkbits)
+	buf = binary.AppendUvarint(buf, p.Rows)
+	buf = binary.AppendVarint(buf, int64(p.Maxword))
+	h := crc32.NewIEEE()
+	h.Write(buf)
+	writeChunk := func(b []byte) {
+		h.Write(binary.AppendUvarint(nil, uint64(len(b))))
+		h.Write(b)
+	}
+	writeChunk(p.Pk)
+	h.Write(binary.AppendUvarint(nil, uint64(len(p.Buckets))))
+	for _, b := range p.Buckets {
+		writeChunk(b)
+	}
+	h.Write(binary.AppendUvarint(nil, uint64(len(p.Counts))))
+	for _, b := range p.Counts {
+		writeChunk(b)
+	}
+	for _, term := range sortedTerms(p.Terms) {
+		writeChunk([]byte(term))
+		h.Write(binary.AppendUvarint(nil, p.Terms[term]))
+	}
+	// fields added later are tagged and only hashed when set, keeping older checksums valid
+	writeOptional := func(tag byte, v uint64) {
+		if v != 0 {
+			h.Write(binary.AppendUvarint([]byte{tag}, v))
+		}
+	}
+	writeOptional(12, uint64(p.Truncate))
+	if p.Fold {
+		writeOptional(13, 1)
+	}
+	if p.Analyzer != "" {
+		h.Write([]byte{14})
+		writeChunk([]byte(p.Analyzer))
+	}
+	for _, name := range sortedFacets(p.Facets) {
+		h.Write([]byte{15})
+		writeChunk([]byte(name))
+		for _, value := range p.Facets[name] {
+			writeChunk([]byte(value))
+		}
+	}
+	if len(p.Payloads) > 0 {
+		h.Write([]byte{16})
+		for _, payload := range p.Payloads {
+			writeChunk(payload)
+		}
+	}
+	if len(p.Bloom) > 0 {
+		h.Write([]byte{17})
+		writeChunk(p.Bloom)
+	}
+	if len(p.Frequencies) > 0 {
+		h.Write([]byte{22})
+		writeChunk(p.Frequencies)
+	}
+	if len(p.Weights) > 0 {
+		h.Write([]byte{23})
+		writeChunk(p.Weights)
+	}
+	if p.BuildID != "" {
+		h.Write([]byte{24})
+		writeChunk([]byte(p.BuildID))
+	}
+	writeOptional(25, uint64(p.BuiltAt))
+	writeOptional(26, uint64(p.Stride))
+	for _, word := range sortedPostings(p.Postings) {
+		h.Write([]byte{27})
+		writeChunk([]byte(word))
+		writeChunk(p.Postings[word])
+	}
+	writeOptional(28, uint64(p.Depth))
+	if p.Backend != "" {
+		h.Write([]byte{29})
+		writeChunk([]byte(p.Backend))
+	}
+	if len(p.TermPayloads) > 0 {
+		h.Write([]byte{30})
+		writeChunk(p.TermPayloads)
+	}
+	if len(p.Padding) > 0 {
+		h.Write([]byte{31})
+		writeChunk(p.Padding)
+	}
+	if len(p.Stats) > 0 {
+		h.Write([]byte{32})
+		writeChunk(p.Stats)
+	}
+	if len(p.InputHash) > 0 {
+		h.Write([]byte{33})
+		writeChunk(p.InputHash)
+	}
+	if p.Unstemmed {
+		writeOptional(34, 1)
+	}
+	if p.DualCase {
+		writeOptional(35, 1)
+	}
+
+	writeOptional(18, p.Generation)
+	writeOptional(21, p.Seed)
+	if len(p.Tokens) > 0 {
+		h.Write([]byte{20})
+		for _, tokens := range p.Tokens {
+			h.Write(binary.AppendUvarint(nil, uint64(len(tokens))))
+			for _, token := range tokens {
+				writeChunk([]byte(token))
+			}
+		}
+	}
+	for _, pk := range sortedTerms(p.Deleted) {
+		h.Write([]byte{19})
+		writeChunk([]byte(pk))
+		h.Write(binary.AppendUvarint(nil, p.Deleted[pk]))
+	}
+	return h.Sum32()
+}
@@ -0,0 +1,364 @@
+package fulltext
+
+import "bytes"
+import "encoding/binary"
+import "fmt"
+
+var ErrMalformedProto = fmt.Errorf("malformed_protobuf")
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// SerializeProto serializes to the protobuf wire format described by fulltext.proto
+func (idx *Index) SerializeProto() ([]byte, error) {
+	var buf []byte
+	for _, p := range idx.checksummed() {
+		buf = appendProtoBytes(buf, 1, p.appendProto(nil))
+	}
+	return buf, nil
+}
+
+// DeserializeProto deserializes from the protobuf wire format described by fulltext.proto, decoding the shards in parallel.
+// Unknown fields are skipped, so newer writers remain readable.
+func (idx *Index) DeserializeProto(data []byte) error {
+	if err := idx.bounds().size(len(data)); err != nil {
+		return err
+	}
+	return idx.deserializeProto(bytes.Clone(data)) // shards keep subslices of the buffer
+}
+
+// deserializeProto decodes the shards as subslices of data, which must outlive the index
+func (idx *Index) deserializeProto(data []byte) error {
+	var raws [][]byte
+	err := walkProto(data, func(field, wire uint64, num uint64, raw []byte) error {
+		if field == 1 && wire == wireBytes {
+			raws = append(raws, raw)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	shards := make([]index, len(raws))
+	err = parallel(len(raws), func(curr int) error {
+		return shards[curr].unmarshalProto(raws[curr])
+	})
+	if err != nil {
+		return err
+	}
+	idx.private = shards
+	return idx.loaded()
+}
+
+func (p *index) appendProto(buf []byte) []byte {
+	buf = appendProtoVarint(buf, 1, uint64(p.Version))
+	buf = appendProtoBytes(buf, 2, p.Pk)
+	for _, b := range p.Buckets {
+		buf = appendProtoBytes(buf, 3, b)
+	}
+	for _, b := range p.Counts {
+		buf = appendProtoBytes(buf, 4, b)
+	}
+	buf = appendProtoVarint(buf, 5, p.Pkbits)
+	buf = appendProtoVarint(buf, 6, p.Rows)
+	buf = appendProtoVarint(buf, 7, uint64(p.Logrows))
+	buf = appendProtoVarint(buf, 8, uint64(int64(p.Maxword)))
+	buf = appendProtoVarint(buf, 9, uint64(p.MinWord))
+	for _, term := range sortedTerms(p.Terms) {
+		var entry []byte
+		entry = appendProtoBytes(entry, 1, []byte(term))
+		entry = appendProtoVarint(entry, 2, p.Terms[term])
+		buf = appendProtoBytes(buf, 11, entry)
+	}
+	buf = appendProtoVarint(buf, 12, uint64(p.Truncate))
+	if p.Fold {
+		buf = appendProtoVarint(buf, 13, 1)
+	}
+	if p.Analyzer != "" {
+		buf = appendProtoBytes(buf, 14, []byte(p.Analyzer))
+	}
+	for _, name := range sortedFacets(p.Facets) {
+		var column []byte
+		column = appendProtoBytes(column, 1, []byte(name))
+		for _, value := range p.Facets[name] {
+			column = appendProtoBytes(column, 2, []byte(value))
+		}
+		buf = appendProtoBytes(buf, 15, column)
+	}
+	for _, payload := range p.Payloads {
+		buf = appendProtoBytes(buf, 16, payload)
+	}
+	if len(p.Bloom) > 0 {
+		buf = appendProtoBytes(buf, 17, p.Bloom)
+	}
+	buf = appendProtoVarint(buf, 18, p.Generation)
+	if len(p.Frequencies) > 0 {
+		buf = appendProtoBytes(buf, 22, p.Frequencies)
+	}
+	if len(p.Weights) > 0 {
+		buf = appendProtoBytes(buf, 23, p.Weights)
+	}
+	if p.BuildID != "" {
+		buf = appendProtoBytes(buf, 24, []byte(p.BuildID))
+	}
+	buf = appendProtoVarint(buf, 25, uint64(p.BuiltAt))
+	buf = appendProtoVarint(buf, 26, uint64(p.Stride))
+	for _, word := range sortedPostings(p.Postings) {
+		var entry []byte
+		entry = appendProtoBytes(entry, 1, []byte(word))
+		entry = appendProtoBytes(entry, 2, p.Postings[word])
+		buf = appendProtoBytes(buf, 27, entry)
+	}
+	buf = appendProtoVarint(buf, 28, uint64(p.Depth))
+	if p.Backend != "" {
+		buf = appendProtoBytes(buf, 29, []byte(p.Backend))
+	}
+	if len(p.TermPayloads) > 0 {
+		buf = appendProtoBytes(buf, 30, p.TermPayloads)
+	}
+	if len(p.Padding) > 0 {
+		buf = appendProtoBytes(buf, 31, p.Padding)
+	}
+	if len(p.Stats) > 0 {
+		buf = appendProtoBytes(buf, 32, p.Stats)
+	}
+	if len(p.InputHash) > 0 {
+		buf = appendProtoBytes(buf, 33, p.InputHash)
+	}
+	if p.Unstemmed {
+		buf = appendProtoVarint(buf, 34, 1)
+	}
+	if p.DualCase {
+		buf = appendProtoVarint(buf, 35, 1)
+	}
+	buf = appendProtoVarint(buf, 21, p.Seed)
+	for _, pk := range sortedTerms(p.Deleted) {
+		buf = appendProtoBytes(buf, 19, appendTombstone(nil, pk, p.Deleted[pk]))
+	}
+	for _, tokens := range p.Tokens {
+		var list []byte
+		for _, token := range tokens {
+			list = appendProtoBytes(list, 1, []byte(token))
+		}
+		buf = appendProtoBytes(buf, 20, list)
+	}
+	if p.Checksum != 0 {
+		buf = binary.AppendUvarint(buf, 10<<3|wireFixed32)
+		buf = binary.LittleEndian.AppendUint32(buf, p.Checksum)
+	}
+	return buf
+}
+
+func (p *index) unmarshalProto(data []byte) error {
+	return walkProto(data, func(field, wire uint64, num uint64, raw []byte) error {
+		switch field {
+		case 1:
+			p.Version = byte(num)
+		case 2:
+			p.Pk = raw
+		case 3:
+			p.Buckets = append(p.Buckets, raw)
+		case 4:
+			p.Counts = append(p.Counts, raw)
+		case 5:
+			p.Pkbits = num
+		case 6:
+			p.Rows = num
+		case 7:
+			p.Logrows = byte(num)
+		case 8:
+			p.Maxword = int(int64(num))
+		case 9:
+			p.MinWord = byte(num)
+		case 10:
+			p.Checksum = uint32(num)
+		case 11:
+			var term string
+			var count uint64
+			err := walkProto(raw, func(field, wire uint64, num uint64, raw []byte) error {
+				switch field {
+				case 1:
+					term = string(raw)
+				case 2:
+					count = num
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if p.Terms == nil {
+				p.Terms = make(map[string]uint64)
+			}
+			p.Terms[term] = count
+		case 12:
+			p.Truncate = int(num)
+		case 13:
+			p.Fold = num != 0
+		case 14:
+			p.Analyzer = string(raw)
+		case 15:
+			var name string
+			var values []string
+			err := walkProto(raw, func(field, wire uint64, num uint64, raw []byte) error {
+				switch field {
+				case 1:
+					name = string(raw)
+				case 2:
+					values = append(values, string(raw))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if p.Facets == nil {
+				p.Facets = make(map[string][]string)
+			}
+			p.Facets[name] = values
+		case 16:
+			if len(raw) == 0 {
+				raw = nil
+			}
+			p.Payloads = append(p.Payloads, raw)
+		case 17:
+			p.Bloom = raw
+		case 18:
+			p.Generation = num
+		case 19:
+			pk, generation, err := parseTombstone(raw)
+			if err != nil {
+				return err
+			}
+			if p.Deleted == nil {
+				p.Deleted = make(map[string]uint64)
+			}
+			p.Deleted[pk] = generation
+		case 20:
+			var tokens []string
+			err := walkProto(raw, func(field, wire uint64, num uint64, raw []byte) error {
+				if field == 1 {
+					tokens = append(tokens, string(raw))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			p.Tokens = append(p.Tokens, tokens)
+		case 21:
+			p.Seed = num
+		case 22:
+			p.Frequencies = raw
+		case 23:
+			p.Weights = raw
+		case 24:
+			p.BuildID = string(raw)
+		case 25:
+			p.BuiltAt = int64(num)
+		case 26:
+			p.Stride = byte(num)
+		case 27:
+			var word string
+			var posting []byte
+			err := walkProto(raw, func(field, wire uint64, num uint64, raw []byte) error {
+				switch field {
+				case 1:
+					word = string(raw)
+				case 2:
+					posting = raw
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if p.Postings == nil {
+				p.Postings = make(map[string][]byte)
+			}
+			p.Postings[word] = posting
+		case 28:
+			p.Depth = int(num)
+		case 29:
+			p.Backend = string(raw)
+		case 30:
+			p.TermPayloads = raw
+		case 31:
+			p.Padding = raw
+		case 32:
+			p.Stats = raw
+		case 33:
+			p.InputHash = raw
+		case 34:
+			p.Unstemmed = num != 0
+		case 35:
+			p.DualCase = num != 0
+		}
+		return nil
+	})
+}
+
+func appendProtoVarint(buf []byte, field, v uint64) []byte {
+	if v == 0 {
+		return buf
+	}
+	buf = binary.AppendUvarint(buf, field<<3|wireVarint)
+	return binary.AppendUvarint(buf, v)
+}
+
+func appendProtoBytes(buf []byte, field uint64, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, field<<3|wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// walkProto calls fn for every field in data, with num holding numeric values and raw holding length delimited ones
+func walkProto(data []byte, fn func(field, wire uint64, num uint64, raw []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return ErrMalformedProto
+		}
+		data = data[n:]
+		var num uint64
+		var raw []byte
+		switch tag & 7 {
+		case wireVarint:
+			num, n = binary.Uvarint(data)
+			if n <= 0 {
+				return ErrMalformedProto
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return ErrMalformedProto
+			}
+			num = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return ErrMalformedProto
+			}
+			num = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return ErrMalformedProto
+			}
+			raw = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return ErrMalformedProto
+		}
+		if err := fn(tag>>3, tag&7, num, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
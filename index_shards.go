@@ -0,0 +1,110 @@
+package fulltext
+
+import "bytes"
+import "encoding/binary"
+import "fmt"
+
+var ErrMalformedHeader = fmt.Errorf("malformed_shard_header")
+var ErrShardOutOfRange = fmt.Errorf("shard_out_of_range")
+
+// shardedMagic starts the header of sharded indexes, followed by the shard count and the shard directory
+var shardedMagic = []byte("FTXS\x01")
+
+// shardedPrefix is the length of the magic and the uint32 shard count
+var shardedPrefix = len(shardedMagic) + 4
+
+// SerializeSharded serializes to a header holding a shard directory followed by every shard in the protobuf
+// wire format. The directory lists the fixed width end offsets of the shards, so readers can locate and load
+// only the shards they are assigned, see DeserializeShards.
+func (idx *Index) SerializeSharded() ([]byte, error) {
+	shards := idx.checksummed()
+	var body []byte
+	buf := append(bytes.Clone(shardedMagic), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(buf[len(shardedMagic):], uint32(len(shards)))
+	for curr := range shards {
+		body = shards[curr].appendProto(body)
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(len(body)))
+	}
+	return append(buf, body...), nil
+}
+
+// DeserializeSharded deserializes every shard of data produced by SerializeSharded
+func (idx *Index) DeserializeSharded(data []byte) error {
+	count, _, err := shardDirectory(data)
+	if err != nil {
+		return err
+	}
+	ids := make([]int, count)
+	for curr := range ids {
+		ids[curr] = curr
+	}
+	return idx.DeserializeShards(data, ids)
+}
+
+// DeserializeShards deserializes only the shards numbered shardIDs, in that order, from data produced by SerializeSharded.
+// This lets a horizontally scaled service load its assigned shard range from a shared index artifact.
+func (idx *Index) DeserializeShards(data []byte, shardIDs []int) error {
+	if err := idx.bounds().size(len(data)); err != nil {
+		return err
+	}
+	return idx.deserializeShards(data, shardIDs, false)
+}
+
+// deserializeShards decodes the shards numbered shardIDs, as subslices of data when shared and of copies otherwise
+func (idx *Index) deserializeShards(data []byte, shardIDs []int, shared bool) error {
+	count, dir, err := shardDirectory(data)
+	if err != nil {
+		return err
+	}
+	body := data[shardedPrefix+8*count:]
+	raws := make([][]byte, len(shardIDs))
+	for n, id := range shardIDs {
+		if id < 0 || id >= count {
+			return ErrShardOutOfRange
+		}
+		start, end := shardSpan(dir, id)
+		if start > end || end > uint64(len(body)) {
+			return ErrMalformedHeader
+		}
+		raws[n] = body[start:end]
+		if !shared {
+			raws[n] = bytes.Clone(raws[n]) // shards keep subslices of the buffer
+		}
+	}
+	shards := make([]index, len(raws))
+	err = parallel(len(raws), func(curr int) error {
+		return shards[curr].unmarshalProto(raws[curr])
+	})
+	if err != nil {
+		return err
+	}
+	idx.private = shards
+	return idx.loaded()
+}
+
+// shardCount parses the magic and the shard count at the start of the header
+func shardCount(data []byte) (int, error) {
+	if len(data) < shardedPrefix || !bytes.HasPrefix(data, shardedMagic) {
+		return 0, ErrMalformedHeader
+	}
+	return int(binary.LittleEndian.Uint32(data[len(shardedMagic):])), nil
+}
+
+// shardDirectory parses the header, returning the shard count and the raw directory of end offsets
+func shardDirectory(data []byte) (count int, dir []byte, err error) {
+	if count, err = shardCount(data); err != nil {
+		return 0, nil, err
+	}
+	if uint64(len(data)-shardedPrefix)/8 < uint64(count) {
+		return 0, nil, ErrMalformedHeader
+	}
+	return count, data[shardedPrefix : shardedPrefix+8*count], nil
+}
+
+// shardSpan returns the offsets of shard id within the body following the directory
+func shardSpan(dir []byte, id int) (start, end uint64) {
+	if id > 0 {
+		start = binary.LittleEndian.Uint64(dir[8*(id-1):])
+	}
+	return start, binary.LittleEndian.Uint64(dir[8*id:])
+}
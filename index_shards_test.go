@@ -0,0 +1,59 @@
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func newShardedTestIndex(t *testing.T) *Index {
+	data := make(map[string][]string)
+	for j := 0; j < 40; j++ {
+		data[fmt.Sprintf("doc:%03d", j)] = []string{"common", fmt.Sprintf("word%03d", j)}
+	}
+	opts := NewDefaultOpts()
+	opts.TargetShardRows = 10
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return idx
+}
+
+// TestDeserializeShards tests loading a subset of shards through the shard directory
+func TestDeserializeShards(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	data, err := idx.SerializeSharded()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var all Index
+	if err := all.DeserializeSharded(data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(all.private) != len(idx.private) {
+		t.Fatalf("expected %d shards, got %d", len(idx.private), len(all.private))
+	}
+
+	var part Index
+	if err := part.DeserializeShards(data, []int{2, 0}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(part.private) != 2 || part.private[0].Rows != idx.private[2].Rows || string(part.private[1].Pk) != string(idx.private[0].Pk) {
+		t.Fatal("expected shards 2 and 0 in the requested order")
+	}
+	var n int
+	for range part.Lookup("common", true, true) {
+		n++
+	}
+	if want := int(idx.private[0].Rows + idx.private[2].Rows); n != want {
+		t.Fatalf("expected %d results, got %d", want, n)
+	}
+
+	if err := part.DeserializeShards(data, []int{len(idx.private)}); !errors.Is(err, ErrShardOutOfRange) {
+		t.Fatalf("expected ErrShardOutOfRange, got %v", err)
+	}
+	if err := part.DeserializeShards(data[:8], []int{0}); !errors.Is(err, ErrMalformedHeader) {
+		t.Fatalf("expected ErrMalformedHeader, got %v", err)
+	}
+}
@@ -1,7 +1,14 @@
 package fulltext
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 // TestNewIndexCreation tests basic index creation with valid inputs
@@ -305,3 +312,416 @@ func TestSerialize(t *testing.T) {
 		t.Fatalf("expected no error, got %v", err)
 	}
 }
+
+// TestDeserializeCorrupted tests that a flipped byte is detected and the shard is named
+func TestDeserializeCorrupted(t *testing.T) {
+	pk := BagOfWords{"doc:1": struct{}{}, "doc:2": struct{}{}}
+	getter := func(key string) BagOfWords {
+		words := map[string]BagOfWords{
+			"doc:1": {"golang": struct{}{}, "backend": struct{}{}},
+			"doc:2": {"rust": struct{}{}, "backend": struct{}{}},
+		}
+		return words[key]
+	}
+
+	idx, err := New(nil, pk, getter)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data, _ := idx.Serialize()
+	var shards []index
+	_ = json.Unmarshal(data, &shards)
+	shards[1].Pk[0] ^= 0xff
+	data, _ = json.Marshal(shards)
+
+	var loaded Index
+	err = loaded.Deserialize(data)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !errors.Is(err, ErrCorrupted) || verr.Shard != 1 {
+		t.Fatalf("expected ErrCorrupted in shard 1, got %v", err)
+	}
+}
+
+// TestSerializeProto tests the protobuf round trip
+func TestSerializeProto(t *testing.T) {
+	pk := BagOfWords{"doc:1": struct{}{}, "doc:2": struct{}{}}
+	getter := func(key string) BagOfWords {
+		words := map[string]BagOfWords{
+			"doc:1": {"golang": struct{}{}, "backend": struct{}{}},
+			"doc:2": {"rust": struct{}{}, "backend": struct{}{}},
+		}
+		return words[key]
+	}
+
+	idx, err := New(nil, pk, getter)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data, err := idx.SerializeProto()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var loaded Index
+	if err := loaded.DeserializeProto(data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	results := make(map[string]struct{})
+	for pk := range loaded.Lookup("backend", true, true) {
+		results[pk] = struct{}{}
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if err := loaded.DeserializeProto(data[:len(data)-1]); err == nil {
+		t.Fatal("expected error for truncated data, got nil")
+	}
+}
+
+// TestSerializeEncrypted tests the encrypted round trip and rejection of a wrong key
+func TestSerializeEncrypted(t *testing.T) {
+	pk := BagOfWords{"doc:1": struct{}{}, "doc:2": struct{}{}}
+	getter := func(key string) BagOfWords {
+		words := map[string]BagOfWords{
+			"doc:1": {"golang": struct{}{}, "backend": struct{}{}},
+			"doc:2": {"rust": struct{}{}, "secret": struct{}{}},
+		}
+		return words[key]
+	}
+
+	idx, _ := New(nil, pk, getter)
+	key := []byte("0123456789abcdef0123456789abcdef")
+	data, err := idx.SerializeEncrypted(key)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var loaded Index
+	if err := loaded.DeserializeEncrypted(data, key); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	count := 0
+	for range loaded.Lookup("secret", true, true) {
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 result, got %d", count)
+	}
+	key[0] ^= 1
+	if err := loaded.DeserializeEncrypted(data, key); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("expected ErrDecryptionFailed, got %v", err)
+	}
+}
+
+// TestMaxWordLength tests truncating and skipping overlong words, never splitting a rune
+func TestMaxWordLength(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "short"},
+		"doc:2": {"supercalifragilistic", "short"},
+		"doc:3": {"ažžžžžžž"},
+	}
+	opts := NewDefaultOpts()
+	opts.MaxWordLength = 8
+	opts.StoreTerms = true
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for term := range idx.Terms() {
+		if !utf8.ValidString(term) {
+			t.Fatalf("expected valid UTF-8 terms, got %q", term)
+		}
+	}
+	results := []string{}
+	for pk := range idx.Lookup("ažžžžžžž", true, true) {
+		results = append(results, pk)
+	}
+	if len(results) != 1 || results[0] != "doc:3" {
+		t.Fatalf("expected doc:3 via truncated query, got %v", results)
+	}
+	for _, p := range idx.private {
+		if p.Maxword > 8 || len(p.Buckets) > 8-3+1 {
+			t.Fatalf("expected bounded buckets, got maxword %d with %d buckets", p.Maxword, len(p.Buckets))
+		}
+	}
+	results = results[:0]
+	for pk := range idx.Lookup("supercalifragilistic", true, true) {
+		results = append(results, pk)
+	}
+	if len(results) != 1 || results[0] != "doc:2" {
+		t.Fatalf("expected doc:2 via truncated query, got %v", results)
+	}
+
+	opts.SkipLongWords = true
+	idx, _ = New(opts, data, nil)
+	results = results[:0]
+	for pk := range idx.Lookup("supercalifragilistic", true, true) {
+		results = append(results, pk)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected skipped word not to be found, got %v", results)
+	}
+}
+
+// TestTargetShardRows tests that shards are cut at the target row count instead of the exponent
+func TestTargetShardRows(t *testing.T) {
+	data := make(map[string][]string)
+	for j := 0; j < 100; j++ {
+		data[fmt.Sprintf("doc:%03d", j)] = []string{"common", fmt.Sprintf("word%03d", j)}
+	}
+	opts := NewDefaultOpts()
+	opts.TargetShardRows = 10
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, p := range idx.private {
+		if p.Rows > 10 {
+			t.Fatalf("expected at most 10 rows per shard, got %d", p.Rows)
+		}
+	}
+	if len(idx.private) < 10 {
+		t.Fatalf("expected at least 10 shards, got %d", len(idx.private))
+	}
+	var n int
+	for range idx.Lookup("common", true, true) {
+		n++
+	}
+	if n != 100 {
+		t.Fatalf("expected 100 results, got %d", n)
+	}
+}
+
+// TestDeserializeParallel tests that many shards decode in parallel into the original order
+func TestDeserializeParallel(t *testing.T) {
+	data := make(map[string][]string)
+	for j := 0; j < 200; j++ {
+		data[fmt.Sprintf("doc:%03d", j)] = []string{"common", fmt.Sprintf("word%03d", j)}
+	}
+	opts := NewDefaultOpts()
+	opts.TargetShardRows = 4
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	jsonData, _ := idx.Serialize()
+	protoData, _ := idx.SerializeProto()
+	for _, decode := range []func(*Index) error{
+		func(loaded *Index) error { return loaded.Deserialize(jsonData) },
+		func(loaded *Index) error { return loaded.DeserializeProto(protoData) },
+	} {
+		var loaded Index
+		if err := decode(&loaded); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(loaded.private) != len(idx.private) {
+			t.Fatalf("expected %d shards, got %d", len(idx.private), len(loaded.private))
+		}
+		for curr := range loaded.private {
+			if loaded.private[curr].Rows != idx.private[curr].Rows || string(loaded.private[curr].Pk) != string(idx.private[curr].Pk) {
+				t.Fatalf("expected shard %d to keep its position", curr)
+			}
+		}
+	}
+}
+
+// TestHashSeed tests that seeded indexes differ in their filters yet find the same rows
+func TestHashSeed(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+	}
+	plain, _ := New(nil, data, nil)
+	opts := NewDefaultOpts()
+	opts.RandomHashSeed = true
+	seeded, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if seeded.private[0].Seed == 0 || string(seeded.private[0].Counts[0]) == string(plain.private[0].Counts[0]) {
+		t.Fatal("expected a random seed changing the filters")
+	}
+	serialized, _ := seeded.SerializeProto()
+	var loaded Index
+	if err := loaded.DeserializeProto(serialized); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, idx := range []*Index{seeded, &loaded} {
+		count := 0
+		for range idx.Lookup("backend", false, true) {
+			count++
+		}
+		if count != 2 {
+			t.Fatalf("expected 2 results, got %d", count)
+		}
+	}
+}
+
+func benchmarkData() map[string][]string {
+	data := make(map[string][]string)
+	for j := 0; j < 2000; j++ {
+		data[fmt.Sprintf("doc:%05d", j)] = []string{"common", fmt.Sprintf("word%05d", j), fmt.Sprintf("backend%03d", j%100)}
+	}
+	return data
+}
+
+// BenchmarkNew measures building an index, dominated by the bucket key encoding
+func BenchmarkNew(b *testing.B) {
+	data := benchmarkData()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := New(nil, data, nil); err != nil {
+			b.Fatalf("expected no error, got %v", err)
+		}
+	}
+}
+
+// BenchmarkLookup measures a lookup probing a bucket key per matching row
+func BenchmarkLookup(b *testing.B) {
+	idx, err := New(nil, benchmarkData(), nil)
+	if err != nil {
+		b.Fatalf("expected no error, got %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for range idx.Lookup("backend042", true, true) {
+		}
+	}
+}
+
+// BenchmarkLookupSubword measures a subword lookup probing every bucket with every shingle of the word
+func BenchmarkLookupSubword(b *testing.B) {
+	idx, err := New(nil, benchmarkData(), nil)
+	if err != nil {
+		b.Fatalf("expected no error, got %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for range idx.Lookup("kend042", false, true) {
+		}
+	}
+}
+
+// BenchmarkDelete measures deleting a row, looked up in the key table of every shard
+func BenchmarkDelete(b *testing.B) {
+	idx, err := New(nil, benchmarkData(), nil)
+	if err != nil {
+		b.Fatalf("expected no error, got %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		idx.Delete(fmt.Sprintf("doc:%05d", n%2000))
+	}
+}
+
+// TestPositions tests that resolving all occurrences of a shingle at once matches resolving them one by one
+func TestPositions(t *testing.T) {
+	idx, err := New(nil, benchmarkData(), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for curr := range idx.private {
+		p := &idx.private[curr]
+		count := p.count(0, "bac")
+		if count == 0 {
+			t.Fatalf("expected occurrences of bac in shard %d", curr)
+		}
+		found := p.positions(0, "bac", count, nil)
+		for c := uint64(1); c <= count; c++ {
+			if found[c-1] != p.position(0, "bac", c) {
+				t.Fatalf("expected position %d, got %d", p.position(0, "bac", c), found[c-1])
+			}
+		}
+	}
+}
+
+// TestLookupStopsEarly tests that breaking out of a lookup over many shards stops every shard goroutine
+func TestLookupStopsEarly(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	before := runtime.NumGoroutine()
+	for dedup := range 2 {
+		var n int
+		for range idx.Lookup("common", true, dedup == 1) {
+			n++
+			break
+		}
+		if n != 1 {
+			t.Fatalf("expected 1 result, got %d", n)
+		}
+	}
+	for wait := 0; wait < 100 && runtime.NumGoroutine() > before; wait++ {
+		time.Sleep(time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("expected at most %d goroutines, got %d", before, after)
+	}
+}
+
+// TestGetterTimeout tests that a hung getter call is retried, and fails the build once the retries are exhausted
+func TestGetterTimeout(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	pk := BagOfWords{"user:1": struct{}{}, "user:2": struct{}{}}
+	var calls atomic.Int32
+	getter := func(key string) BagOfWords {
+		if key == "user:2" && calls.Add(1) == 1 {
+			<-hang
+		}
+		return BagOfWords{"golang": struct{}{}}
+	}
+	opts := NewDefaultOpts()
+	opts.GetterTimeout = 10 * time.Millisecond
+	opts.GetterRetries = 1
+	idx, err := New(opts, pk, getter)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var n int
+	for range idx.Lookup("golang", true, true) {
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 results, got %d", n)
+	}
+
+	opts.GetterRetries = 0
+	calls.Store(0)
+	if _, err := New(opts, pk, getter); !errors.Is(err, ErrGetterTimeout) {
+		t.Fatalf("expected ErrGetterTimeout, got %v", err)
+	}
+}
+
+// TestFalsePositiveFunctionsPerBucket tests that bucket offsets get their own false positive functions
+func TestFalsePositiveFunctionsPerBucket(t *testing.T) {
+	opts := NewDefaultOpts()
+	opts.FalsePositiveFunctionsPerBucket = []byte{10, 1}
+	if opts.falsePositiveFunctions(0) != 10 || opts.falsePositiveFunctions(5) != 1 {
+		t.Fatal("expected offsets past the end to use the last entry")
+	}
+	data := map[string][]string{"doc:1": {"golang"}, "doc:2": {"backend"}}
+	accurate, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	opts.FalsePositiveFunctionsPerBucket = []byte{1}
+	cheap, _ := New(opts, data, nil)
+	size := func(idx *Index) (n int) {
+		for _, p := range idx.private {
+			if len(p.Counts) > 0 {
+				n += len(p.Counts[0])
+			}
+		}
+		return
+	}
+	if size(accurate) <= size(cheap) {
+		t.Fatal("expected more false positive functions to grow bucket 0")
+	}
+	var results []string
+	for pk := range accurate.Lookup("golang", true, true) {
+		results = append(results, pk)
+	}
+	if len(results) != 1 || results[0] != "doc:1" {
+		t.Fatalf("expected [doc:1], got %v", results)
+	}
+}
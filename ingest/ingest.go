@@ -0,0 +1,144 @@
+// package ingest builds fulltext indexes straight from export files, without converting them into a map[string]BagOfWords first.
+// CSV, JSON lines and Parquet are read natively, other formats plug in by implementing RecordReader.
+// Scraped HTML and Markdown pages are reduced to the words of their visible text by FromHTML and FromMarkdown.
+//
+//	f, _ := os.Open("products.csv")
+//	idx, err := ingest.NewFromCSV(f, "sku", "description", nil)
+package ingest
+
+import "encoding/csv"
+import "encoding/json"
+import "errors"
+import "fmt"
+import "io"
+import "github.com/neurlang/fulltext"
+import "github.com/neurlang/fulltext/analyzer"
+
+var ErrMissingColumn = fmt.Errorf("missing_column")
+
+// RecordReader reads the records of an export file one at a time, as column names mapped to values.
+// Read returns io.EOF once every record was read.
+type RecordReader interface {
+	Read() (map[string]string, error)
+}
+
+// NewFromRecords indexes the text column of every record under its primary key column. Opts can be nil.
+// The text is split by Words. Records repeating a primary key add to its words,
+// and primary keys must be of uniform size like in fulltext.New.
+func NewFromRecords(r RecordReader, pkColumn, textColumn string, opts *fulltext.NewOpts) (*fulltext.Index, error) {
+	data := make(map[string]fulltext.BagOfWords)
+	for {
+		pk, text, err := next(r, pkColumn, textColumn)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		bag := data[pk]
+		if bag == nil {
+			bag = make(fulltext.BagOfWords)
+			data[pk] = bag
+		}
+		for word := range Words(text, opts) {
+			bag[word] = struct{}{}
+		}
+	}
+	return fulltext.New(opts, data, nil)
+}
+
+// next reads the primary key and the text of the next record
+func next(r RecordReader, pkColumn, textColumn string) (pk, text string, err error) {
+	record, err := r.Read()
+	if err != nil {
+		return "", "", err
+	}
+	pk, ok := record[pkColumn]
+	if !ok {
+		return "", "", ErrMissingColumn
+	}
+	text, ok = record[textColumn]
+	if !ok {
+		return "", "", ErrMissingColumn
+	}
+	return pk, text, nil
+}
+
+// Words splits text into the bag of words to index, or keeps it whole for opts.Analyzer when set. Opts can be nil.
+func Words(text string, opts *fulltext.NewOpts) fulltext.BagOfWords {
+	if opts != nil && opts.Analyzer != "" {
+		return fulltext.BagOfWords{text: {}}
+	}
+	bag := make(fulltext.BagOfWords)
+	for _, word := range analyzer.Words(text) {
+		bag[word] = struct{}{}
+	}
+	return bag
+}
+
+// PadKeys returns opts, or the default options when nil, padding the primary keys with NUL bytes unless KeyPadding
+// is set, so tables keyed by integers or text of varying length can be indexed. Opts is not modified.
+func PadKeys(opts *fulltext.NewOpts) *fulltext.NewOpts {
+	if opts == nil {
+		opts = fulltext.NewDefaultOpts()
+	} else if opts.KeyPadding != nil {
+		return opts
+	}
+	padded := *opts
+	padded.KeyPadding = &fulltext.KeyPadding{Byte: 0}
+	return &padded
+}
+
+// NewFromCSV indexes CSV data whose first row names the columns, see NewFromRecords
+func NewFromCSV(r io.Reader, pkColumn, textColumn string, opts *fulltext.NewOpts) (*fulltext.Index, error) {
+	return NewFromRecords(&csvReader{r: csv.NewReader(r)}, pkColumn, textColumn, opts)
+}
+
+// NewFromJSONL indexes JSON lines holding one object per record, see NewFromRecords.
+// Values that are not strings are indexed in their JSON form.
+func NewFromJSONL(r io.Reader, pkField, textField string, opts *fulltext.NewOpts) (*fulltext.Index, error) {
+	return NewFromRecords(&jsonlReader{dec: json.NewDecoder(r)}, pkField, textField, opts)
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	header []string
+}
+
+func (c *csvReader) Read() (map[string]string, error) {
+	if c.header == nil {
+		header, err := c.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		c.header = header
+	}
+	row, err := c.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	record := make(map[string]string, len(row))
+	for n, value := range row {
+		record[c.header[n]] = value
+	}
+	return record, nil
+}
+
+type jsonlReader struct {
+	dec *json.Decoder
+}
+
+func (j *jsonlReader) Read() (map[string]string, error) {
+	var object map[string]json.RawMessage
+	if err := j.dec.Decode(&object); err != nil {
+		return nil, err
+	}
+	record := make(map[string]string, len(object))
+	for field, raw := range object {
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			s = string(raw)
+		}
+		record[field] = s
+	}
+	return record, nil
+}
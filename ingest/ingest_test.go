@@ -0,0 +1,53 @@
+package ingest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/neurlang/fulltext"
+)
+
+func lookupAll(idx *fulltext.Index, word string) (results []string) {
+	for pk := range idx.Lookup(word, true, true) {
+		results = append(results, pk)
+	}
+	return
+}
+
+// TestNewFromCSV tests indexing a CSV export by its header columns
+func TestNewFromCSV(t *testing.T) {
+	csv := "sku,description,price\n" +
+		"A1,\"golang backend, fast\",10\n" +
+		"B2,rust systems,20\n"
+	idx, err := NewFromCSV(strings.NewReader(csv), "sku", "description", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if results := lookupAll(idx, "backend"); len(results) != 1 || results[0] != "A1" {
+		t.Fatalf("expected [A1], got %v", results)
+	}
+	if _, err := NewFromCSV(strings.NewReader(csv), "id", "description", nil); !errors.Is(err, ErrMissingColumn) {
+		t.Fatalf("expected ErrMissingColumn, got %v", err)
+	}
+}
+
+// TestNewFromJSONL tests indexing JSON lines, merging the words of repeated primary keys
+func TestNewFromJSONL(t *testing.T) {
+	jsonl := `{"id": "doc:1", "text": "golang backend"}
+{"id": "doc:2", "text": "rust systems"}
+{"id": "doc:1", "text": "search engine"}
+`
+	idx, err := NewFromJSONL(strings.NewReader(jsonl), "id", "text", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, word := range []string{"golang", "engine"} {
+		if results := lookupAll(idx, word); len(results) != 1 || results[0] != "doc:1" {
+			t.Fatalf("expected [doc:1] for %s, got %v", word, results)
+		}
+	}
+	if _, err := NewFromJSONL(strings.NewReader(`{"id": `), "id", "text", nil); err == nil {
+		t.Fatal("expected an error for truncated JSON")
+	}
+}
@@ -0,0 +1,86 @@
+package ingest
+
+import "errors"
+import "io"
+import "maps"
+import "sort"
+import "strings"
+import "github.com/neurlang/fulltext"
+
+// LanguageFacet is the facet recording the detected language of every row, see NewFromRecordsByLanguage
+const LanguageFacet = "language"
+
+// LanguageDetector names the language of a text like the analyzer indexing it, such as "english", "" when unknown.
+// analyzer.NewDetector detects the languages of the analyzer subpackage.
+type LanguageDetector interface {
+	Detect(text string) string
+}
+
+// NewFromRecordsByLanguage is NewFromRecords for mixed language corpora. The language of every row is detected from its text
+// and recorded in the LanguageFacet. Rows of a language with an analyzer registered under its name are indexed by that
+// analyzer, the others by opts.Analyzer. Every language is built into its own shards of the one returned index, whose
+// lookups analyze the query like each shard was built. Opts can be nil.
+func NewFromRecordsByLanguage(r RecordReader, pkColumn, textColumn string, detector LanguageDetector, opts *fulltext.NewOpts) (*fulltext.Index, error) {
+	if opts == nil {
+		opts = fulltext.NewDefaultOpts()
+	}
+	texts := make(map[string][]string)
+	for {
+		pk, text, err := next(r, pkColumn, textColumn)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		texts[pk] = append(texts[pk], text)
+	}
+	languages := make(map[string]string, len(texts))
+	rows := make(map[string]map[string]fulltext.BagOfWords)
+	for pk, text := range texts {
+		joined := strings.Join(text, "\n")
+		language := detector.Detect(joined)
+		languages[pk] = language
+		if rows[language] == nil {
+			rows[language] = make(map[string]fulltext.BagOfWords)
+		}
+		rows[language][pk] = Words(joined, languageOpts(language, opts))
+	}
+	idx := new(fulltext.Index)
+	for _, language := range sortedLanguages(rows) {
+		languageOpts := *languageOpts(language, opts)
+		facets := opts.Facets
+		languageOpts.Facets = func(pk string) map[string]string {
+			values := make(map[string]string)
+			if facets != nil {
+				maps.Copy(values, facets(pk))
+			}
+			values[LanguageFacet] = languages[pk]
+			return values
+		}
+		part, err := fulltext.New(&languageOpts, rows[language], nil)
+		if err != nil {
+			return nil, err
+		}
+		idx.Append(part)
+	}
+	return idx, nil
+}
+
+// languageOpts returns opts analyzing by the analyzer registered under language, if there is one
+func languageOpts(language string, opts *fulltext.NewOpts) *fulltext.NewOpts {
+	if _, ok := fulltext.LookupAnalyzer(language); !ok || language == "" {
+		return opts
+	}
+	languageOpts := *opts
+	languageOpts.Analyzer = language
+	return &languageOpts
+}
+
+func sortedLanguages(rows map[string]map[string]fulltext.BagOfWords) []string {
+	languages := make([]string, 0, len(rows))
+	for language := range rows {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
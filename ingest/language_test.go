@@ -0,0 +1,35 @@
+package ingest
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/neurlang/fulltext/analyzer"
+)
+
+// TestNewFromRecordsByLanguage tests that every row is indexed by the analyzer of its language, recording the language facet
+func TestNewFromRecordsByLanguage(t *testing.T) {
+	jsonl := `{"id": "doc:1", "text": "the cats are running in the garden"}
+{"id": "doc:2", "text": "die Katzen laufen in den Gärten und sind nicht müde"}
+{"id": "doc:3", "text": "12345 67890"}
+`
+	idx, err := NewFromRecordsByLanguage(&jsonlReader{dec: json.NewDecoder(strings.NewReader(jsonl))}, "id", "text", analyzer.NewDetector(), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// english stems "running" to "runn", german folds and stems "Gärten" to "gart"
+	if results := lookupAll(idx, "running"); len(results) != 1 || results[0] != "doc:1" {
+		t.Fatalf("expected [doc:1], got %v", results)
+	}
+	if results := lookupAll(idx, "gärten"); len(results) != 1 || results[0] != "doc:2" {
+		t.Fatalf("expected [doc:2], got %v", results)
+	}
+	if results := lookupAll(idx, "12345"); len(results) != 1 || results[0] != "doc:3" {
+		t.Fatalf("expected [doc:3], got %v", results)
+	}
+	_, counts := idx.LookupFaceted("katzen", true)
+	if counts[LanguageFacet]["german"] != 1 || len(counts[LanguageFacet]) != 1 {
+		t.Fatalf("expected one german row, got %v", counts)
+	}
+}
@@ -0,0 +1,99 @@
+package ingest
+
+import "html"
+import "io"
+import "regexp"
+import "strings"
+import "github.com/neurlang/fulltext"
+
+// invisible are the elements whose content is not rendered as text
+var invisible = []string{"script", "style", "noscript", "template", "svg"}
+
+// htmlComment matches comments, htmlTag any start or end tag along with its attributes
+var htmlComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+var htmlTag = regexp.MustCompile(`(?s)</?[a-zA-Z!?][^>]*>`)
+
+// StripHTML returns the visible text of an HTML document or fragment: comments, tags with their attributes and the content
+// of scripts and styles are removed, and entities are decoded. Tags are replaced by spaces, so adjacent blocks do not join.
+func StripHTML(text string) string {
+	text = htmlComment.ReplaceAllString(text, " ")
+	for _, element := range invisible {
+		text = stripElement(text, element)
+	}
+	text = htmlTag.ReplaceAllString(text, " ")
+	return html.UnescapeString(text)
+}
+
+// stripElement removes every element named name along with its content, case insensitively
+func stripElement(text, name string) string {
+	lower := strings.ToLower(text)
+	var b strings.Builder
+	for {
+		start := strings.Index(lower, "<"+name)
+		if start < 0 || !tagEnds(lower, start+1+len(name)) {
+			if start >= 0 {
+				// a longer tag name sharing the prefix, such as <stylesheet>
+				b.WriteString(text[:start+1])
+				text, lower = text[start+1:], lower[start+1:]
+				continue
+			}
+			b.WriteString(text)
+			return b.String()
+		}
+		b.WriteString(text[:start])
+		b.WriteByte(' ')
+		end := strings.Index(lower[start:], "</"+name)
+		if end < 0 {
+			return b.String()
+		}
+		end += start
+		if close := strings.IndexByte(lower[end:], '>'); close >= 0 {
+			end += close + 1
+		} else {
+			end = len(lower)
+		}
+		text, lower = text[end:], lower[end:]
+	}
+}
+
+// tagEnds reports whether the tag name ends at pos
+func tagEnds(lower string, pos int) bool {
+	return pos >= len(lower) || strings.IndexByte(" \t\r\n/>", lower[pos]) >= 0
+}
+
+// markdownImage and markdownLink keep the text of images and links without their URL, markdownReference drops
+// reference definitions and markdownAutolink bare autolinks
+var markdownImage = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+var markdownLink = regexp.MustCompile(`\[([^\]]*)\](\([^)]*\)|\[[^\]]*\])`)
+var markdownReference = regexp.MustCompile(`(?m)^ {0,3}\[[^\]]+\]:\s*\S+.*$`)
+var markdownAutolink = regexp.MustCompile(`<[a-zA-Z][a-zA-Z0-9+.-]*:[^>\s]*>`)
+var markdownSyntax = regexp.MustCompile("(?m)^ {0,3}(#{1,6}|>+|[-*+]|\\d+[.)])\\s+|[*_~`]+")
+
+// StripMarkdown returns the visible text of a Markdown document: link and image URLs, reference definitions, inline HTML
+// and the heading, quote, list and emphasis markers are removed, keeping link texts, image descriptions and code.
+func StripMarkdown(text string) string {
+	text = markdownReference.ReplaceAllString(text, "")
+	text = markdownAutolink.ReplaceAllString(text, " ")
+	text = markdownImage.ReplaceAllString(text, "$1")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = StripHTML(text)
+	return markdownSyntax.ReplaceAllString(text, " ")
+}
+
+// FromHTML reads an HTML document and returns the words of its visible text, see StripHTML
+func FromHTML(r io.Reader) (fulltext.BagOfWords, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Words(StripHTML(string(raw)), nil), nil
+}
+
+// FromMarkdown reads a Markdown document and returns the words of its visible text, see StripMarkdown
+func FromMarkdown(r io.Reader) (fulltext.BagOfWords, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Words(StripMarkdown(string(raw)), nil), nil
+}
@@ -0,0 +1,51 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestFromHTML tests that only the visible text of a page is tokenized, with entities decoded
+func TestFromHTML(t *testing.T) {
+	page := `<!DOCTYPE html><html><head><title>Go &amp; Rust</title>
+<style type="text/css">.hidden { display: none }</style>
+<script>var tracking = "analytics";</script></head>
+<body class="container"><!-- sidebar comment --><h1 id="main">Backend<br/>services</h1>
+<p>Caf&eacute; <a href="https://example.com/hyperlink">guide</a></p><stylesheet>kept</stylesheet></body></html>`
+	bag, err := FromHTML(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, word := range []string{"Go", "Rust", "Backend", "services", "Café", "guide", "kept"} {
+		if _, ok := bag[word]; !ok {
+			t.Errorf("expected %q in %v", word, bag)
+		}
+	}
+	for _, word := range []string{"html", "title", "hidden", "tracking", "analytics", "container", "sidebar", "main", "href", "hyperlink", "amp"} {
+		if _, ok := bag[word]; ok {
+			t.Errorf("expected no %q in %v", word, bag)
+		}
+	}
+}
+
+// TestFromMarkdown tests that link URLs, reference definitions and markup are dropped while texts are kept
+func TestFromMarkdown(t *testing.T) {
+	doc := "# Getting started\n\n" +
+		"Read the [installation guide](https://example.com/install) and ![architecture diagram](diagram.png).\n" +
+		"> **Note:** use `go build` <kbd>now</kbd>, see [docs][ref] or <https://autolink.example>\n\n" +
+		"[ref]: https://reference.example/path\n"
+	bag, err := FromMarkdown(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, word := range []string{"Getting", "started", "installation", "guide", "architecture", "diagram", "Note", "go", "build", "now", "docs"} {
+		if _, ok := bag[word]; !ok {
+			t.Errorf("expected %q in %v", word, bag)
+		}
+	}
+	for _, word := range []string{"https", "example", "install", "png", "kbd", "ref", "autolink", "reference", "path"} {
+		if _, ok := bag[word]; ok {
+			t.Errorf("expected no %q in %v", word, bag)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package ingest
+
+import "io"
+import "strings"
+import "github.com/neurlang/fulltext"
+import "github.com/parquet-go/parquet-go"
+
+// NewFromParquet indexes the rows of a Parquet file of size bytes, see NewFromRecords. Columns of nested groups are
+// named by their dotted path, such as "product.sku", values that are not byte arrays are indexed in their text form,
+// the values of repeated columns are joined by spaces and null values are empty.
+func NewFromParquet(r io.ReaderAt, size int64, pkColumn, textColumn string, opts *fulltext.NewOpts) (*fulltext.Index, error) {
+	f, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return nil, err
+	}
+	var columns []string
+	for _, path := range f.Schema().Columns() {
+		columns = append(columns, strings.Join(path, "."))
+	}
+	reader := parquet.NewReader(f)
+	defer reader.Close()
+	return NewFromRecords(&parquetReader{r: reader, columns: columns, rows: make([]parquet.Row, 1)}, pkColumn, textColumn, opts)
+}
+
+type parquetReader struct {
+	r       *parquet.Reader
+	columns []string
+	rows    []parquet.Row
+}
+
+func (p *parquetReader) Read() (map[string]string, error) {
+	n, err := p.r.ReadRows(p.rows)
+	if n == 0 {
+		if err == nil {
+			err = io.EOF
+		}
+		return nil, err
+	}
+	record := make(map[string]string, len(p.columns))
+	for _, value := range p.rows[0] {
+		name := p.columns[value.Column()]
+		if value.IsNull() {
+			record[name] += ""
+			continue
+		}
+		if text, ok := record[name]; ok && text != "" {
+			record[name] = text + " " + value.String()
+		} else {
+			record[name] = value.String()
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,57 @@
+package ingest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type product struct {
+	SKU         string   `parquet:"sku"`
+	Description string   `parquet:"description,optional"`
+	Price       int64    `parquet:"price"`
+	Tags        []string `parquet:"tags,list"`
+}
+
+// TestNewFromParquet tests indexing a Parquet file by its column names, in text and other columns
+func TestNewFromParquet(t *testing.T) {
+	var buf bytes.Buffer
+	err := parquet.Write(&buf, []product{
+		{SKU: "A1", Description: "golang backend", Price: 10, Tags: []string{"fast", "server"}},
+		{SKU: "B2", Description: "rust systems", Price: 20},
+		{SKU: "C3", Price: 30, Tags: []string{"legacy"}},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	data := bytes.NewReader(buf.Bytes())
+	idx, err := NewFromParquet(data, data.Size(), "sku", "description", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if results := lookupAll(idx, "backend"); len(results) != 1 || results[0] != "A1" {
+		t.Fatalf("expected [A1], got %v", results)
+	}
+	idx, err = NewFromParquet(data, data.Size(), "sku", "tags.list.element", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if results := lookupAll(idx, "server"); len(results) != 1 || results[0] != "A1" {
+		t.Fatalf("expected [A1], got %v", results)
+	}
+	idx, err = NewFromParquet(data, data.Size(), "price", "description", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if results := lookupAll(idx, "systems"); len(results) != 1 || results[0] != "20" {
+		t.Fatalf("expected [20], got %v", results)
+	}
+	if _, err := NewFromParquet(data, data.Size(), "id", "description", nil); !errors.Is(err, ErrMissingColumn) {
+		t.Fatalf("expected ErrMissingColumn, got %v", err)
+	}
+	if _, err := NewFromParquet(bytes.NewReader([]byte("sku,description")), 15, "sku", "description", nil); err == nil {
+		t.Fatal("expected an error for a file that is not Parquet")
+	}
+}
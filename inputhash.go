@@ -0,0 +1,76 @@
+package fulltext
+
+import "crypto/sha256"
+import "encoding/binary"
+import "fmt"
+import "sort"
+
+var ErrNoInputHash = fmt.Errorf("no_input_hash")
+
+// addInputHash mixes the row primaryKey with its words, as returned by the getter, into the input hash of the shard
+func (p *index) addInputHash(primaryKey string, words BagOfWords) {
+	if p.InputHash == nil {
+		p.InputHash = make([]byte, sha256.Size)
+	}
+	sum := rowHash(primaryKey, words)
+	addHash(p.InputHash, sum[:])
+}
+
+// addHash adds sum to hash as big endian numbers modulo 2^256. Unlike xor, a row hashed twice does not cancel out.
+func addHash(hash, sum []byte) {
+	var carry uint16
+	for j := len(hash) - 1; j >= 0; j-- {
+		carry += uint16(hash[j]) + uint16(sum[j])
+		hash[j] = byte(carry)
+		carry >>= 8
+	}
+}
+
+// rowHash hashes a row independently of the order of its words, every string prefixed by its length
+func rowHash(primaryKey string, words BagOfWords) [sha256.Size]byte {
+	sorted := make([]string, 0, len(words))
+	for word := range words {
+		sorted = append(sorted, word)
+	}
+	sort.Strings(sorted)
+	h := sha256.New()
+	h.Write(binary.AppendUvarint(nil, uint64(len(primaryKey))))
+	h.Write([]byte(primaryKey))
+	for _, word := range sorted {
+		h.Write(binary.AppendUvarint(nil, uint64(len(word))))
+		h.Write([]byte(word))
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// HashRows returns the input hash of the rows of source, equal to the InputHash of an index built from them with
+// NewOpts.HashInputs, so CI can verify a serialized index against a dataset snapshot without rebuilding it.
+func HashRows(source RowSource) []byte {
+	hash := make([]byte, sha256.Size)
+	source(func(pk string, words BagOfWords) bool {
+		sum := rowHash(pk, words)
+		addHash(hash, sum[:])
+		return true
+	})
+	return hash
+}
+
+// InputHash returns a content hash of the (primary key, words) rows the shards were built from, independent of their
+// order and of the sharding, see NewOpts.HashInputs and HashRows. Shards added by Append contribute their rows, rows
+// deleted later still do. ErrNoInputHash is returned when a shard holding rows was built without HashInputs.
+func (i *Index) InputHash() ([]byte, error) {
+	hash := make([]byte, sha256.Size)
+	for curr := range i.private {
+		p := &i.private[curr]
+		if p.Rows == 0 {
+			continue
+		}
+		if len(p.InputHash) != sha256.Size {
+			return nil, ErrNoInputHash
+		}
+		addHash(hash, p.InputHash)
+	}
+	return hash, nil
+}
@@ -0,0 +1,70 @@
+package fulltext
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestInputHash tests that the input hash matches the rows regardless of sharding, appends and serialization, and that
+// repeated rows do not cancel out
+func TestInputHash(t *testing.T) {
+	data := make(map[string]BagOfWords)
+	for n := 0; n < 100; n++ {
+		data[fmt.Sprintf("doc:%02d", n)] = BagOfWords{"common": {}, fmt.Sprintf("word%02d", n): {}}
+	}
+	source := func(yield func(string, BagOfWords) bool) {
+		for pk, words := range data {
+			if !yield(pk, words) {
+				return
+			}
+		}
+	}
+	opts := NewDefaultOpts()
+	opts.HashInputs = true
+	opts.TargetShardRows = 30
+	opts.ASCIIFold = true
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	buf, err := idx.SerializeProto()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var loaded Index
+	if err := loaded.DeserializeProto(buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := HashRows(source)
+	if hash, err := loaded.InputHash(); err != nil || !bytes.Equal(hash, want) {
+		t.Fatalf("expected %x, got %x and %v", want, hash, err)
+	}
+	more, err := New(opts, map[string]BagOfWords{"doc:xx": {"Über": {}}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	loaded.Append(more)
+	data["doc:xx"] = BagOfWords{"Über": {}}
+	if hash, err := loaded.InputHash(); err != nil || !bytes.Equal(hash, HashRows(source)) {
+		t.Fatalf("expected the hash of the appended rows, got %x and %v", hash, err)
+	}
+	data["doc:00"] = BagOfWords{"changed": {}}
+	if hash, _ := loaded.InputHash(); bytes.Equal(hash, HashRows(source)) {
+		t.Fatalf("expected a different hash of changed rows")
+	}
+	twice := func(yield func(string, BagOfWords) bool) {
+		_ = yield("doc:00", data["doc:00"]) && yield("doc:00", data["doc:00"])
+	}
+	if hash := HashRows(twice); bytes.Equal(hash, HashRows(func(func(string, BagOfWords) bool) {})) {
+		t.Fatalf("expected a row hashed twice not to cancel out")
+	}
+	plain, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := plain.InputHash(); !errors.Is(err, ErrNoInputHash) {
+		t.Fatalf("expected ErrNoInputHash, got %v", err)
+	}
+}
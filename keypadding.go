@@ -0,0 +1,81 @@
+package fulltext
+
+import "fmt"
+
+var ErrAmbiguousPadding = fmt.Errorf("ambiguous_key_padding")
+
+// KeyPadding pads the shorter primary keys of a shard to its longest key with Byte, see NewOpts.KeyPadding
+type KeyPadding struct {
+	// Byte is appended to the keys, or prepended with Left, such as ' ' or '0'
+	Byte byte
+	Left bool
+}
+
+// padding encodes k as stored in index.Padding, nil = no padding
+func (k *KeyPadding) padding() []byte {
+	switch {
+	case k == nil:
+		return nil
+	case k.Left:
+		return []byte{'l', k.Byte}
+	default:
+		return []byte{'r', k.Byte}
+	}
+}
+
+// ambiguous reports whether key starts (Left) or ends with the padding byte, which unpadding would strip
+func (k *KeyPadding) ambiguous(key string) bool {
+	switch {
+	case k == nil || key == "":
+		return false
+	case k.Left:
+		return key[0] == k.Byte
+	default:
+		return key[len(key)-1] == k.Byte
+	}
+}
+
+// keyPadding decodes index.Padding, nil = no padding
+func keyPadding(padding []byte) *KeyPadding {
+	if len(padding) != 2 {
+		return nil
+	}
+	return &KeyPadding{Byte: padding[1], Left: padding[0] == 'l'}
+}
+
+// validPadding reports whether padding is empty or a side followed by the padding byte
+func validPadding(padding []byte) bool {
+	return len(padding) == 0 || (len(padding) == 2 && (padding[0] == 'l' || padding[0] == 'r'))
+}
+
+// pad pads key to length bytes
+func (p *index) pad(key string, length int) string {
+	if len(p.Padding) == 0 || len(key) >= length {
+		return key
+	}
+	fill := make([]byte, length-len(key))
+	for j := range fill {
+		fill[j] = p.Padding[1]
+	}
+	if p.Padding[0] == 'l' {
+		return string(fill) + key
+	}
+	return key + string(fill)
+}
+
+// unpad strips the padding of the shard from key
+func (p *index) unpad(key string) string {
+	if len(p.Padding) == 0 {
+		return key
+	}
+	if p.Padding[0] == 'l' {
+		for len(key) > 0 && key[0] == p.Padding[1] {
+			key = key[1:]
+		}
+		return key
+	}
+	for len(key) > 0 && key[len(key)-1] == p.Padding[1] {
+		key = key[:len(key)-1]
+	}
+	return key
+}
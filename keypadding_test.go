@@ -0,0 +1,52 @@
+package fulltext
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestKeyPadding tests that keys of different sizes are padded and yielded unpadded, also after serialization
+func TestKeyPadding(t *testing.T) {
+	data := map[string][]string{
+		"7":    {"golang", "backend"},
+		"42":   {"rust", "backend"},
+		"1337": {"python"},
+	}
+	if _, err := New(nil, data, nil); !errors.Is(err, ErrNonuniform) {
+		t.Fatalf("expected ErrNonuniform without padding, got %v", err)
+	}
+	for _, padding := range []KeyPadding{{Byte: '0', Left: true}, {Byte: ' '}} {
+		opts := NewDefaultOpts()
+		opts.KeyPadding = &padding
+		idx, err := New(opts, data, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		buf, err := idx.SerializeProto()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var loaded Index
+		if err := loaded.DeserializeProto(buf); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := loaded.Validate(); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if keys := lookupAll(&loaded, "backend"); !slices.Equal(keys, []string{"42", "7"}) {
+			t.Fatalf("expected [42 7] with padding %+v, got %v", padding, keys)
+		}
+		if err := loaded.Update("123456", BagOfWords{"golang": {}}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if keys := lookupAll(&loaded, "golang"); !slices.Equal(keys, []string{"123456", "7"}) {
+			t.Fatalf("expected [123456 7] after update, got %v", keys)
+		}
+	}
+	opts := NewDefaultOpts()
+	opts.KeyPadding = &KeyPadding{Byte: '0', Left: true}
+	if _, err := New(opts, map[string][]string{"07": {"golang"}, "1": {"rust"}}, nil); !errors.Is(err, ErrAmbiguousPadding) {
+		t.Fatalf("expected ErrAmbiguousPadding, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package fulltext
+
+import "fmt"
+import "math/bits"
+
+var ErrExceedsLimits = fmt.Errorf("exceeds_limits")
+
+// Limits bounds what deserialization accepts, so loading untrusted index blobs cannot trigger huge allocations
+// or unbounded lookups. Zero fields are unlimited.
+type Limits struct {
+	// MaxBytes bounds the size of the serialized data
+	MaxBytes int
+	// MaxShards bounds the number of shards
+	MaxShards int
+	// MaxRows bounds the rows of a shard
+	MaxRows uint64
+	// MaxKeyBytes bounds the primary key size
+	MaxKeyBytes uint64
+	// MaxBuckets bounds the buckets of a shard, and with it the indexed word length
+	MaxBuckets int
+	// MaxFilterBytes bounds every filter of a shard
+	MaxFilterBytes int
+}
+
+// DefaultLimits returns the limits applied unless WithLimits is used. They reject corrupt lengths while admitting any realistic index.
+func DefaultLimits() *Limits {
+	return &Limits{
+		MaxShards:      1 << 24,
+		MaxRows:        1 << 40,
+		MaxKeyBytes:    1 << 16,
+		MaxBuckets:     1 << 12,
+		MaxFilterBytes: 1 << 36,
+	}
+}
+
+// LimitError reports the shard and the limit a deserialized index exceeds, it wraps ErrExceedsLimits
+type LimitError struct {
+	Shard int
+	Limit string
+	Value uint64
+	Max   uint64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("fulltext: shard %d: %s %d exceeds %d: %v", e.Shard, e.Limit, e.Value, e.Max, ErrExceedsLimits)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrExceedsLimits
+}
+
+// WithLimits sets the limits checked by the deserializers of the index, nil restores DefaultLimits.
+// WithLimits is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) WithLimits(l *Limits) *Index {
+	i.limits = l
+	return i
+}
+
+// bounds returns the limits in effect
+func (i *Index) bounds() *Limits {
+	if i.limits == nil {
+		return DefaultLimits()
+	}
+	return i.limits
+}
+
+// size checks the size of serialized data before it is decoded
+func (l *Limits) size(n int) error {
+	if l.MaxBytes > 0 && n > l.MaxBytes {
+		return &LimitError{Shard: -1, Limit: "bytes", Value: uint64(n), Max: uint64(l.MaxBytes)}
+	}
+	return nil
+}
+
+// check bounds the decoded shards, and their lengths which lookups trust
+func (l *Limits) check(shards []index) error {
+	if l.MaxShards > 0 && len(shards) > l.MaxShards {
+		return &LimitError{Shard: -1, Limit: "shards", Value: uint64(len(shards)), Max: uint64(l.MaxShards)}
+	}
+	exceeds := func(value, max uint64) bool {
+		return max > 0 && value > max
+	}
+	for curr := range shards {
+		p := &shards[curr]
+		if exceeds(p.Rows, l.MaxRows) {
+			return &LimitError{Shard: curr, Limit: "rows", Value: p.Rows, Max: l.MaxRows}
+		}
+		if p.Logrows > 64 || int(p.Logrows) > bits.Len64(p.Rows) {
+			return &LimitError{Shard: curr, Limit: "logrows", Value: uint64(p.Logrows), Max: uint64(bits.Len64(p.Rows))}
+		}
+		if exceeds(p.Pkbits/8, l.MaxKeyBytes) {
+			return &LimitError{Shard: curr, Limit: "key bytes", Value: p.Pkbits / 8, Max: l.MaxKeyBytes}
+		}
+		if l.MaxBuckets > 0 {
+			if len(p.Buckets) > l.MaxBuckets || len(p.Counts) > l.MaxBuckets {
+				return &LimitError{Shard: curr, Limit: "buckets", Value: uint64(max(len(p.Buckets), len(p.Counts))), Max: uint64(l.MaxBuckets)}
+			}
+			if p.Maxword < 0 || p.Maxword > l.MaxBuckets+int(p.MinWord) {
+				return &LimitError{Shard: curr, Limit: "maxword", Value: uint64(p.Maxword), Max: uint64(l.MaxBuckets + int(p.MinWord))}
+			}
+		}
+		if l.MaxFilterBytes > 0 {
+			filters := append([][]byte{p.Pk, p.Bloom, p.Frequencies, p.Weights, p.TermPayloads}, p.Buckets...)
+			for _, f := range append(filters, p.Counts...) {
+				if len(f) > l.MaxFilterBytes {
+					return &LimitError{Shard: curr, Limit: "filter bytes", Value: uint64(len(f)), Max: uint64(l.MaxFilterBytes)}
+				}
+			}
+		}
+	}
+	return nil
+}
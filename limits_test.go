@@ -0,0 +1,56 @@
+package fulltext
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestDeserializeLimits tests that oversized or implausible blobs are rejected with the exceeded limit
+func TestDeserializeLimits(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	data, _ := idx.Serialize()
+
+	var loaded Index
+	loaded.WithLimits(&Limits{MaxBytes: len(data) - 1})
+	if err := loaded.Deserialize(data); !errors.Is(err, ErrExceedsLimits) {
+		t.Fatalf("expected ErrExceedsLimits, got %v", err)
+	}
+	loaded.WithLimits(&Limits{MaxShards: 1})
+	var lerr *LimitError
+	if err := loaded.Deserialize(data); !errors.As(err, &lerr) || lerr.Limit != "shards" {
+		t.Fatalf("expected a shards LimitError, got %v", err)
+	}
+
+	pkbits := idx.private[0].Pkbits
+	idx.private[0].Pkbits = 1 << 40
+	idx.private[1].Maxword = 1 << 30
+	data, _ = idx.SerializeProto()
+	loaded.WithLimits(nil)
+	if err := loaded.DeserializeProto(data); !errors.As(err, &lerr) || lerr.Shard != 0 || lerr.Limit != "key bytes" {
+		t.Fatalf("expected a key bytes LimitError in shard 0, got %v", err)
+	}
+	idx.private[0].Pkbits = pkbits
+	data, _ = idx.SerializeProto()
+	if err := loaded.DeserializeProto(data); !errors.As(err, &lerr) || lerr.Shard != 1 || lerr.Limit != "maxword" {
+		t.Fatalf("expected a maxword LimitError in shard 1, got %v", err)
+	}
+}
+
+// TestDeserializeMisaligned tests that structurally broken shards are rejected on load instead of panicking lookups
+func TestDeserializeMisaligned(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	idx.private[1].Counts = idx.private[1].Counts[:1]
+	data, _ := idx.Serialize()
+	var loaded Index
+	var verr *ValidationError
+	err := loaded.Deserialize(data)
+	if !errors.Is(err, ErrCorrupted) || !errors.Is(err, ErrMisalignedBuckets) || !errors.As(err, &verr) || verr.Shard != 1 {
+		t.Fatalf("expected corrupted misaligned buckets in shard 1, got %v", err)
+	}
+	idx = newShardedTestIndex(t)
+	idx.private[0].Pk = idx.private[0].Pk[:len(idx.private[0].Pk)/2]
+	data, _ = idx.SerializeProto()
+	if err := loaded.DeserializeProto(data); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("expected ErrCorrupted, got %v", err)
+	}
+}
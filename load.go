@@ -0,0 +1,199 @@
+package fulltext
+
+import "bytes"
+import "context"
+import "encoding/binary"
+import "fmt"
+import "io"
+import "io/fs"
+import "math"
+import "net/http"
+import "net/url"
+
+var ErrUnsupportedScheme = fmt.Errorf("unsupported_url_scheme")
+
+// LoadFromURL fetches and deserializes an index from s3://bucket/key, gs://bucket/key, https:// or http:// locations.
+// Object storage is reached over its public HTTPS endpoint, so private objects should be passed as presigned https URLs.
+// The JSON, the protobuf and the sharded serialization are accepted. DefaultLimits apply, see Index.Fetch.
+func LoadFromURL(ctx context.Context, rawURL string) (*Index, error) {
+	i := new(Index)
+	if err := i.Fetch(ctx, rawURL); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Fetch is LoadFromURL into i, within the limits of i, see WithLimits. The download stops once it exceeds
+// Limits.MaxBytes.
+func (i *Index) Fetch(ctx context.Context, rawURL string) error {
+	endpoint, err := objectEndpoint(rawURL)
+	if err != nil {
+		return err
+	}
+	data, err := fetch(ctx, endpoint, -1, 0, i.bounds())
+	if err != nil {
+		return err
+	}
+	return i.deserializeAny(data)
+}
+
+// LoadShardsFromURL loads only the shards numbered shardIDs of an index produced by SerializeSharded.
+// The header, the directory and each shard are fetched with HTTP range requests, so the rest of the artifact is never downloaded.
+// DefaultLimits apply, see Index.FetchShards.
+func LoadShardsFromURL(ctx context.Context, rawURL string, shardIDs []int) (*Index, error) {
+	i := new(Index)
+	if err := i.FetchShards(ctx, rawURL, shardIDs); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// FetchShards is LoadShardsFromURL into i, within the limits of i, see WithLimits. The shard count of the header is
+// checked against Limits.MaxShards before the directory is fetched, and the shards against Limits.MaxBytes before
+// they are fetched.
+func (i *Index) FetchShards(ctx context.Context, rawURL string, shardIDs []int) error {
+	endpoint, err := objectEndpoint(rawURL)
+	if err != nil {
+		return err
+	}
+	limits := i.bounds()
+	header, err := fetch(ctx, endpoint, 0, uint64(shardedPrefix), limits)
+	if err != nil {
+		return err
+	}
+	count, err := shardCount(header)
+	if err != nil {
+		return err
+	}
+	if limits.MaxShards > 0 && count > limits.MaxShards {
+		return &LimitError{Shard: -1, Limit: "shards", Value: uint64(count), Max: uint64(limits.MaxShards)}
+	}
+	if count > 0 {
+		dir, err := fetch(ctx, endpoint, int64(shardedPrefix), 8*uint64(count), limits)
+		if err != nil {
+			return err
+		}
+		header = append(header, dir...)
+	}
+	_, dir, err := shardDirectory(header)
+	if err != nil {
+		return err
+	}
+	// reassemble a sharded buffer holding just the wanted shards
+	var body []byte
+	data := append(bytes.Clone(shardedMagic), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(data[len(shardedMagic):], uint32(len(shardIDs)))
+	for _, id := range shardIDs {
+		if id < 0 || id >= count {
+			return ErrShardOutOfRange
+		}
+		start, end := shardSpan(dir, id)
+		if start > end {
+			return ErrMalformedHeader
+		}
+		if limits.MaxBytes > 0 && end-start > uint64(limits.MaxBytes-len(body)) {
+			return &LimitError{Shard: id, Limit: "bytes", Value: uint64(len(body)) + end - start, Max: uint64(limits.MaxBytes)}
+		}
+		shard, err := fetch(ctx, endpoint, int64(uint64(len(header))+start), end-start, limits)
+		if err != nil {
+			return err
+		}
+		body = append(body, shard...)
+		data = binary.LittleEndian.AppendUint64(data, uint64(len(body)))
+	}
+	return i.DeserializeSharded(append(data, body...))
+}
+
+// fetch downloads length bytes at offset of endpoint, or the whole object when offset is negative, failing once the
+// download exceeds limits.MaxBytes
+func fetch(ctx context.Context, endpoint string, offset int64, length uint64, limits *Limits) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= 0 {
+		if length == 0 {
+			return nil, nil
+		}
+		if err := limits.size(int(min(length, math.MaxInt))); err != nil {
+			return nil, err
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, uint64(offset)+length-1))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("fulltext: fetching %s: %s", endpoint, resp.Status)
+	}
+	if offset >= 0 && resp.StatusCode == http.StatusOK {
+		// the server ignored the range, skip to it
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			return nil, err
+		}
+	}
+	if offset >= 0 {
+		data := make([]byte, length)
+		if _, err := io.ReadFull(resp.Body, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	var body io.Reader = resp.Body
+	if limits.MaxBytes > 0 {
+		body = io.LimitReader(resp.Body, int64(limits.MaxBytes)+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := limits.size(len(data)); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// LoadFS deserializes an index stored at path in fsys, such as an embed.FS holding an index baked in with go:embed.
+// The JSON, the protobuf and the sharded serialization are accepted.
+func LoadFS(fsys fs.FS, path string) (*Index, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	i := new(Index)
+	if err := i.deserializeAny(data); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// objectEndpoint maps object storage URLs to their HTTPS endpoints
+func objectEndpoint(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return rawURL, nil
+	case "s3":
+		return "https://" + u.Host + ".s3.amazonaws.com" + u.EscapedPath(), nil
+	case "gs":
+		return "https://storage.googleapis.com/" + u.Host + u.EscapedPath(), nil
+	}
+	return "", ErrUnsupportedScheme
+}
+
+// deserializeAny detects the JSON serialization by its leading bracket, the sharded one by its magic and falls back to protobuf
+func (idx *Index) deserializeAny(data []byte) error {
+	if bytes.HasPrefix(data, shardedMagic) {
+		return idx.DeserializeSharded(data)
+	}
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return idx.Deserialize(data)
+	}
+	return idx.DeserializeProto(data)
+}
@@ -0,0 +1,151 @@
+package fulltext
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// TestLoadFromURL tests loading both serializations over HTTP
+func TestLoadFromURL(t *testing.T) {
+	idx := newTestIndex(t)
+	jsonData, _ := idx.Serialize()
+	protoData, _ := idx.SerializeProto()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.json":
+			w.Write(jsonData)
+		case "/index.pb":
+			w.Write(protoData)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for _, path := range []string{"/index.json", "/index.pb"} {
+		loaded, err := LoadFromURL(context.Background(), srv.URL+path)
+		if err != nil {
+			t.Fatalf("expected no error for %s, got %v", path, err)
+		}
+		count := 0
+		for range loaded.Lookup("backend", true, true) {
+			count++
+		}
+		if count != 2 {
+			t.Fatalf("expected 2 results for %s, got %d", path, count)
+		}
+	}
+	if _, err := LoadFromURL(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Fatal("expected error for missing object, got nil")
+	}
+}
+
+// TestObjectEndpoint tests the mapping of object storage URLs
+func TestObjectEndpoint(t *testing.T) {
+	for in, want := range map[string]string{
+		"s3://bucket/dir/index.json": "https://bucket.s3.amazonaws.com/dir/index.json",
+		"gs://bucket/index.json":     "https://storage.googleapis.com/bucket/index.json",
+	} {
+		got, err := objectEndpoint(in)
+		if err != nil || got != want {
+			t.Fatalf("expected %s, got %s (%v)", want, got, err)
+		}
+	}
+	if _, err := objectEndpoint("ftp://host/index"); !errors.Is(err, ErrUnsupportedScheme) {
+		t.Fatalf("expected ErrUnsupportedScheme, got %v", err)
+	}
+}
+
+// TestLoadFS tests loading from a read-only filesystem
+func TestLoadFS(t *testing.T) {
+	data, _ := newTestIndex(t).Serialize()
+	fsys := fstest.MapFS{"data/index.json": &fstest.MapFile{Data: data}}
+	loaded, err := LoadFS(fsys, "data/index.json")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	count := 0
+	for range loaded.Lookup("golang", true, true) {
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 result, got %d", count)
+	}
+	if _, err := LoadFS(fsys, "missing.json"); err == nil {
+		t.Fatal("expected error for missing file, got nil")
+	}
+}
+
+// TestLoadShardsFromURL tests loading a shard range with HTTP range requests
+func TestLoadShardsFromURL(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	data, _ := idx.SerializeSharded()
+	var served int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") == "" {
+			t.Errorf("expected a range request")
+		}
+		http.ServeContent(&countingWriter{ResponseWriter: w, n: &served}, r, "index.ftxs", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	loaded, err := LoadShardsFromURL(context.Background(), srv.URL+"/index.ftxs", []int{1})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(loaded.private) != 1 || loaded.private[0].Rows != idx.private[1].Rows {
+		t.Fatalf("expected shard 1 only, got %d shards", len(loaded.private))
+	}
+	if served >= int64(len(data)) {
+		t.Fatalf("expected a partial download, got %d of %d bytes", served, len(data))
+	}
+}
+
+// TestFetchLimits tests that downloads stop at the limits of the index, a huge shard count before its directory
+func TestFetchLimits(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	sharded, _ := idx.SerializeSharded()
+	forged := append(bytes.Clone(shardedMagic), 0xff, 0xff, 0xff, 0x7f)
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		data := sharded
+		if r.URL.Path == "/forged.ftxs" {
+			data = forged
+		}
+		http.ServeContent(w, r, "index.ftxs", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	err := new(Index).WithLimits(&Limits{MaxShards: 16}).FetchShards(context.Background(), srv.URL+"/forged.ftxs", []int{0})
+	if !errors.Is(err, ErrExceedsLimits) || requests != 1 {
+		t.Fatalf("expected ErrExceedsLimits after the header only, got %v after %d requests", err, requests)
+	}
+	small := &Limits{MaxBytes: len(sharded) / 2}
+	if err := new(Index).WithLimits(small).Fetch(context.Background(), srv.URL+"/index.ftxs"); !errors.Is(err, ErrExceedsLimits) {
+		t.Fatalf("expected ErrExceedsLimits, got %v", err)
+	}
+	if err := new(Index).WithLimits(small).FetchShards(context.Background(), srv.URL+"/index.ftxs", []int{0, 1, 2}); !errors.Is(err, ErrExceedsLimits) {
+		t.Fatalf("expected ErrExceedsLimits, got %v", err)
+	}
+	loaded := new(Index).WithLimits(&Limits{MaxBytes: len(sharded)})
+	if err := loaded.Fetch(context.Background(), srv.URL+"/index.ftxs"); err != nil || len(loaded.private) != len(idx.private) {
+		t.Fatalf("expected the index within its limits, got %v", err)
+	}
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n *int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	*w.n += int64(len(b))
+	return w.ResponseWriter.Write(b)
+}
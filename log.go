@@ -0,0 +1,10 @@
+package fulltext
+
+import "log/slog"
+
+// WithLogger logs the lookups of the index at debug level to l, nil disables logging.
+// WithLogger is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) WithLogger(l *slog.Logger) *Index {
+	i.logger = l
+	return i
+}
@@ -0,0 +1,29 @@
+package fulltext
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestLogger tests that builds, shard flushes and lookups are logged
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	opts := NewDefaultOpts()
+	opts.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	idx, err := New(opts, map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for range idx.Lookup("backend", true, true) {
+	}
+	for _, want := range []string{"fulltext: index built", "fulltext: shard flushed", "fulltext: bucket built", "fulltext: lookup"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %q in log, got\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package fulltext
+
+import "context"
+import "math"
+
+// LookupOpts tunes a single lookup, see LookupWith
+type LookupOpts struct {
+	// Exact finds exact word matches (faster)
+	Exact bool
+
+	// Dedup hits each primary key exactly once per shard
+	Dedup bool
+
+	// MinCoverage yields rows matching at least this fraction of the query shingles, such as 0.7 for partial matches.
+	// It implies Dedup. 0 = all shingles but one, the Lookup default.
+	MinCoverage float64
+
+	// Boosts weighs matches per field in FieldIndex.LookupScored, such as {"title": 3} to rank title matches
+	// above body matches. Fields not listed weigh 1.
+	Boosts map[string]float64
+
+	// GlobalDedup hits each primary key exactly once across all shards, such as a key present in shards added by Append.
+	// The yielded keys are tracked for the duration of the lookup.
+	GlobalDedup bool
+
+	// Allow, when set, is asked about every matching primary key before it is yielded, and keys it rejects are skipped,
+	// such as rows of other tenants, so callers never see keys they are not permitted to.
+	Allow func(primaryKey string) bool
+
+	// MaxCandidates aborts the lookup once the shards sent more candidate rows, and MaxShardProbes before probing
+	// more shards, such as a short subword lookup over a huge corpus. LookupLimited reports the abort as a
+	// QueryLimitError, LookupWith stops silently. 0 = unlimited.
+	MaxCandidates  int
+	MaxShardProbes int
+
+	// DisableStemming looks the word up without stemming in shards built with NewOpts.IndexUnstemmed, such as to find
+	// "testing" but not "tests", without a second index. Other shards are looked up stemmed.
+	DisableStemming bool
+
+	// CaseSensitive matches the case of the word in shards built with NewOpts.DualCase, which are otherwise looked up
+	// case insensitively. Other shards are looked up as built.
+	CaseSensitive bool
+
+	// Exclude skips the rows matching any of these words, looked up like the word itself, such as the NOT clauses of a
+	// search, so callers need not fetch the rows to filter them. The excluded rows are resolved once the iteration starts.
+	Exclude []string
+}
+
+// LookupWith is Lookup tuned by opts. Opts can be nil.
+func (i *Index) LookupWith(word string, opts *LookupOpts) func(yield func(primaryKey string) bool) {
+	if opts == nil {
+		opts = new(LookupOpts)
+	}
+	return i.lookupWith(word, opts, opts.budget())
+}
+
+// lookupWith is LookupWith within limit
+func (i *Index) lookupWith(word string, opts *LookupOpts, limit *budget) func(yield func(primaryKey string) bool) {
+	lookup := i.lookupKeys(context.Background(), cacheKey{
+		word:     opts.mark(word),
+		exact:    opts.Exact,
+		dedup:    opts.Dedup || opts.GlobalDedup || opts.MinCoverage > 0,
+		coverage: opts.MinCoverage,
+	}, limit)
+	if len(opts.Exclude) > 0 {
+		lookup = i.excluding(lookup, opts)
+	}
+	if opts.Allow != nil {
+		lookup = allowed(lookup, opts.Allow)
+	}
+	if !opts.GlobalDedup {
+		return lookup
+	}
+	return func(yield func(string) bool) {
+		seen := make(map[string]struct{})
+		for pk := range lookup {
+			if _, ok := seen[pk]; ok {
+				continue
+			}
+			seen[pk] = struct{}{}
+			if !yield(pk) {
+				return
+			}
+		}
+	}
+}
+
+// mark prefixes word with the marks of the shards looking it up case sensitively or unstemmed
+func (opts *LookupOpts) mark(word string) string {
+	if opts.CaseSensitive {
+		word = caseMark + word
+	}
+	if opts.DisableStemming {
+		word = unstemmedMark + word
+	}
+	return word
+}
+
+// excluded returns the keys of rows matching any word of opts.Exclude
+func (i *Index) excluded(opts *LookupOpts) map[string]struct{} {
+	excluded := make(map[string]struct{})
+	for _, word := range opts.Exclude {
+		i.lookup(opts.mark(word), opts.Exact, true, func(shard int, pos uint64) bool {
+			excluded[i.private[shard].key(pos)] = struct{}{}
+			return true
+		})
+	}
+	return excluded
+}
+
+// excluding filters the keys of rows matching any word of opts.Exclude out of lookup
+func (i *Index) excluding(lookup func(yield func(string) bool), opts *LookupOpts) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		excluded := i.excluded(opts)
+		for pk := range lookup {
+			if _, ok := excluded[pk]; !ok && !yield(pk) {
+				return
+			}
+		}
+	}
+}
+
+// LookupCoverage iterates the rows holding any shingle of word at any offset, each once per shard, yielding the fraction
+// of the query shingles the row matched, so callers can rank partial substring matches instead of relying on the
+// deduplicated match threshold. Rows below minCoverage are skipped, 0 = keep all.
+func (i *Index) LookupCoverage(word string, minCoverage float64) func(yield func(primaryKey string, coverage float64) bool) {
+	return func(yield func(string, float64) bool) {
+		coverage := max(minCoverage, math.SmallestNonzeroFloat64)
+		i.lookupCoverage(context.Background(), word, false, true, coverage, func(shard int, pos uint64, score float64) bool {
+			return yield(i.private[shard].key(pos), score)
+		})
+	}
+}
+
+// allowed filters the keys of lookup by allow
+func allowed(lookup func(yield func(string) bool), allow func(primaryKey string) bool) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		for pk := range lookup {
+			if allow(pk) && !yield(pk) {
+				return
+			}
+		}
+	}
+}
+
+// LookupGrouped is an exact deduplicated Lookup yielding one primary key per group of keys sharing their first prefixLen
+// bytes, the first key found, such as prefixLen 7 collapsing all versions "doc:42:v1", "doc:42:v2" into one hit.
+// Keys shorter than prefixLen, or all keys for prefixLen <= 0, form groups of their own. The yielded groups are tracked
+// for the duration of the lookup.
+func (i *Index) LookupGrouped(word string, prefixLen int) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		seen := make(map[string]struct{})
+		for pk := range i.Lookup(word, true, true) {
+			group := pk
+			if prefixLen > 0 && len(pk) > prefixLen {
+				group = pk[:prefixLen]
+			}
+			if _, ok := seen[group]; ok {
+				continue
+			}
+			seen[group] = struct{}{}
+			if !yield(pk) {
+				return
+			}
+		}
+	}
+}
+
+// LookupBatches is Lookup yielding the primary keys in batches filling buf, one call per len(buf) keys instead of one
+// per key, for callers collecting millions of matches. The batch aliases buf, so it is only valid until yield returns.
+// An empty buf uses batches of 256 keys.
+func (i *Index) LookupBatches(word string, exact, dedup bool, buf []string) func(yield func(batch []string) bool) {
+	return func(yield func([]string) bool) {
+		if len(buf) == 0 {
+			buf = make([]string, 256)
+		}
+		var n int
+		for pk := range i.Lookup(word, exact, dedup) {
+			buf[n] = pk
+			if n++; n < len(buf) {
+				continue
+			}
+			if n = 0; !yield(buf) {
+				return
+			}
+		}
+		if n > 0 {
+			yield(buf[:n])
+		}
+	}
+}
+
+// boost returns the weight of matches in the named field
+func (opts *LookupOpts) boost(field string) float64 {
+	if boost, ok := opts.Boosts[field]; ok {
+		return boost
+	}
+	return 1
+}
+
+// lookupKeys resolves the primary keys of a lookup within limit, served from the cache when it is enabled
+func (i *Index) lookupKeys(ctx context.Context, key cacheKey, limit *budget) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		// limited lookups bypass the cache, which would serve them without counting their candidates and probes
+		if i.cache != nil && limit == nil {
+			i.cachedLookup(ctx, key, yield)
+			return
+		}
+		i.lookupWithin(ctx, key.word, key.exact, key.dedup, key.coverage, limit, func(shard int, pos uint64, _ float64) bool {
+			return yield(i.private[shard].key(pos))
+		})
+	}
+}
+
+// matched reports whether a row hit by v of the query shingles is a deduplicated match. One shingle may be missing,
+// unless strict, as in strided shards, where a single shared shingle of the few probed ones matches other words.
+func matched(v, shingles int, coverage float64, strict bool) bool {
+	if coverage > 0 {
+		return float64(v) >= math.Ceil(coverage*float64(shingles))
+	}
+	if strict {
+		return v >= shingles
+	}
+	return v+1 >= shingles
+}
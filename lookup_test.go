@@ -0,0 +1,207 @@
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestLookupGlobalDedup tests that a key present in several shards is yielded once
+func TestLookupGlobalDedup(t *testing.T) {
+	idx := newTestIndex(t)
+	more, err := New(nil, map[string][]string{"doc:1": {"backend"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.Append(more)
+	count := func(opts *LookupOpts) (n int) {
+		for range idx.LookupWith("backend", opts) {
+			n++
+		}
+		return
+	}
+	if n := count(&LookupOpts{Exact: true, Dedup: true}); n != 3 {
+		t.Fatalf("expected 3 results with per shard dedup, got %d", n)
+	}
+	if n := count(&LookupOpts{Exact: true, GlobalDedup: true}); n != 2 {
+		t.Fatalf("expected 2 results with global dedup, got %d", n)
+	}
+}
+
+// TestLookupMinCoverage tests partial matches of a fraction of the query shingles
+func TestLookupMinCoverage(t *testing.T) {
+	idx := newTestIndex(t)
+	count := func(opts *LookupOpts) (n int) {
+		for range idx.LookupWith("backing", opts) {
+			n++
+		}
+		return
+	}
+	if n := count(&LookupOpts{Exact: true, Dedup: true}); n != 0 {
+		t.Fatalf("expected no results by default, got %d", n)
+	}
+	if n := count(&LookupOpts{Exact: true, MinCoverage: 0.4}); n != 2 {
+		t.Fatalf("expected 2 results matching 2 of 5 shingles, got %d", n)
+	}
+	if n := count(&LookupOpts{Exact: true, MinCoverage: 0.5}); n != 0 {
+		t.Fatalf("expected no results requiring 3 of 5 shingles, got %d", n)
+	}
+}
+
+// TestLookupCoverage tests that partial substring matches are yielded with the fraction of shingles they matched
+func TestLookupCoverage(t *testing.T) {
+	idx, err := New(nil, map[string][]string{
+		"doc:1": {"internationalization"},
+		"doc:2": {"internal"},
+		"doc:3": {"python"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	scores := make(map[string]float64)
+	for pk, coverage := range idx.LookupCoverage("internationalization", 0) {
+		scores[pk] = max(scores[pk], coverage)
+	}
+	if scores["doc:1"] != 1 || scores["doc:2"] <= 0 || scores["doc:2"] >= 1 {
+		t.Fatalf("unexpected coverage %v", scores)
+	}
+	clear(scores)
+	for pk, coverage := range idx.LookupCoverage("internationalization", 0.9) {
+		scores[pk] = coverage
+	}
+	if _, ok := scores["doc:2"]; ok || scores["doc:1"] != 1 {
+		t.Fatalf("expected only doc:1 above 0.9, got %v", scores)
+	}
+}
+
+// TestLookupAllow tests that keys rejected by Allow are never yielded
+func TestLookupAllow(t *testing.T) {
+	idx := newTestIndex(t)
+	allow := func(pk string) bool { return pk != "doc:1" }
+	var results []string
+	for pk := range idx.LookupWith("backend", &LookupOpts{Exact: true, Dedup: true, Allow: allow}) {
+		results = append(results, pk)
+	}
+	if len(results) != 1 || results[0] != "doc:2" {
+		t.Fatalf("expected [doc:2], got %v", results)
+	}
+	f := &FieldIndex{fields: map[string]*Index{"body": idx}}
+	for pk := range f.LookupScored("backend", &LookupOpts{Exact: true, Allow: allow}) {
+		if pk == "doc:1" {
+			t.Errorf("expected doc:1 to be rejected")
+		}
+	}
+}
+
+// TestLookupExclude tests that rows matching an excluded word are skipped
+func TestLookupExclude(t *testing.T) {
+	idx := newTestIndex(t)
+	var results []string
+	for pk := range idx.LookupWith("backend", &LookupOpts{Exact: true, Dedup: true, Exclude: []string{"golang", "python"}}) {
+		results = append(results, pk)
+	}
+	if len(results) != 1 || results[0] != "doc:2" {
+		t.Fatalf("expected [doc:2], got %v", results)
+	}
+	f := &FieldIndex{fields: map[string]*Index{"body": idx}}
+	for pk := range f.LookupScored("backend", &LookupOpts{Exact: true, Exclude: []string{"golang"}}) {
+		if pk == "doc:1" {
+			t.Errorf("expected doc:1 to be excluded")
+		}
+	}
+}
+
+// TestLookupBatches tests that batches hold the keys of Lookup
+func TestLookupBatches(t *testing.T) {
+	data := make(map[string][]string)
+	for n := 0; n < 1000; n++ {
+		data[fmt.Sprintf("doc:%04d", n)] = []string{"common"}
+	}
+	idx, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var keys, batches int
+	for batch := range idx.LookupBatches("common", true, true, make([]string, 300)) {
+		keys += len(batch)
+		batches++
+	}
+	if keys != 1000 || batches != 4 {
+		t.Fatalf("expected 1000 keys in 4 batches, got %d in %d", keys, batches)
+	}
+	for batch := range idx.LookupBatches("common", true, true, nil) {
+		if len(batch) != 256 {
+			t.Fatalf("expected a default batch of 256 keys, got %d", len(batch))
+		}
+		break
+	}
+}
+
+// TestLookupGrouped tests that versions of a row sharing a key prefix are yielded once
+func TestLookupGrouped(t *testing.T) {
+	idx, err := New(nil, map[string][]string{
+		"doc:41:v1": {"golang"},
+		"doc:42:v1": {"golang"},
+		"doc:42:v2": {"golang"},
+		"doc:43:v1": {"rust"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	groups := make(map[string]int)
+	for pk := range idx.LookupGrouped("golang", 7) {
+		if len(pk) > 7 {
+			pk = pk[:7]
+		}
+		groups[pk]++
+	}
+	if len(groups) != 2 || groups["doc:41:"] != 1 || groups["doc:42:"] != 1 {
+		t.Fatalf("expected one hit of doc:41: and doc:42:, got %v", groups)
+	}
+	var n int
+	for range idx.LookupGrouped("golang", 0) {
+		n++
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 ungrouped hits, got %d", n)
+	}
+}
+
+// TestLookupLimited tests that lookups exceeding MaxShardProbes or MaxCandidates are aborted with ErrLimitExceeded
+func TestLookupLimited(t *testing.T) {
+	data := make(map[string][]string)
+	for n := 0; n < 1000; n++ {
+		data[fmt.Sprintf("doc:%04d", n)] = []string{"common"}
+	}
+	opts := NewDefaultOpts()
+	opts.TargetShardRows = 100
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.WithCache(10)
+	count := func(opts *LookupOpts) (n int, err error) {
+		lookup, errs := idx.LookupLimited("common", opts)
+		for range lookup {
+			n++
+		}
+		return n, errs()
+	}
+	n, err := count(&LookupOpts{Exact: true, Dedup: true, MaxShardProbes: 3})
+	var limitErr *QueryLimitError
+	if !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &limitErr) || limitErr.Limit != "MaxShardProbes" || n != 0 {
+		t.Fatalf("expected a MaxShardProbes error and no keys, got %v and %d keys", err, n)
+	}
+	n, err = count(&LookupOpts{Exact: true, Dedup: true, MaxCandidates: 10})
+	if !errors.Is(err, ErrLimitExceeded) || n > 10 {
+		t.Fatalf("expected a MaxCandidates error after at most 10 keys, got %v and %d keys", err, n)
+	}
+	n, err = count(&LookupOpts{Exact: true, Dedup: true})
+	if err != nil || n != 1000 {
+		t.Fatalf("expected 1000 keys without limits, got %v and %d keys", err, n)
+	}
+	n, err = count(&LookupOpts{Exact: true, Dedup: true, MaxCandidates: 10})
+	if !errors.Is(err, ErrLimitExceeded) || n > 10 {
+		t.Fatalf("expected the cached result set bypassed by a limited lookup, got %v and %d keys", err, n)
+	}
+}
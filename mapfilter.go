@@ -0,0 +1,67 @@
+package fulltext
+
+import "encoding/binary"
+import "sort"
+
+// mapBackendName is the FilterBackend of the shards built below NewOpts.MapShardRows rows
+const mapBackendName = "map"
+
+// mapVersion is the format byte leading every map filter
+const mapVersion = 1
+
+func init() {
+	RegisterFilterBackend(mapBackendName, mapBackend{})
+}
+
+// mapBackend is an exact FilterBackend holding the sorted keys next to their values, probed by binary search, so
+// absent keys never return a value. A filter is mapVersion, the uvarint number of entries, the little endian uint32
+// offset of every entry and the entries, each a uvarint key length, the key, a uvarint value length and the value.
+type mapBackend struct{}
+
+func (mapBackend) New(m map[string][]byte, bitLimit, falsePositiveFunctions byte) []byte {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var entries []byte
+	filter := binary.AppendUvarint([]byte{mapVersion}, uint64(len(keys)))
+	for _, key := range keys {
+		filter = binary.LittleEndian.AppendUint32(filter, uint32(len(entries)))
+		entries = binary.AppendUvarint(entries, uint64(len(key)))
+		entries = append(entries, key...)
+		entries = binary.AppendUvarint(entries, uint64(len(m[key])))
+		entries = append(entries, m[key]...)
+	}
+	return append(filter, entries...)
+}
+
+func (mapBackend) Get(filter []byte, bitLimit uint64, key string) []byte {
+	n, read := binary.Uvarint(filter[1:])
+	table := filter[1+read:]
+	entries := table[4*n:]
+	entry := func(k int) (key, value []byte) {
+		e := entries[binary.LittleEndian.Uint32(table[4*k:]):]
+		length, read := binary.Uvarint(e)
+		key, e = e[read:read+int(length)], e[read+int(length):]
+		length, read = binary.Uvarint(e)
+		return key, e[read : read+int(length)]
+	}
+	at := sort.Search(int(n), func(k int) bool {
+		found, _ := entry(k)
+		return string(found) >= key
+	})
+	if at < int(n) {
+		if found, value := entry(at); string(found) == key {
+			return value
+		}
+	}
+	return nil
+}
+
+func (b mapBackend) GetNum(filter []byte, bitLimit uint64, key string) uint64 {
+	var buf [8]byte
+	value := b.Get(filter, bitLimit, key)
+	copy(buf[8-min(len(value), 8):], value)
+	return binary.BigEndian.Uint64(buf[:])
+}
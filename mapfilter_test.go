@@ -0,0 +1,54 @@
+package fulltext
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestMapShardRows tests that small shards are built as exact maps finding the same rows without false positives
+func TestMapShardRows(t *testing.T) {
+	data := make(map[string][]string)
+	for j := 0; j < 50; j++ {
+		data[fmt.Sprintf("doc:%04d", j)] = []string{fmt.Sprintf("word%d", j), "common"}
+	}
+	opts := NewDefaultOpts()
+	opts.MapShardRows = 100
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, p := range idx.private {
+		if p.Backend != mapBackendName {
+			t.Fatalf("expected map shards, got %q", p.Backend)
+		}
+	}
+	serialized, _ := idx.SerializeProto()
+	loaded, err := View(serialized)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	count := func(word string, exact, dedup bool) (keys []string) {
+		for pk := range loaded.Lookup(word, exact, dedup) {
+			keys = append(keys, pk)
+		}
+		return
+	}
+	found := make(map[string]bool)
+	for _, pk := range count("word42", true, true) {
+		found[pk] = true
+	}
+	if !found["doc:0042"] || found["doc:0017"] {
+		t.Fatalf("expected doc:0042 without doc:0017, got %v", found)
+	}
+	if keys := count("common", true, true); len(keys) != 50 {
+		t.Fatalf("expected 50 keys, got %d", len(keys))
+	}
+	for _, word := range []string{"absent", "zzz", "qxj"} {
+		if keys := count(word, false, false); len(keys) != 0 {
+			t.Fatalf("expected no false positives for %q, got %v", word, keys)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package fulltext
+
+import "encoding/json"
+import "time"
+
+// Meta stamps an index with the build it came from, see NewOpts.BuildID and Stamp
+type Meta struct {
+	// BuildID is the user supplied identifier of the build, such as a release or a data snapshot
+	BuildID string
+	// BuiltAt is when the index was built or stamped, zero for unstamped indexes
+	BuiltAt time.Time
+	// Stats are the statistics and options of the build, nil for indexes built before they were recorded
+	Stats *BuildStats
+}
+
+// BuildStats are the statistics of a build recorded in its shards, and the options it was built with
+type BuildStats struct {
+	Rows   int `json:"rows"`
+	Shards int `json:"shards"`
+	// Terms sums the distinct words of the shards, a word held by several shards counts once per shard
+	Terms    int           `json:"terms"`
+	Duration time.Duration `json:"duration"`
+	Opts     BuildOpts     `json:"opts"`
+}
+
+// BuildOpts are the NewOpts of a build without the values that cannot be serialized, see BuildStats.
+// Callbacks names the function fields set, such as "Payload", HashSeed is left out as the shards store their seed.
+type BuildOpts struct {
+	FalsePositiveFunctions          byte            `json:"false_positive_functions"`
+	FalsePositiveFunctionsPerBucket []byte          `json:"false_positive_functions_per_bucket,omitempty"`
+	BucketingExponent               byte            `json:"bucketing_exponent"`
+	MinShards                       byte            `json:"min_shards"`
+	MinWordLength                   byte            `json:"min_word_length"`
+	Sync                            bool            `json:"sync,omitempty"`
+	StoreTerms                      bool            `json:"store_terms,omitempty"`
+	MaxWordLength                   int             `json:"max_word_length,omitempty"`
+	SkipLongWords                   bool            `json:"skip_long_words,omitempty"`
+	ASCIIFold                       bool            `json:"ascii_fold,omitempty"`
+	BloomBitsPerShingle             byte            `json:"bloom_bits_per_shingle"`
+	Analyzer                        string          `json:"analyzer,omitempty"`
+	TargetShardRows                 int             `json:"target_shard_rows,omitempty"`
+	ShardBuildBudget                time.Duration   `json:"shard_build_budget,omitempty"`
+	RandomHashSeed                  bool            `json:"random_hash_seed,omitempty"`
+	GetterTimeout                   time.Duration   `json:"getter_timeout,omitempty"`
+	GetterRetries                   int             `json:"getter_retries,omitempty"`
+	CheckpointDir                   string          `json:"checkpoint_dir,omitempty"`
+	BuildID                         string          `json:"build_id,omitempty"`
+	ShingleStride                   byte            `json:"shingle_stride,omitempty"`
+	MaxBucketDepth                  int             `json:"max_bucket_depth,omitempty"`
+	BagCacheRows                    int             `json:"bag_cache_rows,omitempty"`
+	RoaringPostings                 float64         `json:"roaring_postings,omitempty"`
+	FilterBackend                   string          `json:"filter_backend,omitempty"`
+	MapShardRows                    int             `json:"map_shard_rows,omitempty"`
+	KeyPadding                      *KeyPadding     `json:"key_padding,omitempty"`
+	Duplicates                      DuplicatePolicy `json:"duplicates,omitempty"`
+	HashInputs                      bool            `json:"hash_inputs,omitempty"`
+	IndexUnstemmed                  bool            `json:"index_unstemmed,omitempty"`
+	DualCase                        bool            `json:"dual_case,omitempty"`
+	Callbacks                       []string        `json:"callbacks,omitempty"`
+}
+
+// buildOpts records the serializable options of opts
+func (opts *NewOpts) buildOpts() BuildOpts {
+	o := BuildOpts{
+		FalsePositiveFunctions:          opts.FalsePositiveFunctions,
+		FalsePositiveFunctionsPerBucket: opts.FalsePositiveFunctionsPerBucket,
+		BucketingExponent:               opts.BucketingExponent,
+		MinShards:                       opts.MinShards,
+		MinWordLength:                   opts.MinWordLength,
+		Sync:                            opts.Sync,
+		StoreTerms:                      opts.StoreTerms,
+		MaxWordLength:                   opts.MaxWordLength,
+		SkipLongWords:                   opts.SkipLongWords,
+		ASCIIFold:                       opts.ASCIIFold,
+		BloomBitsPerShingle:             opts.BloomBitsPerShingle,
+		Analyzer:                        opts.Analyzer,
+		TargetShardRows:                 opts.TargetShardRows,
+		ShardBuildBudget:                opts.ShardBuildBudget,
+		RandomHashSeed:                  opts.RandomHashSeed,
+		GetterTimeout:                   opts.GetterTimeout,
+		GetterRetries:                   opts.GetterRetries,
+		CheckpointDir:                   opts.CheckpointDir,
+		BuildID:                         opts.BuildID,
+		ShingleStride:                   opts.ShingleStride,
+		MaxBucketDepth:                  opts.MaxBucketDepth,
+		BagCacheRows:                    opts.BagCacheRows,
+		RoaringPostings:                 opts.RoaringPostings,
+		FilterBackend:                   opts.FilterBackend,
+		MapShardRows:                    opts.MapShardRows,
+		KeyPadding:                      opts.KeyPadding,
+		Duplicates:                      opts.Duplicates,
+		HashInputs:                      opts.HashInputs,
+		IndexUnstemmed:                  opts.IndexUnstemmed,
+		DualCase:                        opts.DualCase,
+	}
+	for _, callback := range []struct {
+		name string
+		set  bool
+	}{
+		{"Facets", opts.Facets != nil},
+		{"Payload", opts.Payload != nil},
+		{"Tokens", opts.Tokens != nil},
+		{"Frequencies", opts.Frequencies != nil},
+		{"Weights", opts.Weights != nil},
+		{"TermPayloads", opts.TermPayloads != nil},
+		{"BulkGetter", opts.BulkGetter != nil},
+	} {
+		if callback.set {
+			o.Callbacks = append(o.Callbacks, callback.name)
+		}
+	}
+	return o
+}
+
+// NewOpts returns build options reproducing the recorded ones, starting from NewDefaultOpts.
+// The callbacks and the HashSeed are not recorded and must be set again.
+func (o *BuildOpts) NewOpts() *NewOpts {
+	opts := NewDefaultOpts()
+	opts.FalsePositiveFunctions = o.FalsePositiveFunctions
+	opts.FalsePositiveFunctionsPerBucket = o.FalsePositiveFunctionsPerBucket
+	opts.BucketingExponent = o.BucketingExponent
+	opts.MinShards = o.MinShards
+	opts.MinWordLength = o.MinWordLength
+	opts.Sync = o.Sync
+	opts.StoreTerms = o.StoreTerms
+	opts.MaxWordLength = o.MaxWordLength
+	opts.SkipLongWords = o.SkipLongWords
+	opts.ASCIIFold = o.ASCIIFold
+	opts.BloomBitsPerShingle = o.BloomBitsPerShingle
+	opts.Analyzer = o.Analyzer
+	opts.TargetShardRows = o.TargetShardRows
+	opts.ShardBuildBudget = o.ShardBuildBudget
+	opts.RandomHashSeed = o.RandomHashSeed
+	opts.GetterTimeout = o.GetterTimeout
+	opts.GetterRetries = o.GetterRetries
+	opts.CheckpointDir = o.CheckpointDir
+	opts.BuildID = o.BuildID
+	opts.ShingleStride = o.ShingleStride
+	opts.MaxBucketDepth = o.MaxBucketDepth
+	opts.BagCacheRows = o.BagCacheRows
+	opts.RoaringPostings = o.RoaringPostings
+	opts.FilterBackend = o.FilterBackend
+	opts.MapShardRows = o.MapShardRows
+	opts.KeyPadding = o.KeyPadding
+	opts.Duplicates = o.Duplicates
+	opts.HashInputs = o.HashInputs
+	opts.IndexUnstemmed = o.IndexUnstemmed
+	opts.DualCase = o.DualCase
+	return opts
+}
+
+// recordStats stores the stats of the build of rows into every shard, with the distinct words of the shard
+func (i *Index) recordStats(opts *NewOpts, rows int, duration time.Duration) {
+	stats := BuildStats{Rows: rows, Shards: len(i.private), Duration: duration, Opts: opts.buildOpts()}
+	for curr := range i.private {
+		stats.Terms = i.private[curr].distinct
+		i.private[curr].Stats, _ = json.Marshal(stats)
+	}
+}
+
+// Meta returns the stamp of the most recently built or stamped shard, so services can detect serving a stale index,
+// and the stats of its build summed over the shards of that build. Shards added by Append keep their own stamp.
+func (i *Index) Meta() Meta {
+	var m Meta
+	var newest int64
+	for curr := range i.private {
+		p := &i.private[curr]
+		if p.BuiltAt > newest || (p.BuiltAt == newest && p.BuildID != "") {
+			newest = p.BuiltAt
+			m.BuildID = p.BuildID
+		}
+	}
+	if newest != 0 {
+		m.BuiltAt = time.Unix(0, newest)
+	}
+	var terms int
+	for curr := range i.private {
+		p := &i.private[curr]
+		var stats BuildStats
+		if p.BuiltAt != newest || len(p.Stats) == 0 || json.Unmarshal(p.Stats, &stats) != nil {
+			continue
+		}
+		terms += stats.Terms
+		if m.Stats == nil {
+			m.Stats = &stats
+		}
+	}
+	if m.Stats != nil {
+		m.Stats.Terms = terms
+	}
+	return m
+}
+
+// Stamp restamps every shard with buildID and the current time, such as before serializing a merged index.
+// Stamp is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) Stamp(buildID string) {
+	now := time.Now().UnixNano()
+	for curr := range i.private {
+		i.private[curr].BuildID = buildID
+		i.private[curr].BuiltAt = now
+		i.private[curr].Checksum = 0
+	}
+}
@@ -0,0 +1,48 @@
+package fulltext
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMeta tests that the build stamp survives serialization and restamping
+func TestMeta(t *testing.T) {
+	opts := NewDefaultOpts()
+	opts.BuildID = "release-42"
+	opts.Payload = func(pk string) []byte { return []byte(pk) }
+	begun := time.Now()
+	idx, err := New(opts, map[string][]string{"doc:1": {"golang"}, "doc:2": {"rust"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, serialize := range []func() ([]byte, error){idx.Serialize, idx.SerializeProto, idx.SerializeSharded} {
+		serialized, _ := serialize()
+		var loaded Index
+		if err := loaded.deserializeAny(serialized); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if m := loaded.Meta(); m.BuildID != "release-42" || m.BuiltAt.Before(begun) || m.BuiltAt.After(time.Now()) {
+			t.Fatalf("unexpected meta %+v", m)
+		}
+		s := loaded.Meta().Stats
+		if s == nil || s.Rows != 2 || s.Shards != len(idx.private) || s.Terms != 2 || s.Duration <= 0 {
+			t.Fatalf("unexpected stats %+v", s)
+		}
+		if s.Opts.BuildID != "release-42" || s.Opts.MinWordLength != 3 || len(s.Opts.Callbacks) != 1 || s.Opts.Callbacks[0] != "Payload" {
+			t.Fatalf("unexpected build opts %+v", s.Opts)
+		}
+		if rebuilt := s.Opts.NewOpts(); rebuilt.BuildID != "release-42" || rebuilt.Validate() != nil {
+			t.Fatalf("unexpected rebuilt opts %+v", rebuilt)
+		}
+	}
+	idx.Stamp("release-43")
+	if m := idx.Meta(); m.BuildID != "release-43" {
+		t.Fatalf("expected release-43, got %+v", m)
+	}
+	if err := idx.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if m := new(Index).Meta(); m.BuildID != "" || !m.BuiltAt.IsZero() {
+		t.Fatalf("expected empty meta, got %+v", m)
+	}
+}
@@ -0,0 +1,80 @@
+package fulltext
+
+import "fmt"
+import "io"
+import "net/http"
+import "sync/atomic"
+import "time"
+
+// Metrics receives counters from the index. Methods are called concurrently, so implementations must be thread safe.
+type Metrics interface {
+	// Lookup is called once per Lookup
+	Lookup()
+	// ShardProbe is called for every shard a lookup searches
+	ShardProbe(shard int)
+	// FalsePositive is called for every filter answer a lookup detected as false and discarded
+	FalsePositive(shard int)
+	// Build is called once New finished building rows into shards
+	Build(rows, shards int, d time.Duration)
+}
+
+// WithMetrics reports the counters of the index to m, nil disables reporting.
+// WithMetrics is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) WithMetrics(m Metrics) *Index {
+	i.metrics = m
+	return i
+}
+
+// PrometheusMetrics implements Metrics by exposing the counters in the Prometheus text format when served over HTTP
+type PrometheusMetrics struct {
+	lookups        atomic.Uint64
+	shardProbes    atomic.Uint64
+	falsePositives atomic.Uint64
+	builds         atomic.Uint64
+	buildRows      atomic.Uint64
+	buildNanos     atomic.Uint64
+}
+
+// NewPrometheusMetrics creates a Metrics adapter to be registered as the /metrics handler, or served next to an existing one
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return new(PrometheusMetrics)
+}
+
+func (m *PrometheusMetrics) Lookup()                 { m.lookups.Add(1) }
+func (m *PrometheusMetrics) ShardProbe(shard int)    { m.shardProbes.Add(1) }
+func (m *PrometheusMetrics) FalsePositive(shard int) { m.falsePositives.Add(1) }
+
+func (m *PrometheusMetrics) Build(rows, shards int, d time.Duration) {
+	m.builds.Add(1)
+	m.buildRows.Add(uint64(rows))
+	m.buildNanos.Add(uint64(d))
+}
+
+// WriteTo writes the counters in the Prometheus text exposition format
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	n, err := fmt.Fprintf(w, `# HELP fulltext_lookups_total Lookups served.
+# TYPE fulltext_lookups_total counter
+fulltext_lookups_total %d
+# HELP fulltext_shard_probes_total Shards searched by lookups.
+# TYPE fulltext_shard_probes_total counter
+fulltext_shard_probes_total %d
+# HELP fulltext_false_positives_total Filter answers detected as false and discarded.
+# TYPE fulltext_false_positives_total counter
+fulltext_false_positives_total %d
+# HELP fulltext_build_rows_total Rows built into indexes.
+# TYPE fulltext_build_rows_total counter
+fulltext_build_rows_total %d
+# HELP fulltext_build_seconds Time spent building indexes.
+# TYPE fulltext_build_seconds summary
+fulltext_build_seconds_sum %g
+fulltext_build_seconds_count %d
+`, m.lookups.Load(), m.shardProbes.Load(), m.falsePositives.Load(), m.buildRows.Load(),
+		time.Duration(m.buildNanos.Load()).Seconds(), m.builds.Load())
+	return int64(n), err
+}
+
+// ServeHTTP serves the counters to a Prometheus scraper
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WriteTo(w)
+}
@@ -0,0 +1,41 @@
+package fulltext
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestPrometheusMetrics tests that builds and lookups are counted and exposed
+func TestPrometheusMetrics(t *testing.T) {
+	m := NewPrometheusMetrics()
+	opts := NewDefaultOpts()
+	opts.Metrics = m
+	idx, err := New(opts, map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for range idx.Lookup("backend", true, true) {
+	}
+	for range idx.Lookup("python", true, true) {
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"fulltext_lookups_total 2\n",
+		"fulltext_build_rows_total 2\n",
+		"fulltext_build_seconds_count 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in metrics, got\n%s", want, body)
+		}
+	}
+	if m.shardProbes.Load() == 0 {
+		t.Fatal("expected shard probes to be counted")
+	}
+}
@@ -0,0 +1,98 @@
+package fulltext
+
+// QueryMiddleware rewrites every word looked up in the index into the words actually probed, such as synonyms,
+// before the analyzer, folding and truncation of the shards apply. Returning no words matches nothing.
+// Methods are called concurrently, so implementations must be thread safe.
+type QueryMiddleware interface {
+	Rewrite(word string) []string
+}
+
+// QueryMiddlewareFunc adapts a function to QueryMiddleware
+type QueryMiddlewareFunc func(word string) []string
+
+// Rewrite calls f
+func (f QueryMiddlewareFunc) Rewrite(word string) []string {
+	return f(word)
+}
+
+// WithQueryMiddleware rewrites the words of every lookup by the chain m, applied in order, each middleware
+// rewriting every word produced by the previous one. Rows matched by several of the rewritten words are yielded once
+// by deduplicated lookups. No middleware disables rewriting.
+// WithQueryMiddleware is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) WithQueryMiddleware(m ...QueryMiddleware) *Index {
+	i.middleware = m
+	if i.cache != nil {
+		i.cache.purge()
+	}
+	return i
+}
+
+// rewrite applies the query middleware to word, returning the distinct words to probe. The middleware sees the word
+// without the marks of LookupOpts.CaseSensitive and DisableStemming, which are kept on every rewritten word.
+func (i *Index) rewrite(word string) []string {
+	var marks string
+	for marked(word) {
+		marks, word = marks+word[:1], word[1:]
+	}
+	words := []string{word}
+	for _, m := range i.middleware {
+		var next []string
+		seen := make(map[string]struct{})
+		for _, word := range words {
+			for _, word := range m.Rewrite(word) {
+				if _, ok := seen[word]; !ok {
+					seen[word] = struct{}{}
+					next = append(next, word)
+				}
+			}
+		}
+		words = next
+	}
+	if marks != "" {
+		for n := range words {
+			words[n] = marks + words[n]
+		}
+	}
+	return words
+}
+
+// RewriteTerms replaces the words found in terms by their replacement, such as {"colour": "color"}
+func RewriteTerms(terms map[string]string) QueryMiddleware {
+	return QueryMiddlewareFunc(func(word string) []string {
+		if replacement, ok := terms[word]; ok {
+			return []string{replacement}
+		}
+		return []string{word}
+	})
+}
+
+// ExpandSynonyms looks up the words found in synonyms together with their synonyms, such as {"car": {"automobile"}}
+func ExpandSynonyms(synonyms map[string][]string) QueryMiddleware {
+	return QueryMiddlewareFunc(func(word string) []string {
+		return append([]string{word}, synonyms[word]...)
+	})
+}
+
+// MinQueryLength drops words shorter than n bytes, so they match nothing
+func MinQueryLength(n int) QueryMiddleware {
+	return QueryMiddlewareFunc(func(word string) []string {
+		if len(word) < n {
+			return nil
+		}
+		return []string{word}
+	})
+}
+
+// BlockTerms drops the listed words, so they match nothing
+func BlockTerms(terms ...string) QueryMiddleware {
+	blocked := make(map[string]struct{}, len(terms))
+	for _, term := range terms {
+		blocked[term] = struct{}{}
+	}
+	return QueryMiddlewareFunc(func(word string) []string {
+		if _, ok := blocked[word]; ok {
+			return nil
+		}
+		return []string{word}
+	})
+}
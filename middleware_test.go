@@ -0,0 +1,72 @@
+package fulltext
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestQueryMiddleware tests rewriting, expanding, dropping and blocking lookup words
+func TestQueryMiddleware(t *testing.T) {
+	idx, err := New(NewDefaultOpts(), map[string][]string{
+		"doc:1": {"color", "car"},
+		"doc:2": {"automobile", "password"},
+		"doc:3": {"bicycle"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.WithQueryMiddleware(
+		RewriteTerms(map[string]string{"colour": "color"}),
+		ExpandSynonyms(map[string][]string{"car": {"automobile", "car"}, "vehicle": {"car", "bicycle"}}),
+		QueryMiddlewareFunc(func(word string) []string { return []string{strings.ToLower(word)} }),
+		MinQueryLength(5),
+		BlockTerms("password"),
+	)
+	lookup := func(word string) []string {
+		var results []string
+		for pk := range idx.Lookup(word, true, true) {
+			results = append(results, pk)
+		}
+		sort.Strings(results)
+		return results
+	}
+	for word, expected := range map[string]string{
+		"colour":   "doc:1",
+		"car":      "doc:2",
+		"vehicle":  "doc:3",
+		"BICYCLE":  "doc:3",
+		"password": "",
+	} {
+		if got := strings.Join(lookup(word), " "); got != expected {
+			t.Errorf("expected [%s] for %q, got [%s]", expected, word, got)
+		}
+	}
+	idx.WithQueryMiddleware(ExpandSynonyms(map[string][]string{"car": {"automobile"}}))
+	if got := strings.Join(lookup("car"), " "); got != "doc:1 doc:2" {
+		t.Errorf("expected [doc:1 doc:2] for synonyms, got [%s]", got)
+	}
+}
+
+// TestQueryMiddlewareMarked tests that middleware sees case sensitive lookups without their mark
+func TestQueryMiddlewareMarked(t *testing.T) {
+	opts := NewDefaultOpts()
+	opts.DualCase = true
+	idx, err := New(opts, map[string][]string{
+		"doc:1": {"Secret", "Color"},
+		"doc:2": {"public"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.WithQueryMiddleware(RewriteTerms(map[string]string{"Colour": "Color"}), BlockTerms("Secret"))
+	for word, expected := range map[string]string{"Secret": "", "Colour": "doc:1", "public": "doc:2"} {
+		var results []string
+		for pk := range idx.LookupWith(word, &LookupOpts{Exact: true, Dedup: true, CaseSensitive: true}) {
+			results = append(results, pk)
+		}
+		if got := strings.Join(results, " "); got != expected {
+			t.Errorf("expected [%s] for case sensitive %q, got [%s]", expected, word, got)
+		}
+	}
+}
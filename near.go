@@ -0,0 +1,95 @@
+package fulltext
+
+import "sort"
+import "strings"
+
+// addTokens records the ordered tokens of row pos
+func (p *index) addTokens(pos int, tokens []string) {
+	if tokens == nil {
+		return
+	}
+	for len(p.Tokens) < pos {
+		p.Tokens = append(p.Tokens, nil)
+	}
+	p.Tokens[pos-1] = tokens
+}
+
+// near reports whether row pos holds tokens matching a and b at most maxDistance tokens apart.
+// Tokens match a query like an exact Lookup does, by prefix.
+func (p *index) near(pos uint64, a, b string, maxDistance int) bool {
+	if pos == 0 || pos > uint64(len(p.Tokens)) {
+		return false
+	}
+	lastA, lastB := -1, -1
+	for n, token := range p.Tokens[pos-1] {
+		token = p.query(token)
+		if strings.HasPrefix(token, a) {
+			lastA = n
+			if lastB >= 0 && n-lastB <= maxDistance {
+				return true
+			}
+		}
+		if strings.HasPrefix(token, b) {
+			lastB = n
+			if lastA >= 0 && n-lastA <= maxDistance {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// phrase reports whether row pos holds consecutive tokens matching words in order, like an exact Lookup does, by prefix
+func (p *index) phrase(pos uint64, words []string) bool {
+	if pos == 0 || pos > uint64(len(p.Tokens)) {
+		return false
+	}
+	tokens := p.Tokens[pos-1]
+	for start := 0; start+len(words) <= len(tokens); start++ {
+		matched := true
+		for n, word := range words {
+			if !strings.HasPrefix(p.query(tokens[start+n]), word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupNear iterates the primary keys of rows where words a and b occur at most maxDistance tokens apart.
+// Token positions must have been recorded with NewOpts.Tokens, rows without them never match.
+func (i *Index) LookupNear(a, b string, maxDistance int) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		hitsA := make(map[int]map[uint64]struct{})
+		i.lookup(a, true, true, func(shard int, pos uint64) bool {
+			if len(i.private[shard].Tokens) > 0 {
+				if hitsA[shard] == nil {
+					hitsA[shard] = make(map[uint64]struct{})
+				}
+				hitsA[shard][pos] = struct{}{}
+			}
+			return true
+		})
+		both := make(map[int][]uint64)
+		i.lookup(b, true, true, func(shard int, pos uint64) bool {
+			if _, ok := hitsA[shard][pos]; ok {
+				both[shard] = append(both[shard], pos)
+			}
+			return true
+		})
+		for shard := range i.private {
+			p := &i.private[shard]
+			positions := both[shard]
+			sort.Slice(positions, func(x, y int) bool { return positions[x] < positions[y] })
+			for _, pos := range positions {
+				if p.near(pos, p.query(a), p.query(b), maxDistance) && !yield(p.key(pos)) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package fulltext
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// TestLookupNear tests that only rows with both words close together match, also after a round trip
+func TestLookupNear(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"fast", "golang", "backend", "server"},
+		"doc:2": {"golang", "is", "a", "language", "for", "the", "backend"},
+		"doc:3": {"rust", "backend"},
+	}
+	opts := NewDefaultOpts()
+	opts.Tokens = func(pk string) []string { return data[pk] }
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	near := func(idx *Index, maxDistance int) (keys []string) {
+		for pk := range idx.LookupNear("golang", "backend", maxDistance) {
+			keys = append(keys, pk)
+		}
+		sort.Strings(keys)
+		return
+	}
+	if keys := near(idx, 1); fmt.Sprint(keys) != "[doc:1]" {
+		t.Fatalf("expected [doc:1], got %v", keys)
+	}
+	if keys := near(idx, 6); fmt.Sprint(keys) != "[doc:1 doc:2]" {
+		t.Fatalf("expected [doc:1 doc:2], got %v", keys)
+	}
+	data2, _ := idx.SerializeProto()
+	var loaded Index
+	if err := loaded.DeserializeProto(data2); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keys := near(&loaded, 1); fmt.Sprint(keys) != "[doc:1]" {
+		t.Fatalf("expected [doc:1] after round trip, got %v", keys)
+	}
+}
+
+// TestLookupQueryPhrase tests that phrases match their words in order and adjacent across the whole phrase
+func TestLookupQueryPhrase(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"fast", "golang", "backend", "server"},
+		"doc:2": {"backend", "golang"},
+		"doc:3": {"golang", "fast", "backend"},
+	}
+	opts := NewDefaultOpts()
+	opts.Tokens = func(pk string) []string { return data[pk] }
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for query, want := range map[string]string{
+		`"golang backend"`:         "[doc:1]",
+		`"backend golang"`:         "[doc:2]",
+		`"fast golang backend"`:    "[doc:1]",
+		`"golang fast backend"`:    "[doc:3]",
+		`"fast backend"`:           "[doc:3]",
+		`golang -"golang backend"`: "[doc:2 doc:3]",
+	} {
+		q, err := ParseQuery(query)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		keys := []string{}
+		for pk := range idx.LookupQuery(q, true) {
+			keys = append(keys, pk)
+		}
+		sort.Strings(keys)
+		if fmt.Sprint(keys) != want {
+			t.Fatalf("expected %s for %q, got %v", want, query, keys)
+		}
+	}
+}
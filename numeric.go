@@ -0,0 +1,101 @@
+package fulltext
+
+import "encoding/json"
+import "fmt"
+import "math"
+import "sort"
+
+var ErrMalformedNumeric = fmt.Errorf("malformed_numeric_field")
+
+// Range is an inclusive numeric interval
+type Range struct {
+	Min, Max float64
+}
+
+// Between matches values from min to max inclusive
+func Between(min, max float64) Range {
+	return Range{Min: min, Max: max}
+}
+
+// AtLeast matches values of min or more
+func AtLeast(min float64) Range {
+	return Range{Min: min, Max: math.Inf(1)}
+}
+
+// AtMost matches values of max or less
+func AtMost(max float64) Range {
+	return Range{Min: math.Inf(-1), Max: max}
+}
+
+// NumericField is a sidecar index of one numeric value (price, timestamp, ...) per primary key, sorted for range scans.
+type NumericField struct {
+	Values []float64 `json:"values"`
+	Keys   []string  `json:"keys"`
+}
+
+// NewNumericField builds the sidecar from the values of each primary key
+func NewNumericField(values map[string]float64) *NumericField {
+	var f = &NumericField{
+		Values: make([]float64, 0, len(values)),
+		Keys:   make([]string, 0, len(values)),
+	}
+	for pk := range values {
+		f.Keys = append(f.Keys, pk)
+	}
+	sort.Slice(f.Keys, func(a, b int) bool {
+		if values[f.Keys[a]] != values[f.Keys[b]] {
+			return values[f.Keys[a]] < values[f.Keys[b]]
+		}
+		return f.Keys[a] < f.Keys[b]
+	})
+	for _, pk := range f.Keys {
+		f.Values = append(f.Values, values[pk])
+	}
+	return f
+}
+
+// Range iterates the primary keys whose value lies in r, in ascending value order
+func (f *NumericField) Range(r Range) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		for k := sort.SearchFloat64s(f.Values, r.Min); k < len(f.Values) && f.Values[k] <= r.Max; k++ {
+			if !yield(f.Keys[k]) {
+				return
+			}
+		}
+	}
+}
+
+// Serialize serializes to JSON
+func (f *NumericField) Serialize() ([]byte, error) {
+	return json.Marshal(f)
+}
+
+// Deserialize deserializes from JSON, failing with ErrMalformedNumeric unless every key has a value and the values
+// are sorted numbers, leaving f as it was
+func (f *NumericField) Deserialize(data []byte) error {
+	var loaded NumericField
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return err
+	}
+	if len(loaded.Keys) != len(loaded.Values) || !sort.Float64sAreSorted(loaded.Values) {
+		return ErrMalformedNumeric
+	}
+	for _, v := range loaded.Values {
+		if math.IsNaN(v) {
+			return ErrMalformedNumeric
+		}
+	}
+	*f = loaded
+	return nil
+}
+
+// AttachNumeric makes a numeric sidecar available to Search(...).Where(name, ...).
+// Sidecars are not part of the index serialization, persist them with NumericField.Serialize.
+// AttachNumeric is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) AttachNumeric(name string, f *NumericField) *Index {
+	if i.numeric == nil {
+		i.numeric = make(map[string]*NumericField)
+	}
+	i.numeric[name] = f
+	return i
+}
@@ -0,0 +1,158 @@
+package fulltext
+
+import "fmt"
+
+var ErrOutOfRange = fmt.Errorf("opt_out_of_range")
+var ErrConflictingOpts = fmt.Errorf("conflicting_opts")
+
+// OptsError names the option rejected by NewOpts.Validate, it wraps ErrOutOfRange or ErrConflictingOpts
+type OptsError struct {
+	Field string
+	Err   error
+}
+
+func (e *OptsError) Error() string {
+	return fmt.Sprintf("fulltext: opts: %s: %v", e.Field, e.Err)
+}
+
+func (e *OptsError) Unwrap() error {
+	return e.Err
+}
+
+// Valid ranges of the options checked by Validate
+const (
+	MaxMinWordLength     = 32
+	MaxBucketingExponent = 40
+)
+
+// Validate checks the options, New calls it before building. Valid are:
+//   - MinWordLength from 1 to MaxMinWordLength
+//   - BucketingExponent up to MaxBucketingExponent
+//   - MaxWordLength 0, or at least MinWordLength; SkipLongWords only with a MaxWordLength
+//   - TargetShardRows, ShardBuildBudget, GetterTimeout, GetterRetries, BagCacheRows, MaxBucketDepth and MapShardRows not negative; GetterRetries only with a GetterTimeout
+//   - HashSeed or RandomHashSeed, not both; MapShardRows only without a FilterBackend; IndexUnstemmed only with an Analyzer
+//   - Duplicates one of the DuplicatePolicy constants
+func (opts *NewOpts) Validate() error {
+	switch {
+	case opts.MinWordLength < 1 || opts.MinWordLength > MaxMinWordLength:
+		return &OptsError{Field: "MinWordLength", Err: ErrOutOfRange}
+	case opts.BucketingExponent > MaxBucketingExponent:
+		return &OptsError{Field: "BucketingExponent", Err: ErrOutOfRange}
+	case opts.MaxWordLength < 0:
+		return &OptsError{Field: "MaxWordLength", Err: ErrOutOfRange}
+	case opts.MaxWordLength > 0 && opts.MaxWordLength < int(opts.MinWordLength):
+		return &OptsError{Field: "MaxWordLength", Err: ErrConflictingOpts}
+	case opts.SkipLongWords && opts.MaxWordLength == 0:
+		return &OptsError{Field: "SkipLongWords", Err: ErrConflictingOpts}
+	case opts.TargetShardRows < 0:
+		return &OptsError{Field: "TargetShardRows", Err: ErrOutOfRange}
+	case opts.ShardBuildBudget < 0:
+		return &OptsError{Field: "ShardBuildBudget", Err: ErrOutOfRange}
+	case opts.GetterTimeout < 0:
+		return &OptsError{Field: "GetterTimeout", Err: ErrOutOfRange}
+	case opts.GetterRetries < 0:
+		return &OptsError{Field: "GetterRetries", Err: ErrOutOfRange}
+	case opts.BagCacheRows < 0:
+		return &OptsError{Field: "BagCacheRows", Err: ErrOutOfRange}
+	case opts.MaxBucketDepth < 0:
+		return &OptsError{Field: "MaxBucketDepth", Err: ErrOutOfRange}
+	case opts.MapShardRows < 0:
+		return &OptsError{Field: "MapShardRows", Err: ErrOutOfRange}
+	case opts.MapShardRows > 0 && opts.FilterBackend != "":
+		return &OptsError{Field: "MapShardRows", Err: ErrConflictingOpts}
+	case opts.Duplicates > DuplicateError:
+		return &OptsError{Field: "Duplicates", Err: ErrOutOfRange}
+	case opts.RoaringPostings < 0 || opts.RoaringPostings > 1:
+		return &OptsError{Field: "RoaringPostings", Err: ErrOutOfRange}
+	case opts.GetterRetries > 0 && opts.GetterTimeout == 0:
+		return &OptsError{Field: "GetterRetries", Err: ErrConflictingOpts}
+	case opts.IndexUnstemmed && opts.Analyzer == "":
+		return &OptsError{Field: "IndexUnstemmed", Err: ErrConflictingOpts}
+	case opts.HashSeed != 0 && opts.RandomHashSeed:
+		return &OptsError{Field: "RandomHashSeed", Err: ErrConflictingOpts}
+	}
+	return nil
+}
+
+// OptsBuilder builds validated NewOpts starting from NewDefaultOpts
+type OptsBuilder struct {
+	opts NewOpts
+}
+
+// NewOptsBuilder starts from the default options
+func NewOptsBuilder() *OptsBuilder {
+	return &OptsBuilder{opts: *NewDefaultOpts()}
+}
+
+func (b *OptsBuilder) FalsePositiveFunctions(n byte) *OptsBuilder {
+	b.opts.FalsePositiveFunctions = n
+	return b
+}
+
+func (b *OptsBuilder) BucketingExponent(n byte) *OptsBuilder {
+	b.opts.BucketingExponent = n
+	return b
+}
+
+func (b *OptsBuilder) MinShards(n byte) *OptsBuilder {
+	b.opts.MinShards = n
+	return b
+}
+
+func (b *OptsBuilder) MinWordLength(n byte) *OptsBuilder {
+	b.opts.MinWordLength = n
+	return b
+}
+
+func (b *OptsBuilder) Sync(sync bool) *OptsBuilder {
+	b.opts.Sync = sync
+	return b
+}
+
+func (b *OptsBuilder) StoreTerms(store bool) *OptsBuilder {
+	b.opts.StoreTerms = store
+	return b
+}
+
+// MaxWordLength bounds the indexed word length, skipping longer words instead of truncating them when skip is set
+func (b *OptsBuilder) MaxWordLength(n int, skip bool) *OptsBuilder {
+	b.opts.MaxWordLength = n
+	b.opts.SkipLongWords = skip
+	return b
+}
+
+func (b *OptsBuilder) ASCIIFold(fold bool) *OptsBuilder {
+	b.opts.ASCIIFold = fold
+	return b
+}
+
+func (b *OptsBuilder) Analyzer(name string) *OptsBuilder {
+	b.opts.Analyzer = name
+	return b
+}
+
+func (b *OptsBuilder) TargetShardRows(n int) *OptsBuilder {
+	b.opts.TargetShardRows = n
+	return b
+}
+
+func (b *OptsBuilder) BloomBitsPerShingle(n byte) *OptsBuilder {
+	b.opts.BloomBitsPerShingle = n
+	return b
+}
+
+// Apply sets any other options through fn
+func (b *OptsBuilder) Apply(fn func(opts *NewOpts)) *OptsBuilder {
+	fn(&b.opts)
+	return b
+}
+
+// Build validates and returns the options
+func (b *OptsBuilder) Build() (*NewOpts, error) {
+	opts := b.opts
+	opts.configured = true
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &opts, nil
+}
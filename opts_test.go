@@ -0,0 +1,48 @@
+package fulltext
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestValidateOpts tests that New rejects nonsensical options with the option at fault
+func TestValidateOpts(t *testing.T) {
+	data := map[string][]string{"doc:1": {"golang"}}
+	tests := []struct {
+		set   func(opts *NewOpts)
+		field string
+		err   error
+	}{
+		{func(opts *NewOpts) { opts.MinWordLength = 0 }, "MinWordLength", ErrOutOfRange},
+		{func(opts *NewOpts) { opts.BucketingExponent = 60 }, "BucketingExponent", ErrOutOfRange},
+		{func(opts *NewOpts) { opts.MaxWordLength = 2 }, "MaxWordLength", ErrConflictingOpts},
+		{func(opts *NewOpts) { opts.SkipLongWords = true }, "SkipLongWords", ErrConflictingOpts},
+		{func(opts *NewOpts) { opts.GetterRetries = 3 }, "GetterRetries", ErrConflictingOpts},
+		{func(opts *NewOpts) { opts.HashSeed, opts.RandomHashSeed = 1, true }, "RandomHashSeed", ErrConflictingOpts},
+	}
+	for _, test := range tests {
+		opts := NewDefaultOpts()
+		test.set(opts)
+		_, err := New(opts, data, nil)
+		var oerr *OptsError
+		if !errors.As(err, &oerr) || oerr.Field != test.field || !errors.Is(err, test.err) {
+			t.Fatalf("expected %v in %s, got %v", test.err, test.field, err)
+		}
+	}
+}
+
+// TestOptsBuilder tests that the builder starts from the defaults and validates
+func TestOptsBuilder(t *testing.T) {
+	opts, err := NewOptsBuilder().MinWordLength(4).StoreTerms(true).Apply(func(opts *NewOpts) {
+		opts.HashSeed = 42
+	}).Build()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !opts.configured || opts.MinWordLength != 4 || !opts.StoreTerms || opts.HashSeed != 42 || opts.MinShards != 3 {
+		t.Fatalf("expected configured defaults with overrides, got %+v", opts)
+	}
+	if _, err := NewOptsBuilder().MaxWordLength(0, true).Build(); !errors.Is(err, ErrConflictingOpts) {
+		t.Fatalf("expected ErrConflictingOpts, got %v", err)
+	}
+}
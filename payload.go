@@ -0,0 +1,30 @@
+package fulltext
+
+// addPayload records the stored payload of row pos
+func (p *index) addPayload(pos int, payload []byte) {
+	if payload == nil {
+		return
+	}
+	for len(p.Payloads) < pos {
+		p.Payloads = append(p.Payloads, nil)
+	}
+	p.Payloads[pos-1] = payload
+}
+
+// payload returns the stored payload of row pos
+func (p *index) payload(pos uint64) []byte {
+	if pos == 0 || pos > uint64(len(p.Payloads)) {
+		return nil
+	}
+	return p.Payloads[pos-1]
+}
+
+// LookupWithPayload iterates like Lookup, also yielding the payload stored for each row with NewOpts.Payload.
+// Rows without a payload yield nil. Payloads are shared with the index and must not be modified.
+func (i *Index) LookupWithPayload(word string, exact, dedup bool) func(yield func(primaryKey string, payload []byte) bool) {
+	return func(yield func(string, []byte) bool) {
+		i.lookup(word, exact, dedup, func(shard int, pos uint64) bool {
+			return yield(i.private[shard].key(pos), i.private[shard].payload(pos))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestLookupWithPayload tests that stored payloads come back with their keys after serialization
+func TestLookupWithPayload(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+		"doc:3": {"python"},
+	}
+	titles := map[string]string{"doc:1": "Go services", "doc:2": "Rust services"}
+	opts := NewDefaultOpts()
+	opts.Payload = func(pk string) []byte {
+		if title, ok := titles[pk]; ok {
+			return []byte(title)
+		}
+		return nil
+	}
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, serialize := range []func() ([]byte, error){idx.Serialize, idx.SerializeProto} {
+		serialized, _ := serialize()
+		var loaded Index
+		if err := loaded.deserializeAny(serialized); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		results := make(map[string]string)
+		for pk, payload := range loaded.LookupWithPayload("backend", true, true) {
+			results[pk] = string(payload)
+		}
+		if len(results) != 2 || results["doc:1"] != "Go services" || results["doc:2"] != "Rust services" {
+			t.Fatalf("unexpected payloads %v", results)
+		}
+		for pk, payload := range loaded.LookupWithPayload("python", true, true) {
+			if pk != "doc:3" || payload != nil {
+				t.Errorf("expected doc:3 without payload, got %s %q", pk, payload)
+			}
+		}
+	}
+}
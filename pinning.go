@@ -0,0 +1,166 @@
+package fulltext
+
+import "runtime"
+import "sort"
+import "sync"
+import "sync/atomic"
+
+// PinOpts configures the pinned workers of WithPinning
+type PinOpts struct {
+	// Workers is the number of workers, each locked to its own OS thread. 0 = GOMAXPROCS.
+	Workers int
+
+	// HotShards is the number of most probed shards pinned to the workers, the other shards are probed on fresh
+	// goroutines as without pinning. The ranking is refreshed every 1024 shard probes. 0 = pin every shard.
+	HotShards int
+
+	// CPUs lists the CPUs worker n is pinned to as CPUs[n % len(CPUs)], such as the CPUs of one NUMA node.
+	// Affinity is a hint, only applied on Linux. nil = no affinity.
+	CPUs []int
+}
+
+// WithPinning probes the hot shards of every lookup on a bounded pool of workers, shard n always on worker
+// n % Workers, so the filters of a shard stay in the caches (and NUMA node) of one CPU. A shard whose worker is busy
+// is probed on a fresh goroutine instead of queueing behind it. Nil stops the workers and disables pinning, otherwise
+// the workers stop once the index is garbage collected.
+// WithPinning is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) WithPinning(opts *PinOpts) *Index {
+	if i.pinning != nil {
+		i.pinning.cleanup.Stop()
+		i.pinning.stop()
+		i.pinning = nil
+	}
+	if opts != nil {
+		i.pinning = newPinPool(opts, len(i.private))
+		i.pinning.cleanup = runtime.AddCleanup(i, (*pinPool).close, i.pinning)
+	}
+	return i
+}
+
+// pinRerank is the number of shard probes after which the hot shards are ranked again
+const pinRerank = 1024
+
+// pinPool runs shard probes on workers locked to OS threads
+type pinPool struct {
+	opts    *PinOpts
+	workers []chan func()
+	hotN    int
+	probes  []atomic.Uint64
+	total   atomic.Uint64
+	hotMut  sync.Mutex
+	hot     atomic.Pointer[[]bool]
+	wg      sync.WaitGroup
+	cleanup runtime.Cleanup
+
+	// closeMut guards sending probes to the workers against closing them
+	closeMut sync.RWMutex
+	closed   bool
+}
+
+func newPinPool(opts *PinOpts, shards int) *pinPool {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	pool := &pinPool{opts: opts, workers: make([]chan func(), workers), hotN: opts.HotShards, probes: make([]atomic.Uint64, shards)}
+	for n := range pool.workers {
+		pool.workers[n] = make(chan func())
+		pool.wg.Add(1)
+		go func(tasks chan func(), cpu int) {
+			defer pool.wg.Done()
+			// the thread is never unlocked, so it exits with the worker instead of carrying its affinity elsewhere
+			runtime.LockOSThread()
+			if cpu >= 0 {
+				_ = setAffinity(cpu)
+			}
+			for task := range tasks {
+				task()
+			}
+		}(pool.workers[n], pinnedCPU(opts.CPUs, n))
+	}
+	return pool
+}
+
+// pinnedCPU returns the CPU of worker n, -1 for none
+func pinnedCPU(cpus []int, n int) int {
+	if len(cpus) == 0 {
+		return -1
+	}
+	return cpus[n%len(cpus)]
+}
+
+// close tells the workers to exit once their running probes finish, later probes run on fresh goroutines
+func (pool *pinPool) close() {
+	pool.closeMut.Lock()
+	defer pool.closeMut.Unlock()
+	if pool.closed {
+		return
+	}
+	pool.closed = true
+	for _, tasks := range pool.workers {
+		close(tasks)
+	}
+}
+
+// stop stops the workers once their running probes finish
+func (pool *pinPool) stop() {
+	pool.close()
+	pool.wg.Wait()
+}
+
+// send hands fn to worker n when it is idle, reporting whether it did
+func (pool *pinPool) send(n int, fn func()) bool {
+	pool.closeMut.RLock()
+	defer pool.closeMut.RUnlock()
+	if pool.closed {
+		return false
+	}
+	select {
+	case pool.workers[n] <- fn:
+		return true
+	default:
+		return false
+	}
+}
+
+// pinned counts a probe of shard and reports whether shard is hot
+func (pool *pinPool) pinned(shard int) bool {
+	if pool.hotN <= 0 {
+		return true
+	}
+	if shard >= len(pool.probes) {
+		return false
+	}
+	pool.probes[shard].Add(1)
+	if pool.total.Add(1)%pinRerank == 1 {
+		pool.rank()
+	}
+	hot := pool.hot.Load()
+	return hot != nil && (*hot)[shard]
+}
+
+// rank marks the hotN most probed shards as hot
+func (pool *pinPool) rank() {
+	if !pool.hotMut.TryLock() {
+		return
+	}
+	defer pool.hotMut.Unlock()
+	order := make([]int, len(pool.probes))
+	for shard := range order {
+		order[shard] = shard
+	}
+	sort.SliceStable(order, func(a, b int) bool { return pool.probes[order[a]].Load() > pool.probes[order[b]].Load() })
+	hot := make([]bool, len(pool.probes))
+	for _, shard := range order[:min(pool.hotN, len(order))] {
+		hot[shard] = true
+	}
+	pool.hot.Store(&hot)
+}
+
+// spawn runs fn probing shard on the worker of the shard when it is hot and the worker idle, otherwise on a fresh goroutine
+func (i *Index) spawn(shard int, fn func()) {
+	if pool := i.pinning; pool != nil && pool.pinned(shard) && pool.send(shard%len(pool.workers), fn) {
+		return
+	}
+	go fn()
+}
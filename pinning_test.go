@@ -0,0 +1,68 @@
+package fulltext
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestWithPinning tests that pinned lookups match unpinned ones, the most probed shards get hot,
+// stopping the pool stops its workers, and collecting the index stops them too
+func TestWithPinning(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	before := runtime.NumGoroutine()
+	lookup := func(word string) string {
+		var keys []string
+		for pk := range idx.Lookup(word, true, true) {
+			keys = append(keys, pk)
+		}
+		sort.Strings(keys)
+		return fmt.Sprint(keys)
+	}
+	expected := lookup("common")
+	idx.WithPinning(&PinOpts{Workers: 2, CPUs: []int{0}})
+	for n := 0; n < 10; n++ {
+		if got := lookup("common"); got != expected {
+			t.Fatalf("expected %s, got %s", expected, got)
+		}
+	}
+	idx.WithPinning(&PinOpts{Workers: 2, HotShards: 1})
+	var shard int
+	for pk := range idx.Lookup("word007", true, true) {
+		for curr := range idx.private {
+			for pos := uint64(1); pos <= idx.private[curr].Rows; pos++ {
+				if idx.private[curr].key(pos) == pk {
+					shard = curr
+				}
+			}
+		}
+	}
+	for n := 0; n < 2*pinRerank; n++ {
+		idx.pinning.pinned(shard)
+	}
+	if hot := *idx.pinning.hot.Load(); !hot[shard] || len(hot) != len(idx.private) {
+		t.Fatalf("expected shard %d to be hot, got %v", shard, hot)
+	}
+	stopped := idx.pinning
+	idx.WithPinning(nil)
+	idx.pinning = stopped
+	if got := lookup("common"); got != expected {
+		t.Fatalf("expected %s from a stopped pool, got %s", expected, got)
+	}
+	idx.pinning = nil
+	wait := func() {
+		for wait := 0; wait < 100 && runtime.NumGoroutine() > before; wait++ {
+			runtime.GC()
+			time.Sleep(time.Millisecond)
+		}
+		if after := runtime.NumGoroutine(); after > before {
+			t.Fatalf("expected at most %d goroutines, got %d", before, after)
+		}
+	}
+	wait()
+
+	newShardedTestIndex(t).WithPinning(&PinOpts{Workers: 2})
+	wait()
+}
@@ -0,0 +1,30 @@
+package fulltext
+
+import "sync"
+
+// bagPool recycles the countBag and initialBag maps of shard and bucket builds, which keep their
+// grown storage across builds instead of being reallocated and collected for every shard
+var bagPool = sync.Pool{New: func() any { return make(map[string]uint64) }}
+
+// keysPool recycles the primary key maps of shard builds
+var keysPool = sync.Pool{New: func() any { return make(map[int]string) }}
+
+func getBag() map[string]uint64 {
+	return bagPool.Get().(map[string]uint64)
+}
+
+// putBag empties a bag no longer referenced and returns it to the pool
+func putBag(bag map[string]uint64) {
+	clear(bag)
+	bagPool.Put(bag)
+}
+
+func getKeys() map[int]string {
+	return keysPool.Get().(map[int]string)
+}
+
+// putKeys empties a primary key map no longer referenced and returns it to the pool
+func putKeys(keys map[int]string) {
+	clear(keys)
+	keysPool.Put(keys)
+}
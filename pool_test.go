@@ -0,0 +1,29 @@
+package fulltext
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestPooledBags tests that shards built from recycled maps still find their rows, and that recycled maps are empty
+func TestPooledBags(t *testing.T) {
+	for build := 0; build < 2; build++ {
+		idx := newShardedTestIndex(t)
+		for j := 0; j < 40; j++ {
+			want := fmt.Sprintf("doc:%03d", j)
+			var found bool
+			for pk := range idx.Lookup(fmt.Sprintf("word%03d", j), true, true) {
+				found = found || pk == want
+			}
+			if !found {
+				t.Fatalf("expected %s to be found", want)
+			}
+		}
+	}
+	bag := getBag()
+	bag["stale"] = 1
+	putBag(bag)
+	if bag := getBag(); len(bag) != 0 {
+		t.Fatalf("expected an empty bag, got %v", bag)
+	}
+}
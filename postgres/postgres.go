@@ -0,0 +1,215 @@
+// package postgres keeps a fulltext index of a text column in sync with a PostgreSQL table by tailing a logical
+// replication slot decoded by wal2json, a drop-in alternative to pg_trgm for read-heavy services.
+// Any database/sql driver speaking $n placeholders, such as pgx or lib/pq, can be used.
+//
+//	SELECT pg_create_logical_replication_slot('fulltext', 'wal2json');
+//
+//	f := postgres.NewFollower(db, "fulltext", "public.products", "sku", "description", nil)
+//	if err := f.Load(ctx); err != nil {
+//		...
+//	}
+//	go f.Run(ctx)
+//	for pk := range f.Lookup("golang", true, true) {
+//		...
+//	}
+package postgres
+
+import "context"
+import "database/sql"
+import "encoding/json"
+import "fmt"
+import "strings"
+import "time"
+import "github.com/neurlang/fulltext"
+import "github.com/neurlang/fulltext/ingest"
+import "github.com/neurlang/fulltext/stream"
+
+// Follower tails the replication slot of a table into a stream.Stream.
+// The changes are peeked, and the slot advanced past them once they are appended, so changes not yet appended when the
+// process stops are read again by the next Run.
+type Follower struct {
+	// Interval waits between polls of an idle slot. Default = 1s.
+	Interval time.Duration
+	// Changes bounds the changes read from the slot per poll. Default = 1000.
+	Changes int
+
+	db                   *sql.DB
+	slot, table          string
+	pkColumn, textColumn string
+	opts                 *fulltext.NewOpts
+	stream               *stream.Stream
+}
+
+// NewFollower follows the table, named as schema.table, through the wal2json slot. Opts can be nil. Primary keys of
+// varying length, such as integers, are padded with NUL bytes unless opts sets KeyPadding, see ingest.PadKeys.
+func NewFollower(db *sql.DB, slot, table, pkColumn, textColumn string, opts *fulltext.NewOpts) *Follower {
+	opts = ingest.PadKeys(opts)
+	return &Follower{
+		Interval:   time.Second,
+		Changes:    1000,
+		db:         db,
+		slot:       slot,
+		table:      table,
+		pkColumn:   pkColumn,
+		textColumn: textColumn,
+		opts:       opts,
+		stream:     stream.New(nil, opts),
+	}
+}
+
+// Load builds the index from the current rows of the table, replacing the followed index. Call it before Run.
+func (f *Follower) Load(ctx context.Context) error {
+	rows, err := f.db.QueryContext(ctx, fmt.Sprintf("SELECT %s::text, %s::text FROM %s",
+		quoteIdent(f.pkColumn), quoteIdent(f.textColumn), quoteIdent(f.table)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	data := make(map[string]fulltext.BagOfWords)
+	for rows.Next() {
+		var pk string
+		var text sql.NullString
+		if err := rows.Scan(&pk, &text); err != nil {
+			return err
+		}
+		data[pk] = ingest.Words(text.String, f.opts)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	idx := new(fulltext.Index)
+	if len(data) > 0 {
+		if idx, err = fulltext.New(f.opts, data, nil); err != nil {
+			return err
+		}
+	}
+	f.stream = stream.New(idx, f.opts)
+	return nil
+}
+
+// Run appends the changes of the slot to the index until ctx is done, or reading the slot or appending the changes
+// fails. The slot is advanced past the changes of a poll once they are appended.
+func (f *Follower) Run(ctx context.Context) error {
+	for {
+		events, lsn, err := f.poll(ctx)
+		if err != nil {
+			return err
+		}
+		if lsn == "" {
+			timer := time.NewTimer(f.Interval)
+			select {
+			case <-timer.C:
+				continue
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+		batch := make(chan stream.Event, len(events))
+		for _, e := range events {
+			batch <- e
+		}
+		close(batch)
+		if err := f.stream.Consume(ctx, batch); err != nil {
+			return err
+		}
+		if _, err := f.db.ExecContext(ctx, "SELECT pg_replication_slot_advance($1, $2::pg_lsn)", f.slot, lsn); err != nil {
+			return err
+		}
+	}
+}
+
+// Lookup iterates like Index.Lookup over the followed index
+func (f *Follower) Lookup(word string, exact, dedup bool) func(yield func(primaryKey string) bool) {
+	return f.stream.Lookup(word, exact, dedup)
+}
+
+// Stream returns the stream the changes are appended to, such as to tune its batching or to serialize a snapshot
+func (f *Follower) Stream() *stream.Stream {
+	return f.stream
+}
+
+// poll peeks the next changes of the slot without consuming them, returning their events and the position of the
+// last change, "" if there are none
+func (f *Follower) poll(ctx context.Context) (events []stream.Event, lsn string, err error) {
+	rows, err := f.db.QueryContext(ctx, "SELECT lsn::text, data FROM pg_logical_slot_peek_changes($1, NULL, $2, "+
+		"'format-version', '2', 'add-tables', $3)", f.slot, f.Changes, f.table)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&lsn, &data); err != nil {
+			return nil, "", err
+		}
+		decoded, err := f.decode(data)
+		if err != nil {
+			return nil, "", err
+		}
+		events = append(events, decoded...)
+	}
+	return events, lsn, rows.Err()
+}
+
+// change is a wal2json format version 2 change
+type change struct {
+	Action   string   `json:"action"`
+	Columns  []column `json:"columns"`
+	Identity []column `json:"identity"`
+}
+
+type column struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+// decode turns a wal2json change into the events of the followed columns, ignoring transaction boundaries
+func (f *Follower) decode(data []byte) ([]stream.Event, error) {
+	var c change
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	switch c.Action {
+	case "I", "U":
+		pk, ok := value(c.Columns, f.pkColumn)
+		if !ok {
+			return nil, nil
+		}
+		text, _ := value(c.Columns, f.textColumn)
+		events := []stream.Event{{PK: pk, Words: ingest.Words(text, f.opts)}}
+		if old, ok := value(c.Identity, f.pkColumn); ok && old != pk {
+			events = append([]stream.Event{{PK: old, Delete: true}}, events...)
+		}
+		return events, nil
+	case "D":
+		if pk, ok := value(c.Identity, f.pkColumn); ok {
+			return []stream.Event{{PK: pk, Delete: true}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// value returns the named column as text, numbers and other JSON values in their JSON form, null as empty text
+func value(columns []column, name string) (string, bool) {
+	for _, c := range columns {
+		if c.Name != name {
+			continue
+		}
+		var s string
+		if json.Unmarshal(c.Value, &s) != nil {
+			s = string(c.Value)
+		}
+		return s, true
+	}
+	return "", false
+}
+
+// quoteIdent quotes every part of a possibly schema qualified identifier
+func quoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for n, part := range parts {
+		parts[n] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neurlang/fulltext"
+	"github.com/neurlang/fulltext/stream"
+)
+
+// TestDecode tests turning wal2json changes into stream events
+func TestDecode(t *testing.T) {
+	f := NewFollower(nil, "fulltext", "public.products", "sku", "description", nil)
+	tests := []struct {
+		data string
+		want []stream.Event
+	}{
+		{`{"action":"B"}`, nil},
+		{`{"action":"I","columns":[{"name":"sku","value":"A1"},{"name":"description","value":"golang backend"}]}`,
+			[]stream.Event{{PK: "A1", Words: fulltext.BagOfWords{"golang": {}, "backend": {}}}}},
+		{`{"action":"U","columns":[{"name":"sku","value":"B2"},{"name":"description","value":null}],"identity":[{"name":"sku","value":"A1"}]}`,
+			[]stream.Event{{PK: "A1", Delete: true}, {PK: "B2", Words: fulltext.BagOfWords{}}}},
+		{`{"action":"D","identity":[{"name":"sku","value":42}]}`,
+			[]stream.Event{{PK: "42", Delete: true}}},
+	}
+	for _, test := range tests {
+		got, err := f.decode([]byte(test.data))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("expected %v, got %v", test.want, got)
+		}
+	}
+	if _, err := f.decode([]byte(`{"action":`)); err == nil {
+		t.Fatal("expected an error for truncated JSON")
+	}
+}
+
+// TestQuoteIdent tests quoting schema qualified identifiers
+func TestQuoteIdent(t *testing.T) {
+	if got := quoteIdent(`public.my"table`); got != `"public"."my""table"` {
+		t.Fatalf("expected quoted identifier, got %s", got)
+	}
+}
+
+// fakeSlot is a database/sql driver serving a table and the peeked changes of its slot, advanced by
+// pg_replication_slot_advance
+type fakeSlot struct {
+	rows     [][2]string
+	changes  [][2]string
+	advanced int
+	done     func()
+}
+
+func (s *fakeSlot) Open(string) (driver.Conn, error) { return fakeConn{s}, nil }
+
+type fakeConn struct{ s *fakeSlot }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.s, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no transactions") }
+
+type fakeStmt struct {
+	s     *fakeSlot
+	query string
+}
+
+func (st fakeStmt) Close() error  { return nil }
+func (st fakeStmt) NumInput() int { return -1 }
+
+func (st fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	for n, c := range st.s.changes {
+		if c[0] == args[1] {
+			st.s.advanced = n + 1
+		}
+	}
+	if st.s.advanced == len(st.s.changes) {
+		st.s.done()
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (st fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.Contains(st.query, "pg_logical_slot_peek_changes") {
+		return &fakeRows{rows: st.s.rows}, nil
+	}
+	return &fakeRows{rows: st.s.changes[st.s.advanced:min(len(st.s.changes), st.s.advanced+int(args[1].(int64)))]}, nil
+}
+
+type fakeRows struct{ rows [][2]string }
+
+func (r *fakeRows) Columns() []string { return []string{"a", "b"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[0][0], r.rows[0][1]
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// TestRun tests following a table keyed by integers, advancing the slot only past appended changes
+func TestRun(t *testing.T) {
+	slot := &fakeSlot{changes: [][2]string{
+		{"0/1", `{"action":"I","columns":[{"name":"id","value":100},{"name":"body","value":"python"}]}`},
+		{"0/2", `{"action":"D","identity":[{"name":"id","value":3}]}`},
+	}}
+	for id := 1; id <= 12; id++ {
+		slot.rows = append(slot.rows, [2]string{fmt.Sprint(id), "golang"})
+	}
+	sql.Register("fakeslot", slot)
+	db, err := sql.Open("fakeslot", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	lookup := func(f *Follower, word string) (keys []string) {
+		for pk := range f.Lookup(word, true, true) {
+			keys = append(keys, pk)
+		}
+		sort.Strings(keys)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	slot.done = cancel
+	f := NewFollower(db, "fulltext", "public.notes", "id", "body", nil)
+	f.Interval = time.Millisecond
+	if err := f.Load(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keys := lookup(f, "golang"); len(keys) != 12 {
+		t.Fatalf("expected 12 results, got %v", keys)
+	}
+	if err := f.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if keys := lookup(f, "golang"); len(keys) != 11 {
+		t.Fatalf("expected 11 results after deleting 3, got %v", keys)
+	}
+	if keys := lookup(f, "python"); len(keys) != 1 || keys[0] != "100" {
+		t.Fatalf("expected [100], got %v", keys)
+	}
+
+	slot.advanced = 0
+	opts := fulltext.NewDefaultOpts()
+	opts.KeyPadding = &fulltext.KeyPadding{Byte: '0'}
+	f = NewFollower(db, "fulltext", "public.notes", "id", "body", opts)
+	if err := f.Run(context.Background()); !errors.Is(err, fulltext.ErrAmbiguousPadding) {
+		t.Fatalf("expected ErrAmbiguousPadding, got %v", err)
+	}
+	if slot.advanced != 0 {
+		t.Fatalf("expected the slot not advanced past the failed changes, got %d", slot.advanced)
+	}
+}
@@ -0,0 +1,89 @@
+package fulltext
+
+import "math"
+import "sort"
+
+// addDocuments counts the rows holding every word of bag, to pick the words getting a roaring posting
+func (p *index) addDocuments(bag BagOfWords) {
+	if p.documents == nil {
+		p.documents = make(map[string]uint64)
+	}
+	for word := range bag {
+		p.documents[word]++
+	}
+}
+
+// buildPostings stores a roaring bitmap posting of every word held by at least fraction of the rows, once the buckets
+// are built. The posting holds the rows an exact deduplicated lookup of the word finds in the filters, so lookups
+// served from postings match exactly like lookups probing the filters.
+func (p *index) buildPostings(fraction float64) {
+	documents := p.documents
+	p.documents = nil
+	if p.Rows == 0 || fraction <= 0 {
+		return
+	}
+	threshold := uint64(math.Ceil(fraction * float64(p.Rows)))
+	minWord := p.minWord()
+	postings := make(map[string][]byte)
+	decoded := make(map[string]*bitmap)
+	for word, n := range documents {
+		query := p.query(word)
+		if n < max(threshold, 1) || len(query) < minWord {
+			continue
+		}
+		if _, ok := postings[query]; ok {
+			continue
+		}
+		b := new(bitmap)
+		p.probe(query, minWord, true, true, 0, func(pos uint64, _ float64) bool {
+			b.add(uint32(pos))
+			return true
+		}, func() bool { return false }, func() {}, nil)
+		postings[query] = b.marshal()
+		decoded[query] = b
+	}
+	if len(postings) > 0 {
+		p.Postings, p.postings = postings, decoded
+	}
+}
+
+// posting returns the roaring posting of the query word, nil when the word has none
+func (p *index) posting(query string) *bitmap {
+	return p.postings[query]
+}
+
+// decodePostings decodes the Postings of a loaded shard once, so lookups never unmarshal them
+func (p *index) decodePostings() *ValidationError {
+	if len(p.Postings) == 0 {
+		return nil
+	}
+	decoded := make(map[string]*bitmap, len(p.Postings))
+	for word, raw := range p.Postings {
+		b, err := decodePosting(raw, p.Rows)
+		if err != nil {
+			return &ValidationError{Field: "postings", Err: err}
+		}
+		decoded[word] = b
+	}
+	p.postings = decoded
+	return nil
+}
+
+// decodePosting unmarshals a posting, rejecting positions outside the rows 1 to rows of the shard
+func decodePosting(raw []byte, rows uint64) (*bitmap, error) {
+	b, err := unmarshalBitmap(raw)
+	if err != nil || !b.each(func(pos uint32) bool { return pos >= 1 && uint64(pos) <= rows }) {
+		return nil, ErrCorrupted
+	}
+	return b, nil
+}
+
+// sortedPostings returns the words having a posting in lexicographic order
+func sortedPostings(postings map[string][]byte) []string {
+	words := make([]string, 0, len(postings))
+	for word := range postings {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
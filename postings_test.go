@@ -0,0 +1,100 @@
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// TestRoaringPostings tests that lookups served from postings match the lookups probing the filters of the same shards
+func TestRoaringPostings(t *testing.T) {
+	data := make(map[string][]string)
+	for n := 0; n < 300; n++ {
+		words := []string{fmt.Sprintf("word%03d", n), "common"}
+		if n%2 == 0 {
+			words = append(words, "frequent")
+		}
+		data[fmt.Sprintf("doc:%03d", n)] = words
+	}
+	opts := NewDefaultOpts()
+	opts.RoaringPostings = 0.2
+	roaring, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var postings int
+	for _, p := range roaring.private {
+		if _, ok := p.Postings["word000"]; ok {
+			t.Fatalf("expected no posting of a rare word")
+		}
+		postings += len(p.Postings)
+	}
+	if postings != 2*len(roaring.private) {
+		t.Fatalf("expected postings of common and frequent in every shard, got %d", postings)
+	}
+	serialized, err := roaring.SerializeProto()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var loaded Index
+	if err := loaded.DeserializeProto(serialized); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	plain := &Index{private: make([]index, len(loaded.private))}
+	for curr, p := range loaded.private {
+		if len(p.postings) != len(p.Postings) {
+			t.Fatalf("expected the postings decoded once loaded, got %d of %d", len(p.postings), len(p.Postings))
+		}
+		p.Postings, p.postings = nil, nil
+		plain.private[curr] = p
+	}
+	keys := func(lookup func(yield func(string) bool)) (keys []string) {
+		for pk := range lookup {
+			keys = append(keys, pk)
+		}
+		sort.Strings(keys)
+		return
+	}
+	for _, word := range []string{"common", "frequent", "word042"} {
+		expected := fmt.Sprint(keys(plain.Lookup(word, true, true)))
+		if got := fmt.Sprint(keys(loaded.Lookup(word, true, true))); got != expected {
+			t.Errorf("expected %s for %q, got %s", expected, word, got)
+		}
+	}
+	q, _ := ParseQuery("+frequent +common -word003")
+	expected := keys(plain.LookupQuery(q, true))
+	if got := keys(loaded.LookupQuery(q, true)); len(got) == 0 || fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+// TestRoaringPostingsOutOfRange tests that a loaded posting holding rows the shard lacks is rejected
+func TestRoaringPostingsOutOfRange(t *testing.T) {
+	data := make(map[string][]string)
+	for n := 0; n < 20; n++ {
+		data[fmt.Sprintf("doc:%02d", n)] = []string{"common"}
+	}
+	opts := NewDefaultOpts()
+	opts.RoaringPostings = 0.5
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	b := new(bitmap)
+	b.add(uint32(idx.private[0].Rows) + 1)
+	for word := range idx.private[0].Postings {
+		idx.private[0].Postings[word] = b.marshal()
+	}
+	serialized, err := idx.SerializeProto()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var loaded Index
+	if err := loaded.DeserializeProto(serialized); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("expected ErrCorrupted, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package fulltext
+
+import "fmt"
+import "sort"
+import "strings"
+import "unicode"
+
+var ErrMalformedQuery = fmt.Errorf("malformed_query")
+
+// Query is a parsed search string, see ParseQuery
+type Query struct {
+	// Should terms match rows when the query has no required terms or phrases
+	Should []string
+	// Must terms are required, MustNot terms exclude rows
+	Must    []string
+	MustNot []string
+	// Phrases are required word sequences, ExcludedPhrases exclude rows
+	Phrases         [][]string
+	ExcludedPhrases [][]string
+}
+
+// ParseQuery parses a user typed search string such as `golang +backend -frontend "full text"`.
+// Words prefixed with + are required and with - excluded, quoted phrases are required unless prefixed with -.
+func ParseQuery(s string) (*Query, error) {
+	q := new(Query)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeftFunc(s, unicode.IsSpace) {
+		var sign byte
+		if s[0] == '+' || s[0] == '-' {
+			sign, s = s[0], s[1:]
+		}
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return nil, ErrMalformedQuery
+			}
+			phrase := strings.Fields(s[1 : end+1])
+			s = s[end+2:]
+			if len(phrase) == 0 {
+				continue
+			}
+			if sign == '-' {
+				q.ExcludedPhrases = append(q.ExcludedPhrases, phrase)
+			} else {
+				q.Phrases = append(q.Phrases, phrase)
+			}
+			continue
+		}
+		end := strings.IndexFunc(s, unicode.IsSpace)
+		if end < 0 {
+			end = len(s)
+		}
+		word := s[:end]
+		s = s[end:]
+		if word == "" {
+			continue
+		}
+		switch sign {
+		case '+':
+			q.Must = append(q.Must, word)
+		case '-':
+			q.MustNot = append(q.MustNot, word)
+		default:
+			q.Should = append(q.Should, word)
+		}
+	}
+	return q, nil
+}
+
+// row identifies a candidate row of a shard
+type row struct {
+	shard int
+	pos   uint64
+}
+
+// scoredRow is a row with the fraction of the query shingles it matched
+type scoredRow struct {
+	row
+	score float64
+}
+
+// LookupQuery iterates the primary keys of rows matching the query, in shard and row order.
+// Phrase words must occur next to each other in rows with recorded NewOpts.Tokens, elsewhere they are only required.
+// The matching rows are combined as roaring bitmaps per shard.
+func (i *Index) LookupQuery(q *Query, exact bool) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		var rows rowSet
+		intersect := func(matches rowSet) {
+			if rows == nil {
+				rows = matches
+				return
+			}
+			rows = rows.and(matches)
+		}
+		for _, word := range q.Must {
+			intersect(i.rows(word, exact))
+		}
+		for _, phrase := range q.Phrases {
+			intersect(i.phraseRows(phrase, exact))
+		}
+		if rows == nil {
+			rows = make(rowSet)
+			for _, word := range q.Should {
+				rows = rows.or(i.rows(word, exact))
+			}
+		}
+		for _, word := range q.MustNot {
+			rows = rows.andNot(i.rows(word, exact))
+		}
+		for _, phrase := range q.ExcludedPhrases {
+			rows = rows.andNot(i.phraseRows(phrase, exact))
+		}
+		rows.each(func(r row) bool {
+			return yield(i.private[r.shard].key(r.pos))
+		})
+	}
+}
+
+// rowSet holds rows as a roaring bitmap of positions per shard
+type rowSet map[int]*bitmap
+
+// add adds r to the set
+func (s rowSet) add(r row) {
+	if s[r.shard] == nil {
+		s[r.shard] = new(bitmap)
+	}
+	s[r.shard].add(uint32(r.pos))
+}
+
+// and returns the rows in both s and o
+func (s rowSet) and(o rowSet) rowSet {
+	out := make(rowSet)
+	for shard, b := range s {
+		if other := o[shard]; other != nil {
+			out[shard] = b.and(other)
+		}
+	}
+	return out
+}
+
+// or returns the rows in s or o
+func (s rowSet) or(o rowSet) rowSet {
+	out := make(rowSet)
+	for shard, b := range s {
+		out[shard] = b
+	}
+	for shard, b := range o {
+		if out[shard] != nil {
+			out[shard] = out[shard].or(b)
+		} else {
+			out[shard] = b
+		}
+	}
+	return out
+}
+
+// andNot returns the rows in s but not in o
+func (s rowSet) andNot(o rowSet) rowSet {
+	out := make(rowSet)
+	for shard, b := range s {
+		if other := o[shard]; other != nil {
+			out[shard] = b.andNot(other)
+		} else {
+			out[shard] = b
+		}
+	}
+	return out
+}
+
+// each calls fn with every row in shard and row order until fn returns false
+func (s rowSet) each(fn func(r row) bool) {
+	shards := make([]int, 0, len(s))
+	for shard := range s {
+		shards = append(shards, shard)
+	}
+	sort.Ints(shards)
+	for _, shard := range shards {
+		if !s[shard].each(func(pos uint32) bool { return fn(row{shard, uint64(pos)}) }) {
+			return
+		}
+	}
+}
+
+// rows collects the deduplicated rows matching word
+func (i *Index) rows(word string, exact bool) rowSet {
+	matches := make(rowSet)
+	i.lookup(word, exact, true, func(shard int, pos uint64) bool {
+		matches.add(row{shard, pos})
+		return true
+	})
+	return matches
+}
+
+// phraseRows collects the rows matching every word of phrase, in order and adjacent where token positions were recorded
+func (i *Index) phraseRows(phrase []string, exact bool) rowSet {
+	matches := i.rows(phrase[0], exact)
+	for n := 1; n < len(phrase); n++ {
+		matches = matches.and(i.rows(phrase[n], exact))
+	}
+	if len(phrase) < 2 {
+		return matches
+	}
+	ordered := make(rowSet)
+	matches.each(func(r row) bool {
+		p := &i.private[r.shard]
+		if len(p.Tokens) == 0 {
+			ordered.add(r)
+			return true
+		}
+		words := make([]string, len(phrase))
+		for n, word := range phrase {
+			words[n] = p.query(word)
+		}
+		if p.phrase(r.pos, words) {
+			ordered.add(r)
+		}
+		return true
+	})
+	return ordered
+}
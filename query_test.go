@@ -0,0 +1,48 @@
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// TestParseQuery tests parsing required, excluded and optional words and phrases
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery(`golang +backend -frontend "full text" -"slow search"`)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got := fmt.Sprint(q.Should, q.Must, q.MustNot, q.Phrases, q.ExcludedPhrases)
+	if want := "[golang] [backend] [frontend] [[full text]] [[slow search]]"; got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+	if _, err := ParseQuery(`"unterminated`); !errors.Is(err, ErrMalformedQuery) {
+		t.Fatalf("expected ErrMalformedQuery, got %v", err)
+	}
+}
+
+// TestLookupQuery tests running a parsed query against the index
+func TestLookupQuery(t *testing.T) {
+	idx := newTestIndex(t)
+	for query, want := range map[string]string{
+		"backend":          "[doc:1 doc:2]",
+		"+backend -rust":   "[doc:1]",
+		"golang python":    "[doc:1 doc:3]",
+		"python +backend":  "[doc:1 doc:2]",
+		`"golang backend"`: "[doc:1]",
+	} {
+		q, err := ParseQuery(query)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var keys []string
+		for pk := range idx.LookupQuery(q, true) {
+			keys = append(keys, pk)
+		}
+		sort.Strings(keys)
+		if fmt.Sprint(keys) != want {
+			t.Fatalf("expected %s for %q, got %v", want, query, keys)
+		}
+	}
+}
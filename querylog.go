@@ -0,0 +1,46 @@
+package fulltext
+
+import "log/slog"
+import "time"
+
+// QueryRecord describes a finished lookup, see WithQueryLog
+type QueryRecord struct {
+	Word string
+	// Exact, Dedup and Coverage are the mode of the lookup, Coverage is the minimum coverage of LookupCoverage
+	Exact    bool
+	Dedup    bool
+	Coverage float64
+	// Shards is the number of shards probed, Hits the number of rows yielded
+	Shards int
+	Hits   int
+	// Latency spans the whole iteration, including the time the caller spent between the yielded rows
+	Latency time.Duration
+	// Cached reports a result set served by WithCache, which probes no shards
+	Cached bool
+}
+
+// QueryLog receives the records of lookups. LogQuery is called concurrently, so implementations must be thread safe.
+type QueryLog interface {
+	LogQuery(r QueryRecord)
+}
+
+// QueryLogFunc adapts a function to QueryLog
+type QueryLogFunc func(r QueryRecord)
+
+func (f QueryLogFunc) LogQuery(r QueryRecord) { f(r) }
+
+// WithQueryLog records every lookup taking at least slow in log, such as the subword lookups burning CPU.
+// 0 records every lookup, nil disables the query log. Result sets served by WithCache are recorded with Cached set.
+// WithQueryLog is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) WithQueryLog(log QueryLog, slow time.Duration) *Index {
+	i.queryLog, i.slowQuery = log, slow
+	return i
+}
+
+// SlogQueryLog is a QueryLog writing every record to l at warn level
+func SlogQueryLog(l *slog.Logger) QueryLog {
+	return QueryLogFunc(func(r QueryRecord) {
+		l.Warn("fulltext: query", "word", r.Word, "exact", r.Exact, "dedup", r.Dedup, "coverage", r.Coverage,
+			"shards_probed", r.Shards, "hits", r.Hits, "latency", r.Latency, "cached", r.Cached)
+	})
+}
@@ -0,0 +1,45 @@
+package fulltext
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestQueryLog tests that lookups are recorded with their mode, probed shards and hits, above the slow threshold only
+func TestQueryLog(t *testing.T) {
+	idx := newTestIndex(t)
+	var records []QueryRecord
+	idx.WithQueryLog(QueryLogFunc(func(r QueryRecord) { records = append(records, r) }), 0)
+	for range idx.Lookup("backend", true, true) {
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	if r := records[0]; r.Word != "backend" || !r.Exact || !r.Dedup || r.Shards == 0 || r.Hits != 2 || r.Latency <= 0 {
+		t.Fatalf("unexpected record %+v", r)
+	}
+	idx.WithQueryLog(QueryLogFunc(func(r QueryRecord) { records = append(records, r) }), time.Hour)
+	for range idx.Lookup("backend", false, false) {
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected no record of a fast lookup, got %d", len(records)-1)
+	}
+	idx.WithCache(4).WithQueryLog(QueryLogFunc(func(r QueryRecord) { records = append(records, r) }), 0)
+	for range 2 {
+		for range idx.Lookup("backend", true, true) {
+		}
+	}
+	if r := records[len(records)-1]; len(records) != 3 || !r.Cached || r.Hits != 2 || r.Shards != 0 {
+		t.Fatalf("expected a record of the cached result set, got %d records ending with %+v", len(records)-1, r)
+	}
+	var buf bytes.Buffer
+	idx.WithQueryLog(SlogQueryLog(slog.New(slog.NewTextHandler(&buf, nil))), 0)
+	for range idx.Lookup("backend", false, true) {
+	}
+	if !strings.Contains(buf.String(), "fulltext: query") || !strings.Contains(buf.String(), "hits=2") {
+		t.Fatalf("expected the query in log, got\n%s", buf.String())
+	}
+}
@@ -0,0 +1,246 @@
+package fulltext
+
+import "fmt"
+import "maps"
+import "sync"
+
+var ErrRebuildRunning = fmt.Errorf("rebuild_running")
+
+// RowSource iterates every row of the backing store with its words
+type RowSource = func(yield func(primaryKey string, words BagOfWords) bool)
+
+// Rebuilder serves lookups while rebuilding the index in the background, the reindex without downtime workflow.
+// Writes arriving meanwhile go to an overlay, which is searched along with the index and survives the swap.
+// Rebuilder is thread safe.
+type Rebuilder struct {
+	opts *NewOpts
+
+	// building serializes building overlay segments and swapping in rebuilt indexes, taken before mut
+	building sync.Mutex
+
+	mut  sync.Mutex
+	base *Index
+	// segments hold the overlay built so far, never modified once built, so lookups iterate them without holding
+	// the lock. Later segments take precedence over earlier ones.
+	segments []*segment
+	// pending collects the writes not built into a segment yet, built by the next lookup
+	pending map[string]BagOfWords
+	// err is the error of the last failed segment build, nil once a build succeeds
+	err error
+	// since collects the keys written after the running rebuild started, nil when no rebuild runs
+	since map[string]struct{}
+}
+
+// segment is a part of the overlay of a Rebuilder, its rows and their index
+type segment struct {
+	rows  map[string]BagOfWords
+	index *Index
+}
+
+// NewRebuilder serves lookups from base, which can be nil, rebuilding with opts. Opts can be nil.
+func NewRebuilder(opts *NewOpts, base *Index) *Rebuilder {
+	if base == nil {
+		base = new(Index)
+	}
+	return &Rebuilder{opts: opts, base: base, pending: make(map[string]BagOfWords)}
+}
+
+// Index returns the index currently served, without the overlay
+func (r *Rebuilder) Index() *Index {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.base
+}
+
+// Err returns the error of the last failed build of the overlay, such as keys of another size than the index.
+// Lookups skip the writes that failed to build and retry them, Err returns nil once they build.
+func (r *Rebuilder) Err() error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.err
+}
+
+// Add records a write of the row with primaryKey, replacing its words in lookups until the next rebuild picks it up.
+// A key written again before the rebuild picking it up is resolved by NewOpts.Duplicates, under DuplicateError Add
+// returns a *DuplicateKeyError and the first write stays. Writes are indexed by the next lookup, in segments merged
+// as they grow, so each write is indexed a logarithmic number of times.
+func (r *Rebuilder) Add(primaryKey string, words BagOfWords) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	add, err := r.opts.resolve(primaryKey, r.written(primaryKey))
+	if err != nil {
+		return err
+	}
+	if add {
+		r.pending[primaryKey] = words
+	}
+	if r.since != nil {
+		r.since[primaryKey] = struct{}{}
+	}
+	return nil
+}
+
+// written reports whether the overlay has primaryKey
+func (r *Rebuilder) written(primaryKey string) bool {
+	_, ok := r.latest(primaryKey)
+	return ok
+}
+
+// latest returns the words of the last write of primaryKey to the overlay
+func (r *Rebuilder) latest(primaryKey string) (BagOfWords, bool) {
+	if words, ok := r.pending[primaryKey]; ok {
+		return words, true
+	}
+	for n := len(r.segments) - 1; n >= 0; n-- {
+		if words, ok := r.segments[n].rows[primaryKey]; ok {
+			return words, true
+		}
+	}
+	return nil, false
+}
+
+// Rebuild builds a new index from source in the background and atomically swaps it in when done.
+// Writes that arrived during the rebuild stay in the overlay, as source may have missed them.
+// The returned channel receives the build error, or nil, once. ErrRebuildRunning is sent when a rebuild already runs.
+// Keys yielded twice by source are resolved by NewOpts.Duplicates, under DuplicateError the rebuild fails.
+func (r *Rebuilder) Rebuild(source RowSource) <-chan error {
+	done := make(chan error, 1)
+	r.mut.Lock()
+	if r.since != nil {
+		r.mut.Unlock()
+		done <- ErrRebuildRunning
+		return done
+	}
+	r.since = make(map[string]struct{})
+	r.mut.Unlock()
+	go func() {
+		rows := make(map[string]BagOfWords)
+		var err error
+		source(func(pk string, words BagOfWords) bool {
+			err = r.opts.addRow(rows, pk, words)
+			return err == nil
+		})
+		var i *Index
+		if err == nil {
+			i, err = New(r.opts, rows, nil)
+		}
+		r.building.Lock()
+		r.mut.Lock()
+		if err == nil {
+			// the overlay segments are small and short lived, only the served index gets a cache and pinned workers
+			if r.base.cache != nil {
+				i.WithCache(r.base.cache.maxEntries)
+			}
+			if r.base.pinning != nil {
+				i.WithPinning(r.base.pinning.opts)
+			}
+			r.base = i.configure(r.base)
+			pending := make(map[string]BagOfWords, len(r.since))
+			for pk := range r.since {
+				if words, ok := r.latest(pk); ok {
+					pending[pk] = words
+				}
+			}
+			r.segments, r.pending = nil, pending
+		}
+		r.since = nil
+		r.mut.Unlock()
+		r.building.Unlock()
+		done <- err
+	}()
+	return done
+}
+
+// Lookup iterates like Index.Lookup over the index and the overlay, the overlay taking precedence for keys written to it
+func (r *Rebuilder) Lookup(word string, exact, dedup bool) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		base, segments := r.snapshot()
+		// shadowed reports whether a segment from the n-th on has pk
+		shadowed := func(pk string, n int) bool {
+			for _, s := range segments[n:] {
+				if _, ok := s.rows[pk]; ok {
+					return true
+				}
+			}
+			return false
+		}
+		for pk := range base.Lookup(word, exact, dedup) {
+			if !shadowed(pk, 0) && !yield(pk) {
+				return
+			}
+		}
+		for n, s := range segments {
+			for pk := range s.index.Lookup(word, exact, dedup) {
+				if !shadowed(pk, n+1) && !yield(pk) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// snapshot returns the served index and the overlay segments, building the pending writes into a segment first.
+// The segment is built without holding mut, so writes are not blocked meanwhile. A failed build is recorded in err
+// and logged, and its writes stay pending.
+func (r *Rebuilder) snapshot() (*Index, []*segment) {
+	r.building.Lock()
+	defer r.building.Unlock()
+	r.mut.Lock()
+	base, segments, pending := r.base, r.segments, r.pending
+	if len(pending) > 0 {
+		r.pending = make(map[string]BagOfWords)
+	}
+	r.mut.Unlock()
+	if len(pending) == 0 {
+		return base, segments
+	}
+	merged, err := r.merge(base, segments, pending)
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if err != nil {
+		for pk, words := range pending {
+			if _, ok := r.pending[pk]; !ok {
+				r.pending[pk] = words
+			}
+		}
+		r.err = err
+		if r.opts != nil && r.opts.Logger != nil {
+			r.opts.Logger.Error("fulltext: overlay build failed", "rows", len(pending), "err", err)
+		}
+		return base, segments
+	}
+	r.segments, r.err = merged, nil
+	return base, merged
+}
+
+// merge returns segments with a segment of rows appended, configured like base, merged with the last segments while
+// they hold no more rows than it, so n writes are kept in O(log n) segments
+func (r *Rebuilder) merge(base *Index, segments []*segment, rows map[string]BagOfWords) ([]*segment, error) {
+	merged := make(map[string]BagOfWords, len(rows))
+	for pk, words := range rows {
+		merged[pk] = words
+	}
+	for len(segments) > 0 && len(segments[len(segments)-1].rows) <= len(merged) {
+		for pk, words := range segments[len(segments)-1].rows {
+			if _, ok := merged[pk]; !ok {
+				merged[pk] = words
+			}
+		}
+		segments = segments[:len(segments)-1]
+	}
+	index, err := New(r.opts, merged, nil)
+	if err != nil {
+		return nil, err
+	}
+	return append(segments[:len(segments):len(segments)], &segment{rows: merged, index: index.configure(base)}), nil
+}
+
+// configure gives i the lookup configuration of from, its visibility, query middleware, query log, limits, metrics,
+// logger, tracer and sidecars, so an index built to replace or overlay from serves lookups alike
+func (i *Index) configure(from *Index) *Index {
+	i.visible, i.middleware = from.visible, from.middleware
+	i.queryLog, i.slowQuery = from.queryLog, from.slowQuery
+	i.limits, i.metrics, i.logger, i.tracer = from.limits, from.metrics, from.logger, from.tracer
+	i.numeric, i.geo = maps.Clone(from.numeric), maps.Clone(from.geo)
+	return i
+}
@@ -0,0 +1,186 @@
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// TestRebuilder tests that writes during a rebuild stay visible after the swap
+func TestRebuilder(t *testing.T) {
+	store := map[string]BagOfWords{
+		"doc:1": {"golang": {}},
+		"doc:2": {"rust": {}},
+	}
+	r := NewRebuilder(nil, nil)
+	lookup := func(word string) (keys []string) {
+		for pk := range r.Lookup(word, true, true) {
+			keys = append(keys, pk)
+		}
+		sort.Strings(keys)
+		return
+	}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	source := func(yield func(string, BagOfWords) bool) {
+		close(started)
+		<-release
+		for pk, words := range store {
+			if !yield(pk, words) {
+				return
+			}
+		}
+	}
+	done := r.Rebuild(source)
+	<-started
+	if err := <-r.Rebuild(source); !errors.Is(err, ErrRebuildRunning) {
+		t.Fatalf("expected ErrRebuildRunning, got %v", err)
+	}
+	r.Add("doc:3", BagOfWords{"golang": {}})
+	if keys := lookup("golang"); fmt.Sprint(keys) != "[doc:3]" {
+		t.Fatalf("expected the overlay during the rebuild, got %v", keys)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keys := lookup("golang"); fmt.Sprint(keys) != "[doc:1 doc:3]" {
+		t.Fatalf("expected [doc:1 doc:3] after the swap, got %v", keys)
+	}
+
+	r.Add("doc:1", BagOfWords{"python": {}})
+	if keys := lookup("golang"); fmt.Sprint(keys) != "[doc:3]" {
+		t.Fatalf("expected the overlay to replace doc:1, got %v", keys)
+	}
+}
+
+// TestRebuilderDuplicates tests the duplicate policies of writes and of the rebuild source
+func TestRebuilderDuplicates(t *testing.T) {
+	source := func(yield func(string, BagOfWords) bool) {
+		_ = yield("doc:1", BagOfWords{"golang": {}}) && yield("doc:1", BagOfWords{"rust": {}})
+	}
+	for _, tc := range []struct {
+		policy DuplicatePolicy
+		want   string
+	}{{DuplicateOverwrite, "[doc:1]"}, {DuplicateSkip, "[]"}, {DuplicateError, "[]"}} {
+		opts := NewDefaultOpts()
+		opts.Duplicates = tc.policy
+		r := NewRebuilder(opts, nil)
+		err := <-r.Rebuild(source)
+		var dup *DuplicateKeyError
+		if tc.policy == DuplicateError {
+			if !errors.Is(err, ErrDuplicateKey) || !errors.As(err, &dup) || dup.PrimaryKey != "doc:1" {
+				t.Fatalf("expected a DuplicateKeyError of doc:1, got %v", err)
+			}
+		} else if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var keys []string
+		for pk := range r.Lookup("rust", true, true) {
+			keys = append(keys, pk)
+		}
+		if fmt.Sprint(keys) != tc.want {
+			t.Fatalf("expected %s with policy %d, got %v", tc.want, tc.policy, keys)
+		}
+		if err := r.Add("doc:2", BagOfWords{"python": {}}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		err = r.Add("doc:2", BagOfWords{"scripting": {}})
+		if (tc.policy == DuplicateError) != errors.Is(err, ErrDuplicateKey) {
+			t.Fatalf("unexpected error %v with policy %d", err, tc.policy)
+		}
+	}
+}
+
+// TestRebuilderSegments tests that writes are indexed in few segments, later writes shadowing earlier ones, and that
+// a failed segment build keeps the index served and is reported
+func TestRebuilderSegments(t *testing.T) {
+	base, err := New(nil, map[string][]string{"doc:0": {"golang"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	opts := NewDefaultOpts()
+	opts.KeyPadding = &KeyPadding{Byte: ' '}
+	r := NewRebuilder(opts, base)
+	lookup := func(word string) (keys []string) {
+		for pk := range r.Lookup(word, true, true) {
+			keys = append(keys, pk)
+		}
+		sort.Strings(keys)
+		return
+	}
+	for n := 1; n <= 100; n++ {
+		if err := r.Add(fmt.Sprintf("doc:%d", n%10), BagOfWords{fmt.Sprintf("word%d", n): {}, "golang": {}}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		lookup("golang")
+	}
+	if len(r.segments) > 8 {
+		t.Fatalf("expected merged segments, got %d", len(r.segments))
+	}
+	if keys := lookup("golang"); len(keys) != 10 {
+		t.Fatalf("expected 10 keys, got %v", keys)
+	}
+	if keys := lookup("word95"); !slices.Contains(keys, "doc:5") {
+		t.Fatalf("expected doc:5, got %v", keys)
+	}
+	if keys := lookup("word85"); len(keys) != 0 {
+		t.Fatalf("expected the later write to shadow word85, got %v", keys)
+	}
+
+	if err := r.Add("doc:x ", BagOfWords{"rust": {}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keys := lookup("golang"); len(keys) != 10 {
+		t.Fatalf("expected the index served despite the failed segment, got %v", keys)
+	}
+	if err := r.Err(); !errors.Is(err, ErrAmbiguousPadding) {
+		t.Fatalf("expected ErrAmbiguousPadding, got %v", err)
+	}
+}
+
+// TestRebuilderConfig tests that the rebuilt index and the overlay serve lookups configured like the replaced index
+func TestRebuilderConfig(t *testing.T) {
+	base, err := New(nil, map[string][]string{"doc:0": {"golang"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	limits := &Limits{MaxShards: 100}
+	base.WithVisibility(func(pk string) bool { return pk != "doc:2" }).
+		WithQueryMiddleware(ExpandSynonyms(map[string][]string{"go": {"golang"}})).
+		WithCache(16).WithLimits(limits).AttachNumeric("price", new(NumericField))
+	r := NewRebuilder(nil, base)
+	lookup := func(word string) (keys []string) {
+		for pk := range r.Lookup(word, true, true) {
+			keys = append(keys, pk)
+		}
+		sort.Strings(keys)
+		return
+	}
+	store := map[string]BagOfWords{"doc:1": {"golang": {}}, "doc:2": {"golang": {}}, "doc:3": {"rust": {}}}
+	err = <-r.Rebuild(func(yield func(string, BagOfWords) bool) {
+		for pk, words := range store {
+			if !yield(pk, words) {
+				return
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := r.Add("doc:4", BagOfWords{"golang": {}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := r.Add("doc:2", BagOfWords{"golang": {}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keys := lookup("go"); fmt.Sprint(keys) != "[doc:1 doc:4]" {
+		t.Fatalf("expected the synonym found and doc:2 hidden, got %v", keys)
+	}
+	i := r.Index()
+	if i == base || i.cache == nil || i.limits != limits || i.numeric["price"] == nil {
+		t.Fatalf("expected the cache, limits and sidecars carried over")
+	}
+}
@@ -0,0 +1,373 @@
+package fulltext
+
+import "encoding/binary"
+import "math/bits"
+import "sort"
+
+// arrayMax is the cardinality above which a roaring container switches from a sorted array to a bit set
+const arrayMax = 4096
+
+// bitmap is a roaring bitmap of row positions. Positions sharing their high 16 bits live in one container,
+// holding their low 16 bits as a sorted array while sparse and as a 65536 bit set once dense.
+type bitmap struct {
+	keys       []uint16
+	containers []*container
+}
+
+// container holds either array or bits, never both
+type container struct {
+	array []uint16
+	bits  []uint64
+}
+
+// container returns the container of key, creating it when create is set
+func (b *bitmap) container(key uint16, create bool) *container {
+	at := sort.Search(len(b.keys), func(k int) bool { return b.keys[k] >= key })
+	if at < len(b.keys) && b.keys[at] == key {
+		return b.containers[at]
+	}
+	if !create {
+		return nil
+	}
+	b.keys = append(b.keys, 0)
+	copy(b.keys[at+1:], b.keys[at:])
+	b.keys[at] = key
+	b.containers = append(b.containers, nil)
+	copy(b.containers[at+1:], b.containers[at:])
+	b.containers[at] = new(container)
+	return b.containers[at]
+}
+
+// add adds pos to the bitmap
+func (b *bitmap) add(pos uint32) {
+	c := b.container(uint16(pos>>16), true)
+	low := uint16(pos)
+	if c.bits != nil {
+		c.bits[low>>6] |= 1 << (low & 63)
+		return
+	}
+	at := sort.Search(len(c.array), func(k int) bool { return c.array[k] >= low })
+	if at < len(c.array) && c.array[at] == low {
+		return
+	}
+	c.array = append(c.array, 0)
+	copy(c.array[at+1:], c.array[at:])
+	c.array[at] = low
+	if len(c.array) > arrayMax {
+		c.densify()
+	}
+}
+
+// contains reports whether pos is in the bitmap
+func (b *bitmap) contains(pos uint32) bool {
+	c := b.container(uint16(pos>>16), false)
+	return c != nil && c.contains(uint16(pos))
+}
+
+// cardinality returns the number of positions in the bitmap
+func (b *bitmap) cardinality() (n int) {
+	for _, c := range b.containers {
+		n += c.cardinality()
+	}
+	return
+}
+
+// each calls fn with every position in ascending order until fn returns false
+func (b *bitmap) each(fn func(pos uint32) bool) bool {
+	for k, c := range b.containers {
+		high := uint32(b.keys[k]) << 16
+		if !c.each(func(low uint16) bool { return fn(high | uint32(low)) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// and returns the positions in both b and o
+func (b *bitmap) and(o *bitmap) *bitmap {
+	out := new(bitmap)
+	for k, key := range b.keys {
+		if c := o.container(key, false); c != nil {
+			out.put(key, b.containers[k].and(c))
+		}
+	}
+	return out
+}
+
+// or returns the positions in b or o
+func (b *bitmap) or(o *bitmap) *bitmap {
+	out := new(bitmap)
+	for k, key := range b.keys {
+		if c := o.container(key, false); c != nil {
+			out.put(key, b.containers[k].or(c))
+		} else {
+			out.put(key, b.containers[k].clone())
+		}
+	}
+	for k, key := range o.keys {
+		if b.container(key, false) == nil {
+			out.put(key, o.containers[k].clone())
+		}
+	}
+	sortContainers(out)
+	return out
+}
+
+// andNot returns the positions in b but not in o
+func (b *bitmap) andNot(o *bitmap) *bitmap {
+	out := new(bitmap)
+	for k, key := range b.keys {
+		if c := o.container(key, false); c != nil {
+			out.put(key, b.containers[k].andNot(c))
+		} else {
+			out.put(key, b.containers[k].clone())
+		}
+	}
+	return out
+}
+
+// put appends the container c of key unless it is empty, keys must be put in ascending order or sorted afterwards
+func (b *bitmap) put(key uint16, c *container) {
+	if c.cardinality() == 0 {
+		return
+	}
+	b.keys = append(b.keys, key)
+	b.containers = append(b.containers, c)
+}
+
+// sortContainers restores the key order after containers were put out of order
+func sortContainers(b *bitmap) {
+	order := make([]int, len(b.keys))
+	for k := range order {
+		order[k] = k
+	}
+	sort.Slice(order, func(x, y int) bool { return b.keys[order[x]] < b.keys[order[y]] })
+	keys := make([]uint16, len(order))
+	containers := make([]*container, len(order))
+	for k, from := range order {
+		keys[k], containers[k] = b.keys[from], b.containers[from]
+	}
+	b.keys, b.containers = keys, containers
+}
+
+func (c *container) contains(low uint16) bool {
+	if c.bits != nil {
+		return c.bits[low>>6]&(1<<(low&63)) != 0
+	}
+	at := sort.Search(len(c.array), func(k int) bool { return c.array[k] >= low })
+	return at < len(c.array) && c.array[at] == low
+}
+
+func (c *container) cardinality() (n int) {
+	if c.bits == nil {
+		return len(c.array)
+	}
+	for _, word := range c.bits {
+		n += bits.OnesCount64(word)
+	}
+	return
+}
+
+func (c *container) each(fn func(low uint16) bool) bool {
+	if c.bits == nil {
+		for _, low := range c.array {
+			if !fn(low) {
+				return false
+			}
+		}
+		return true
+	}
+	for k, word := range c.bits {
+		for word != 0 {
+			if !fn(uint16(k<<6 | bits.TrailingZeros64(word))) {
+				return false
+			}
+			word &= word - 1
+		}
+	}
+	return true
+}
+
+func (c *container) clone() *container {
+	return &container{array: append([]uint16(nil), c.array...), bits: append([]uint64(nil), c.bits...)}
+}
+
+// densify turns an array container into a bit set
+func (c *container) densify() {
+	c.bits = make([]uint64, 1<<16/64)
+	for _, low := range c.array {
+		c.bits[low>>6] |= 1 << (low & 63)
+	}
+	c.array = nil
+}
+
+// shrink turns a bit set container holding few positions back into an array
+func (c *container) shrink() *container {
+	if c.bits == nil || c.cardinality() > arrayMax {
+		return c
+	}
+	var array []uint16
+	c.each(func(low uint16) bool {
+		array = append(array, low)
+		return true
+	})
+	return &container{array: array}
+}
+
+func (c *container) and(o *container) *container {
+	if c.bits != nil && o.bits != nil {
+		out := &container{bits: make([]uint64, len(c.bits))}
+		for k := range c.bits {
+			out.bits[k] = c.bits[k] & o.bits[k]
+		}
+		return out.shrink()
+	}
+	if c.bits != nil {
+		c, o = o, c
+	}
+	out := new(container)
+	for _, low := range c.array {
+		if o.contains(low) {
+			out.array = append(out.array, low)
+		}
+	}
+	return out
+}
+
+func (c *container) or(o *container) *container {
+	if c.bits == nil && o.bits == nil {
+		out := &container{array: make([]uint16, 0, len(c.array)+len(o.array))}
+		x, y := 0, 0
+		for x < len(c.array) || y < len(o.array) {
+			switch {
+			case y == len(o.array) || (x < len(c.array) && c.array[x] < o.array[y]):
+				out.array = append(out.array, c.array[x])
+				x++
+			case x == len(c.array) || o.array[y] < c.array[x]:
+				out.array = append(out.array, o.array[y])
+				y++
+			default:
+				out.array = append(out.array, c.array[x])
+				x, y = x+1, y+1
+			}
+		}
+		if len(out.array) > arrayMax {
+			out.densify()
+		}
+		return out
+	}
+	if c.bits == nil {
+		c, o = o, c
+	}
+	out := c.clone()
+	if o.bits != nil {
+		for k := range out.bits {
+			out.bits[k] |= o.bits[k]
+		}
+		return out
+	}
+	for _, low := range o.array {
+		out.bits[low>>6] |= 1 << (low & 63)
+	}
+	return out
+}
+
+func (c *container) andNot(o *container) *container {
+	if c.bits == nil {
+		out := new(container)
+		for _, low := range c.array {
+			if !o.contains(low) {
+				out.array = append(out.array, low)
+			}
+		}
+		return out
+	}
+	out := c.clone()
+	if o.bits != nil {
+		for k := range out.bits {
+			out.bits[k] &^= o.bits[k]
+		}
+	} else {
+		for _, low := range o.array {
+			out.bits[low>>6] &^= 1 << (low & 63)
+		}
+	}
+	return out.shrink()
+}
+
+// marshal encodes the bitmap as the number of containers followed by every container: its key, a 0 byte and
+// the uvarint array length followed by the little endian array, or a 1 byte followed by the little endian bit set
+func (b *bitmap) marshal() []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(b.keys)))
+	for k, key := range b.keys {
+		buf = binary.LittleEndian.AppendUint16(buf, key)
+		c := b.containers[k]
+		if c.bits == nil {
+			buf = append(buf, 0)
+			buf = binary.AppendUvarint(buf, uint64(len(c.array)))
+			for _, low := range c.array {
+				buf = binary.LittleEndian.AppendUint16(buf, low)
+			}
+			continue
+		}
+		buf = append(buf, 1)
+		for _, word := range c.bits {
+			buf = binary.LittleEndian.AppendUint64(buf, word)
+		}
+	}
+	return buf
+}
+
+// unmarshalBitmap decodes a bitmap encoded by marshal, returning ErrCorrupted for malformed data
+func unmarshalBitmap(data []byte) (*bitmap, error) {
+	n, read := binary.Uvarint(data)
+	if read <= 0 || n > 1<<16 {
+		return nil, ErrCorrupted
+	}
+	data = data[read:]
+	b := &bitmap{keys: make([]uint16, 0, n), containers: make([]*container, 0, n)}
+	for ; n > 0; n-- {
+		if len(data) < 3 {
+			return nil, ErrCorrupted
+		}
+		key, kind := binary.LittleEndian.Uint16(data), data[2]
+		data = data[3:]
+		if len(b.keys) > 0 && key <= b.keys[len(b.keys)-1] {
+			return nil, ErrCorrupted
+		}
+		c := new(container)
+		switch kind {
+		case 0:
+			length, read := binary.Uvarint(data)
+			if read <= 0 || length > arrayMax || uint64(len(data)-read) < 2*length {
+				return nil, ErrCorrupted
+			}
+			data = data[read:]
+			c.array = make([]uint16, length)
+			for k := range c.array {
+				c.array[k] = binary.LittleEndian.Uint16(data[2*k:])
+				if k > 0 && c.array[k] <= c.array[k-1] {
+					return nil, ErrCorrupted
+				}
+			}
+			data = data[2*length:]
+		case 1:
+			if len(data) < 1<<16/8 {
+				return nil, ErrCorrupted
+			}
+			c.bits = make([]uint64, 1<<16/64)
+			for k := range c.bits {
+				c.bits[k] = binary.LittleEndian.Uint64(data[8*k:])
+			}
+			data = data[1<<16/8:]
+		default:
+			return nil, ErrCorrupted
+		}
+		b.keys = append(b.keys, key)
+		b.containers = append(b.containers, c)
+	}
+	if len(data) > 0 {
+		return nil, ErrCorrupted
+	}
+	return b, nil
+}
@@ -0,0 +1,61 @@
+package fulltext
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestBitmap tests the roaring set operations and encoding against maps, across sparse and dense containers
+func TestBitmap(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := func(n int, limit uint32) (*bitmap, map[uint32]bool) {
+		b, m := new(bitmap), make(map[uint32]bool)
+		for ; n > 0; n-- {
+			pos := uint32(rnd.Int63n(int64(limit)))
+			b.add(pos)
+			m[pos] = true
+		}
+		return b, m
+	}
+	check := func(name string, b *bitmap, expected func(pos uint32) bool, limit uint32) {
+		var n int
+		var last int64 = -1
+		b.each(func(pos uint32) bool {
+			if int64(pos) <= last {
+				t.Fatalf("%s: expected ascending positions, got %d after %d", name, pos, last)
+			}
+			last = int64(pos)
+			return true
+		})
+		for pos := uint32(0); pos < limit; pos++ {
+			if b.contains(pos) != expected(pos) {
+				t.Fatalf("%s: expected %v for %d", name, expected(pos), pos)
+			}
+			if expected(pos) {
+				n++
+			}
+		}
+		if b.cardinality() != n {
+			t.Fatalf("%s: expected cardinality %d, got %d", name, n, b.cardinality())
+		}
+		decoded, err := unmarshalBitmap(b.marshal())
+		if err != nil || decoded.cardinality() != n {
+			t.Fatalf("%s: expected round trip of %d positions, got %v", name, n, err)
+		}
+	}
+	const limit = 3 << 16
+	for _, sizes := range [][2]int{{100, 200}, {20000, 300}, {30000, 60000}} {
+		a, am := random(sizes[0], limit)
+		b, bm := random(sizes[1], limit)
+		check("a", a, func(pos uint32) bool { return am[pos] }, limit)
+		check("and", a.and(b), func(pos uint32) bool { return am[pos] && bm[pos] }, limit)
+		check("or", a.or(b), func(pos uint32) bool { return am[pos] || bm[pos] }, limit)
+		check("andNot", a.andNot(b), func(pos uint32) bool { return am[pos] && !bm[pos] }, limit)
+		check("notAnd", b.andNot(a), func(pos uint32) bool { return bm[pos] && !am[pos] }, limit)
+	}
+	for _, malformed := range [][]byte{nil, {1}, {1, 0, 0, 2}, {1, 0, 0, 0, 2, 1, 0, 1, 0}, {1, 0, 0, 1, 0}} {
+		if _, err := unmarshalBitmap(malformed); err != ErrCorrupted {
+			t.Errorf("expected ErrCorrupted for %v, got %v", malformed, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package fulltext
+
+// Search combines a word lookup with sidecar filters, built with Index.Search
+type Search struct {
+	index   *Index
+	word    string
+	exact   bool
+	filters []func(*Index) func(yield func(string) bool)
+}
+
+// Search starts a query for rows containing word, matched exactly unless changed with Exact
+func (i *Index) Search(word string) *Search {
+	return &Search{index: i, word: word, exact: true}
+}
+
+// Exact chooses between exact word (prefix) matching and subword matching, see Lookup
+func (s *Search) Exact(exact bool) *Search {
+	s.exact = exact
+	return s
+}
+
+// Where restricts results to rows whose numeric field, attached with AttachNumeric, lies in r.
+// Fields that were not attached match nothing.
+func (s *Search) Where(field string, r Range) *Search {
+	s.filters = append(s.filters, func(i *Index) func(yield func(string) bool) {
+		if f, ok := i.numeric[field]; ok {
+			return f.Range(r)
+		}
+		return func(yield func(string) bool) {}
+	})
+	return s
+}
+
+// Within restricts results to rows whose location field, attached with AttachGeo, lies in box.
+// Fields that were not attached match nothing.
+func (s *Search) Within(field string, box BoundingBox) *Search {
+	s.filters = append(s.filters, func(i *Index) func(yield func(string) bool) {
+		if g, ok := i.geo[field]; ok {
+			return g.Within(box)
+		}
+		return func(yield func(string) bool) {}
+	})
+	return s
+}
+
+// Keys iterates the primary keys matching the word and every filter, each exactly once, also keys present in several
+// shards, such as shards added by Append. Iterator can (in rare cases) have false positives.
+func (s *Search) Keys() func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		var allowed []map[string]struct{}
+		for _, filter := range s.filters {
+			var set = make(map[string]struct{})
+			for pk := range filter(s.index) {
+				set[pk] = struct{}{}
+			}
+			if len(set) == 0 {
+				return
+			}
+			allowed = append(allowed, set)
+		}
+	next:
+		for pk := range s.index.LookupWith(s.word, &LookupOpts{Exact: s.exact, GlobalDedup: true}) {
+			for _, set := range allowed {
+				if _, ok := set[pk]; !ok {
+					continue next
+				}
+			}
+			if !yield(pk) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestSearchWhereRange tests combining a word lookup with numeric ranges
+func TestSearchWhereRange(t *testing.T) {
+	data := map[string][]string{
+		"sku:1": {"laptop", "golang"},
+		"sku:2": {"laptop", "rust"},
+		"sku:3": {"laptop", "python"},
+		"sku:4": {"desk", "wooden"},
+	}
+	idx, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.AttachNumeric("price", NewNumericField(map[string]float64{
+		"sku:1": 999, "sku:2": 1500, "sku:3": 450, "sku:4": 1200,
+	}))
+	idx.AttachNumeric("stock", NewNumericField(map[string]float64{
+		"sku:1": 0, "sku:2": 7, "sku:3": 3, "sku:4": 1,
+	}))
+
+	results := make(map[string]struct{})
+	for pk := range idx.Search("laptop").Where("price", Between(400, 1000)).Keys() {
+		results[pk] = struct{}{}
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %v", results)
+	}
+
+	results = make(map[string]struct{})
+	for pk := range idx.Search("laptop").Where("price", AtMost(1000)).Where("stock", AtLeast(1)).Keys() {
+		results[pk] = struct{}{}
+	}
+	if _, ok := results["sku:3"]; !ok || len(results) != 1 {
+		t.Fatalf("expected only sku:3, got %v", results)
+	}
+
+	count := 0
+	for range idx.Search("laptop").Where("weight", AtLeast(0)).Keys() {
+		count++
+	}
+	if count != 0 {
+		t.Fatalf("expected no results for an unattached field, got %d", count)
+	}
+
+	// a key present in an appended shard too is yielded once
+	appended, err := New(nil, map[string][]string{"sku:3": {"laptop", "refurbished"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.Append(appended)
+	var keys []string
+	for pk := range idx.Search("laptop").Where("price", AtMost(1000)).Keys() {
+		keys = append(keys, pk)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected sku:1 and sku:3 once each, got %v", keys)
+	}
+}
+
+// TestNumericFieldDeserialize tests that a sidecar round trips and that malformed ones are rejected
+func TestNumericFieldDeserialize(t *testing.T) {
+	data, _ := NewNumericField(map[string]float64{"sku:1": 999, "sku:2": 450}).Serialize()
+	var f NumericField
+	if err := f.Deserialize(data); err != nil || len(f.Keys) != 2 || f.Keys[0] != "sku:2" {
+		t.Fatalf("expected sku:2 first, got %v, %v", f.Keys, err)
+	}
+	for _, malformed := range []string{
+		`{"values":[1,2],"keys":["sku:1"]}`,
+		`{"values":[2,1],"keys":["sku:1","sku:2"]}`,
+	} {
+		if err := f.Deserialize([]byte(malformed)); err != ErrMalformedNumeric {
+			t.Fatalf("expected ErrMalformedNumeric for %s, got %v", malformed, err)
+		}
+	}
+	if len(f.Keys) != 2 {
+		t.Fatalf("expected the sidecar unchanged, got %v", f.Keys)
+	}
+}
@@ -0,0 +1,120 @@
+package fulltext
+
+import "fmt"
+import "slices"
+import "strings"
+
+// SkewReport describes the sizes of the shards of an index, see Skew
+type SkewReport struct {
+	Shards []ShardSize
+	// MedianRows and MedianMaxword are the medians over the shards holding rows
+	MedianRows    uint64
+	MedianMaxword int
+	// Flags name the detected skew: "giant_shard" for shards holding over skewFactor times the median rows, such as
+	// one appended full index, "small_shards" for several shards holding under 1/skewFactor of the median rows, such
+	// as left by many Appends and Updates, and "long_words" for shards whose Maxword, and so bucket count, is over
+	// skewFactor times the median
+	Flags []string
+	// CompactNeeded is set when Compact would merge small shards
+	CompactNeeded bool
+}
+
+// ShardSize is the size of one shard
+type ShardSize struct {
+	Shard int
+	Rows  uint64
+	// Deleted counts the rows of the shard hidden by Delete or Update, which Compact drops
+	Deleted uint64
+	Maxword int
+	// BucketBytes holds the bytes of the row and count filters of every bucket, Bytes the bytes of all filters of the shard
+	BucketBytes []int
+	Bytes       int
+	// Skewed is set when the shard caused one of the flags of the report
+	Skewed bool
+}
+
+// skewFactor is the factor from the median a shard must exceed to be reported as skewed
+const skewFactor = 4
+
+// Skew reports the rows, deleted rows, Maxword and bucket sizes of every shard, flagging pathological skew between the shards,
+// so operators know when Compact is needed.
+func (i *Index) Skew() *SkewReport {
+	r := new(SkewReport)
+	var rows []uint64
+	var maxwords []int
+	for curr := range i.private {
+		p := &i.private[curr]
+		s := ShardSize{Shard: curr, Rows: p.Rows, Maxword: p.Maxword}
+		for pos := uint64(1); len(i.deleted) > 0 && pos <= p.Rows; pos++ {
+			if i.deletedAt(curr, pos) {
+				s.Deleted++
+			}
+		}
+		s.Bytes = len(p.Pk) + len(p.Bloom) + len(p.Frequencies) + len(p.Weights)
+		for bucket := range p.Buckets {
+			size := len(p.Buckets[bucket])
+			if bucket < len(p.Counts) {
+				size += len(p.Counts[bucket])
+			}
+			s.BucketBytes = append(s.BucketBytes, size)
+			s.Bytes += size
+		}
+		r.Shards = append(r.Shards, s)
+		if p.Rows > 0 {
+			rows = append(rows, p.Rows)
+			maxwords = append(maxwords, p.Maxword)
+		}
+	}
+	if len(rows) == 0 {
+		return r
+	}
+	slices.Sort(rows)
+	slices.Sort(maxwords)
+	r.MedianRows, r.MedianMaxword = rows[len(rows)/2], maxwords[len(maxwords)/2]
+	var giant, small, long int
+	for curr := range r.Shards {
+		s := &r.Shards[curr]
+		if s.Rows == 0 {
+			continue
+		}
+		if s.Rows > skewFactor*r.MedianRows {
+			s.Skewed = true
+			giant++
+		}
+		if s.Rows*skewFactor < r.MedianRows {
+			s.Skewed = true
+			small++
+		}
+		if s.Maxword > skewFactor*r.MedianMaxword {
+			s.Skewed = true
+			long++
+		}
+	}
+	if giant > 0 {
+		r.Flags = append(r.Flags, "giant_shard")
+	}
+	if small > 1 {
+		r.Flags = append(r.Flags, "small_shards")
+		r.CompactNeeded = true
+	}
+	if long > 0 {
+		r.Flags = append(r.Flags, "long_words")
+	}
+	return r
+}
+
+// String renders the report as a table of shards, marking the skewed ones
+func (r *SkewReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "median rows %d, median maxword %d, flags %v, compact needed %v\n",
+		r.MedianRows, r.MedianMaxword, r.Flags, r.CompactNeeded)
+	for _, s := range r.Shards {
+		var mark string
+		if s.Skewed {
+			mark = " skewed"
+		}
+		fmt.Fprintf(&b, "  shard %d: %d rows, maxword %d, %d bytes in %d buckets%s\n",
+			s.Shard, s.Rows, s.Maxword, s.Bytes, len(s.BucketBytes), mark)
+	}
+	return b.String()
+}
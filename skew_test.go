@@ -0,0 +1,49 @@
+package fulltext
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestSkew tests flagging a giant appended shard and the small shards Compact merges
+func TestSkew(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	if r := idx.Skew(); len(r.Flags) != 0 || r.CompactNeeded || r.MedianRows != 10 {
+		t.Fatalf("expected no skew, got %s", r)
+	}
+	data := make(map[string][]string)
+	for j := 0; j < 100; j++ {
+		data[fmt.Sprintf("big:%03d", j)] = []string{"common"}
+	}
+	opts := NewDefaultOpts()
+	opts.TargetShardRows = 100
+	opts.MinShards = 1
+	giant, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.Append(giant)
+	for _, pk := range []string{"doc:001", "doc:002"} {
+		if err := idx.Update(pk, BagOfWords{"updated": {}}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	r := idx.Skew()
+	if !slices.Contains(r.Flags, "giant_shard") || !slices.Contains(r.Flags, "small_shards") || !r.CompactNeeded {
+		t.Fatalf("expected giant and small shards, got %s", r)
+	}
+	var updated []ShardSize
+	for _, s := range r.Shards {
+		if s.Rows == 1 {
+			updated = append(updated, s)
+		}
+	}
+	if len(updated) != 2 || !updated[1].Skewed || updated[1].Bytes == 0 {
+		t.Fatalf("expected two skewed single row shards, got %+v", updated)
+	}
+	if !strings.Contains(r.String(), "skewed") {
+		t.Fatalf("expected skewed shards in %s", r)
+	}
+}
@@ -0,0 +1,235 @@
+// package sqlite embeds a fulltext index of a text column in a SQLite database, an FTS5-like capability for
+// mobile and desktop apps. Triggers log the changed primary keys of the table, Poll applies them to the index,
+// and Save stores the serialized index in a blob table so it is not rebuilt on the next start.
+// Any database/sql SQLite driver can be used.
+//
+//	t, err := sqlite.Open(ctx, db, "notes", "id", "body", nil)
+//	if err != nil {
+//		...
+//	}
+//	go t.Run(ctx)
+//	for pk := range t.Lookup("golang", true, true) {
+//		...
+//	}
+package sqlite
+
+import "context"
+import "database/sql"
+import "errors"
+import "fmt"
+import "strings"
+import "time"
+import "github.com/neurlang/fulltext"
+import "github.com/neurlang/fulltext/ingest"
+import "github.com/neurlang/fulltext/stream"
+
+// Table keeps the index of a table in sync. Lookup is thread safe, Poll and Save must not run concurrently.
+type Table struct {
+	// Interval waits between the polls of Run. Default = 1s.
+	Interval time.Duration
+	// SaveInterval waits between the saves of Run. Default = 1m.
+	SaveInterval time.Duration
+
+	db                   *sql.DB
+	table                string
+	pkColumn, textColumn string
+	opts                 *fulltext.NewOpts
+	stream               *stream.Stream
+	// seq is the last change log entry applied to the index
+	seq int64
+}
+
+// Open installs the change log triggers of table, then loads the saved index, or builds and saves it on the first open.
+// Opts can be nil. Primary keys of varying length, such as integers, are padded with NUL bytes unless opts sets
+// KeyPadding, see ingest.PadKeys.
+func Open(ctx context.Context, db *sql.DB, table, pkColumn, textColumn string, opts *fulltext.NewOpts) (*Table, error) {
+	opts = ingest.PadKeys(opts)
+	t := &Table{
+		Interval:     time.Second,
+		SaveInterval: time.Minute,
+		db:           db,
+		table:        table,
+		pkColumn:     pkColumn,
+		textColumn:   textColumn,
+		opts:         opts,
+	}
+	for _, stmt := range t.schema() {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, err
+		}
+	}
+	var data []byte
+	err := db.QueryRowContext(ctx, "SELECT seq, data FROM "+t.name("index")+" WHERE id = 1").Scan(&t.seq, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, t.build(ctx)
+	} else if err != nil {
+		return nil, err
+	}
+	idx := new(fulltext.Index)
+	if err := idx.DeserializeProto(data); err != nil {
+		return nil, err
+	}
+	t.stream = stream.New(idx, opts)
+	return t, nil
+}
+
+// schema returns the statements creating the change log, its triggers and the blob table
+func (t *Table) schema() []string {
+	changes, table, pk := t.name("changes"), quoteIdent(t.table), quoteIdent(t.pkColumn)
+	return []string{
+		"CREATE TABLE IF NOT EXISTS " + changes + " (seq INTEGER PRIMARY KEY AUTOINCREMENT, pk TEXT NOT NULL)",
+		"CREATE TABLE IF NOT EXISTS " + t.name("index") + " (id INTEGER PRIMARY KEY CHECK (id = 1), seq INTEGER NOT NULL, data BLOB NOT NULL)",
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER INSERT ON %s BEGIN INSERT INTO %s (pk) VALUES (NEW.%s); END",
+			t.name("insert"), table, changes, pk),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER UPDATE ON %s BEGIN INSERT INTO %s (pk) VALUES (OLD.%s), (NEW.%s); END",
+			t.name("update"), table, changes, pk, pk),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER DELETE ON %s BEGIN INSERT INTO %s (pk) VALUES (OLD.%s); END",
+			t.name("delete"), table, changes, pk),
+	}
+}
+
+// build indexes the current rows of the table and saves the index
+func (t *Table) build(ctx context.Context) error {
+	// changes logged while scanning are applied again by the next Poll
+	err := t.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM "+t.name("changes")).Scan(&t.seq)
+	if err != nil {
+		return err
+	}
+	rows, err := t.db.QueryContext(ctx, fmt.Sprintf("SELECT CAST(%s AS TEXT), %s FROM %s",
+		quoteIdent(t.pkColumn), quoteIdent(t.textColumn), quoteIdent(t.table)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	data := make(map[string]fulltext.BagOfWords)
+	for rows.Next() {
+		var pk string
+		var text sql.NullString
+		if err := rows.Scan(&pk, &text); err != nil {
+			return err
+		}
+		data[pk] = ingest.Words(text.String, t.opts)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	idx := new(fulltext.Index)
+	if len(data) > 0 {
+		if idx, err = fulltext.New(t.opts, data, nil); err != nil {
+			return err
+		}
+	}
+	t.stream = stream.New(idx, t.opts)
+	return t.Save(ctx)
+}
+
+// Poll applies the logged changes to the index, re-reading the text of every changed primary key. The changes stay
+// logged when applying them fails, so the next Poll retries them.
+func (t *Table) Poll(ctx context.Context) error {
+	rows, err := t.db.QueryContext(ctx, "SELECT seq, pk FROM "+t.name("changes")+" WHERE seq > ? ORDER BY seq", t.seq)
+	if err != nil {
+		return err
+	}
+	var seq = t.seq
+	var changed []string
+	seen := make(map[string]struct{})
+	for rows.Next() {
+		var pk string
+		if err := rows.Scan(&seq, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		if _, ok := seen[pk]; !ok {
+			seen[pk] = struct{}{}
+			changed = append(changed, pk)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	events := make(chan stream.Event, len(changed))
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", quoteIdent(t.textColumn), quoteIdent(t.table), quoteIdent(t.pkColumn))
+	for _, pk := range changed {
+		var text sql.NullString
+		err := t.db.QueryRowContext(ctx, query, pk).Scan(&text)
+		if errors.Is(err, sql.ErrNoRows) {
+			events <- stream.Event{PK: pk, Delete: true}
+			continue
+		} else if err != nil {
+			return err
+		}
+		events <- stream.Event{PK: pk, Words: ingest.Words(text.String, t.opts)}
+	}
+	close(events)
+	if err := t.stream.Consume(ctx, events); err != nil {
+		return err
+	}
+	t.seq = seq
+	return nil
+}
+
+// Save stores the serialized index in the blob table and prunes the applied change log entries
+func (t *Table) Save(ctx context.Context) error {
+	var data []byte
+	var err error
+	t.stream.View(func(idx *fulltext.Index) {
+		data, err = idx.SerializeProto()
+	})
+	if err != nil {
+		return err
+	}
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO "+t.name("index")+" (id, seq, data) VALUES (1, ?, ?)", t.seq, data); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+t.name("changes")+" WHERE seq <= ?", t.seq); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Run polls every Interval and saves every SaveInterval until ctx is done or a poll or a save fails.
+// The index is saved once more when ctx is done.
+func (t *Table) Run(ctx context.Context) error {
+	poll := time.NewTicker(t.Interval)
+	defer poll.Stop()
+	save := time.NewTicker(t.SaveInterval)
+	defer save.Stop()
+	for {
+		select {
+		case <-poll.C:
+			if err := t.Poll(ctx); err != nil {
+				return err
+			}
+		case <-save.C:
+			if err := t.Save(ctx); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			if err := t.Save(context.WithoutCancel(ctx)); err != nil {
+				return err
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// Lookup iterates like Index.Lookup over the index of the table
+func (t *Table) Lookup(word string, exact, dedup bool) func(yield func(primaryKey string) bool) {
+	return t.stream.Lookup(word, exact, dedup)
+}
+
+// name returns the quoted name of a helper table or trigger of the table
+func (t *Table) name(kind string) string {
+	return quoteIdent("fulltext_" + t.table + "_" + kind)
+}
+
+// quoteIdent quotes an identifier
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/neurlang/fulltext"
+)
+
+// TestSchema tests that the triggers log the old and new primary keys into the change log of the table
+func TestSchema(t *testing.T) {
+	tbl := &Table{table: `my"notes`, pkColumn: "id", textColumn: "body"}
+	stmts := tbl.schema()
+	if len(stmts) != 5 {
+		t.Fatalf("expected 5 statements, got %d", len(stmts))
+	}
+	want := `CREATE TRIGGER IF NOT EXISTS "fulltext_my""notes_update" AFTER UPDATE ON "my""notes" ` +
+		`BEGIN INSERT INTO "fulltext_my""notes_changes" (pk) VALUES (OLD."id"), (NEW."id"); END`
+	if stmts[3] != want {
+		t.Fatalf("expected %s, got %s", want, stmts[3])
+	}
+	for _, stmt := range stmts {
+		if !strings.Contains(stmt, "IF NOT EXISTS") {
+			t.Fatalf("expected an idempotent statement, got %s", stmt)
+		}
+	}
+}
+
+func openNotes(t *testing.T, rows int) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "notes.db"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"); err != nil && strings.Contains(err.Error(), "cgo") {
+		t.Skip("the sqlite3 driver needs cgo")
+	} else if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for id := 1; id <= rows; id++ {
+		if _, err := db.Exec("INSERT INTO notes (id, body) VALUES (?, ?)", id, fmt.Sprintf("note%03d about golang", id)); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	return db
+}
+
+func lookupAll(tbl *Table, word string) (keys []string) {
+	for pk := range tbl.Lookup(word, true, true) {
+		keys = append(keys, pk)
+	}
+	sort.Strings(keys)
+	return
+}
+
+// TestOpen tests indexing, following and reloading a table keyed by integers of varying length
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	db := openNotes(t, 12)
+	tbl, err := Open(ctx, db, "notes", "id", "body", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keys := lookupAll(tbl, "golang"); fmt.Sprint(keys) != "[1 10 11 12 2 3 4 5 6 7 8 9]" {
+		t.Fatalf("expected the unpadded keys 1 to 12, got %v", keys)
+	}
+	for _, stmt := range []string{
+		"UPDATE notes SET body = 'rust' WHERE id = 3",
+		"DELETE FROM notes WHERE id = 11",
+		"INSERT INTO notes (id, body) VALUES (100, 'python')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := tbl.Poll(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := tbl.Save(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	reopened, err := Open(ctx, db, "notes", "id", "body", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, tbl := range []*Table{tbl, reopened} {
+		if keys := lookupAll(tbl, "golang"); len(keys) != 10 {
+			t.Fatalf("expected 10 results, got %v", keys)
+		}
+		if keys := lookupAll(tbl, "rust"); len(keys) != 1 || keys[0] != "3" {
+			t.Fatalf("expected [3], got %v", keys)
+		}
+		if keys := lookupAll(tbl, "python"); len(keys) != 1 || keys[0] != "100" {
+			t.Fatalf("expected [100], got %v", keys)
+		}
+	}
+}
+
+// TestPollError tests that changes failing to apply stay logged for the next Poll, also across a Save
+func TestPollError(t *testing.T) {
+	ctx := context.Background()
+	db := openNotes(t, 6)
+	opts := fulltext.NewDefaultOpts()
+	opts.KeyPadding = &fulltext.KeyPadding{Byte: '7'}
+	tbl, err := Open(ctx, db, "notes", "id", "body", opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO notes (id, body) VALUES (17, 'python')"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := tbl.Poll(ctx); !errors.Is(err, fulltext.ErrAmbiguousPadding) {
+		t.Fatalf("expected ErrAmbiguousPadding, got %v", err)
+	}
+	if err := tbl.Save(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var logged int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM "fulltext_notes_changes"`).Scan(&logged); err != nil || logged != 1 {
+		t.Fatalf("expected the change kept logged, got %d and %v", logged, err)
+	}
+}
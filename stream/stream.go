@@ -0,0 +1,242 @@
+// package stream keeps a fulltext index tracking a live event stream: events are batched into incremental
+// segments appended to the index every BatchSize events or Interval, updates and deletions hide the older rows.
+//
+//	s := stream.New(idx, nil)
+//	go s.Consume(ctx, events)
+//	for pk := range s.Lookup("golang", true, true) {
+//		...
+//	}
+//
+// Kafka, NATS and other brokers plug in through Reader, see FromReader. Writers needing read-your-writes
+// consistency call Add, and pass the returned sequence number to LookupMinSeq.
+package stream
+
+import "context"
+import "sync"
+import "sync/atomic"
+import "time"
+import "github.com/neurlang/fulltext"
+
+// Event indexes the words of the row with PK, replacing its earlier words, or deletes the row
+type Event struct {
+	PK     string
+	Words  fulltext.BagOfWords
+	Delete bool
+}
+
+// Reader reads events from a broker, blocking until the next event or until ctx is done.
+// A Kafka or NATS consumer becomes a Reader by decoding its messages into events.
+type Reader interface {
+	ReadEvent(ctx context.Context) (Event, error)
+}
+
+// FromReader streams the events of r until ctx is done or r fails, closing the channel afterwards.
+// The returned function reports the error of r, once the channel is closed.
+func FromReader(ctx context.Context, r Reader) (<-chan Event, func() error) {
+	events := make(chan Event)
+	var err error
+	go func() {
+		defer close(events)
+		for {
+			var e Event
+			if e, err = r.ReadEvent(ctx); err != nil {
+				return
+			}
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			}
+		}
+	}()
+	return events, func() error { return err }
+}
+
+// Stream appends the batched events to an index. Stream is thread safe.
+type Stream struct {
+	// BatchSize appends a segment once this many events are pending. Default = 1000.
+	BatchSize int
+	// Interval appends the pending events at least this often. Default = 1s.
+	Interval time.Duration
+	// OnError receives the errors of building a segment, whose events are dropped. Nil = Flush returns the error and
+	// Consume stops with it, leaving Seq before the dropped events.
+	OnError func(err error)
+
+	opts *fulltext.NewOpts
+	mut  sync.RWMutex
+	idx  *fulltext.Index
+	// live holds the primary keys with rows in idx, read from idx by the first append, so only they are deleted
+	live map[string]struct{}
+
+	// pending holds the events added after the last append, the latest numbered added
+	pendingMut sync.Mutex
+	pending    []Event
+	added      uint64
+	// flushMut orders the appends, so applied only grows
+	flushMut sync.Mutex
+	applied  atomic.Uint64
+}
+
+// New streams into idx, which can be nil, building the segments with opts. Opts can be nil.
+func New(idx *fulltext.Index, opts *fulltext.NewOpts) *Stream {
+	if idx == nil {
+		idx = new(fulltext.Index)
+	}
+	return &Stream{BatchSize: 1000, Interval: time.Second, opts: opts, idx: idx}
+}
+
+// Consume batches events into segments until the channel is closed, appending the last pending events, until ctx is
+// done, or until appending a segment fails without OnError
+func (s *Stream) Consume(ctx context.Context, events <-chan Event) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return s.Flush()
+			}
+			if _, err := s.add(e); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Add queues e into the pending batch, appending the batch once BatchSize events are pending, and returns
+// the sequence number of e. The event is visible to lookups once Seq reaches it, see LookupMinSeq.
+// A batch failing to append is reported to OnError only, Flush and Consume return the error when OnError is nil.
+func (s *Stream) Add(e Event) (seq uint64) {
+	seq, _ = s.add(e)
+	return seq
+}
+
+// add is Add returning the error of appending the batch
+func (s *Stream) add(e Event) (seq uint64, err error) {
+	s.pendingMut.Lock()
+	s.pending = append(s.pending, e)
+	s.added++
+	seq = s.added
+	full := len(s.pending) >= s.BatchSize
+	s.pendingMut.Unlock()
+	if full {
+		err = s.Flush()
+	}
+	return seq, err
+}
+
+// Flush appends the pending events now. A segment failing to build drops its events, and its error goes to OnError
+// or is returned.
+func (s *Stream) Flush() error {
+	s.flushMut.Lock()
+	defer s.flushMut.Unlock()
+	s.pendingMut.Lock()
+	batch, seq := s.pending, s.added
+	s.pending = nil
+	s.pendingMut.Unlock()
+	if err := s.append(batch); err != nil {
+		if s.OnError == nil {
+			return err
+		}
+		s.OnError(err)
+	}
+	s.applied.Store(seq)
+	return nil
+}
+
+// Seq returns the sequence number of the last event visible to lookups, events dropped by OnError included
+func (s *Stream) Seq() uint64 {
+	return s.applied.Load()
+}
+
+// append builds a segment of the latest words of every primary key in batch, hiding their older rows
+func (s *Stream) append(batch []Event) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	latest := make(map[string]fulltext.BagOfWords, len(batch))
+	for _, e := range batch {
+		if e.Delete {
+			latest[e.PK] = nil
+		} else {
+			latest[e.PK] = e.Words
+		}
+	}
+	rows := make(map[string]fulltext.BagOfWords, len(latest))
+	for pk, words := range latest {
+		if words != nil {
+			rows[pk] = words
+		}
+	}
+	var segment *fulltext.Index
+	if len(rows) > 0 {
+		var err error
+		if segment, err = fulltext.New(s.opts, rows, nil); err != nil {
+			return err
+		}
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.live == nil {
+		s.live = make(map[string]struct{})
+		for pk := range s.idx.Keys() {
+			s.live[pk] = struct{}{}
+		}
+	}
+	for pk, words := range latest {
+		if _, ok := s.live[pk]; ok {
+			s.idx.Delete(pk)
+			delete(s.live, pk)
+		}
+		if words != nil {
+			s.live[pk] = struct{}{}
+		}
+	}
+	if segment != nil {
+		s.idx.Append(segment)
+	}
+	return nil
+}
+
+// Lookup iterates like Index.Lookup, holding off segment appends until the iteration ends
+func (s *Stream) Lookup(word string, exact, dedup bool) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		s.mut.RLock()
+		defer s.mut.RUnlock()
+		for pk := range s.idx.Lookup(word, exact, dedup) {
+			if !yield(pk) {
+				return
+			}
+		}
+	}
+}
+
+// LookupMinSeq iterates like Lookup, first appending the pending events when the event numbered minSeq by Add
+// is not visible yet, so a just added row is found before acknowledging its write
+func (s *Stream) LookupMinSeq(word string, exact, dedup bool, minSeq uint64) func(yield func(primaryKey string) bool) {
+	return func(yield func(string) bool) {
+		if s.Seq() < minSeq {
+			s.Flush()
+		}
+		for pk := range s.Lookup(word, exact, dedup) {
+			if !yield(pk) {
+				return
+			}
+		}
+	}
+}
+
+// View calls fn with the index, holding off segment appends until fn returns, such as to serialize a snapshot.
+// Fn must not modify the index, the stream tracks the keys it holds.
+func (s *Stream) View(fn func(idx *fulltext.Index)) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	fn(s.idx)
+}
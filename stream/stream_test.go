@@ -0,0 +1,145 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/neurlang/fulltext"
+)
+
+func lookupAll(s *Stream, word string) (results []string) {
+	for pk := range s.Lookup(word, true, true) {
+		results = append(results, pk)
+	}
+	return
+}
+
+// TestConsume tests that batched events are appended as segments, updates and deletions hiding older rows
+func TestConsume(t *testing.T) {
+	s := New(nil, nil)
+	s.BatchSize = 2
+	events := make(chan Event, 8)
+	events <- Event{PK: "doc:1", Words: fulltext.BagOfWords{"golang": {}}}
+	events <- Event{PK: "doc:2", Words: fulltext.BagOfWords{"golang": {}}}
+	events <- Event{PK: "doc:1", Words: fulltext.BagOfWords{"rust": {}}}
+	events <- Event{PK: "doc:2", Delete: true}
+	events <- Event{PK: "doc:3", Words: fulltext.BagOfWords{"rust": {}}}
+	close(events)
+	if err := s.Consume(context.Background(), events); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if results := lookupAll(s, "golang"); len(results) != 0 {
+		t.Fatalf("expected no results, got %v", results)
+	}
+	if results := lookupAll(s, "rust"); len(results) != 2 {
+		t.Fatalf("expected 2 results, got %v", results)
+	}
+	var generation uint64
+	s.View(func(idx *fulltext.Index) {
+		generation = idx.Generation()
+	})
+	if generation == 0 {
+		t.Fatal("expected the segments to advance the generation")
+	}
+}
+
+// TestDeleteOnlyKnownKeys tests that only keys holding rows are deleted, new keys adding no tombstones
+func TestDeleteOnlyKnownKeys(t *testing.T) {
+	idx, err := fulltext.New(nil, map[string][]string{"doc:1": {"golang"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	s := New(idx, nil)
+	generation := func() (g uint64) {
+		s.View(func(idx *fulltext.Index) { g = idx.Generation() })
+		return
+	}
+	s.Add(Event{PK: "doc:2", Words: fulltext.BagOfWords{"rust": {}}})
+	s.Add(Event{PK: "doc:3", Delete: true})
+	s.Flush()
+	if g := generation(); g != 1 {
+		t.Fatalf("expected only the segment to advance the generation, got %d", g)
+	}
+	s.Add(Event{PK: "doc:1", Words: fulltext.BagOfWords{"rust": {}}})
+	s.Add(Event{PK: "doc:2", Words: fulltext.BagOfWords{"python": {}}})
+	s.Flush()
+	if g := generation(); g != 4 {
+		t.Fatalf("expected two deletions and a segment, got generation %d", g)
+	}
+	if results := lookupAll(s, "golang"); len(results) != 0 {
+		t.Fatalf("expected the replaced doc:1 hidden, got %v", results)
+	}
+	if results := lookupAll(s, "rust"); len(results) != 1 || results[0] != "doc:1" {
+		t.Fatalf("expected [doc:1], got %v", results)
+	}
+}
+
+// TestConsumeError tests that a segment failing to build stops Consume without OnError, leaving Seq before it
+func TestConsumeError(t *testing.T) {
+	s := New(nil, nil)
+	events := make(chan Event, 2)
+	events <- Event{PK: "doc:1", Words: fulltext.BagOfWords{"golang": {}}}
+	events <- Event{PK: "doc:10", Words: fulltext.BagOfWords{"golang": {}}}
+	close(events)
+	if err := s.Consume(context.Background(), events); !errors.Is(err, fulltext.ErrNonuniform) {
+		t.Fatalf("expected ErrNonuniform, got %v", err)
+	}
+	if s.Seq() != 0 {
+		t.Fatalf("expected seq 0, got %d", s.Seq())
+	}
+	var handled error
+	s.OnError = func(err error) { handled = err }
+	s.Add(Event{PK: "doc:1", Words: fulltext.BagOfWords{"golang": {}}})
+	s.Add(Event{PK: "doc:10", Words: fulltext.BagOfWords{"golang": {}}})
+	if err := s.Flush(); err != nil || !errors.Is(handled, fulltext.ErrNonuniform) || s.Seq() != 4 {
+		t.Fatalf("expected ErrNonuniform handled at seq 4, got %v and %v at %d", err, handled, s.Seq())
+	}
+}
+
+// TestLookupMinSeq tests that lookups requiring a sequence number see the added event before the batch is full
+func TestLookupMinSeq(t *testing.T) {
+	s := New(nil, nil)
+	seq := s.Add(Event{PK: "doc:1", Words: fulltext.BagOfWords{"golang": {}}})
+	if results := lookupAll(s, "golang"); len(results) != 0 {
+		t.Fatalf("expected the pending event to be hidden, got %v", results)
+	}
+	var results []string
+	for pk := range s.LookupMinSeq("golang", true, true, seq) {
+		results = append(results, pk)
+	}
+	if len(results) != 1 || results[0] != "doc:1" {
+		t.Fatalf("expected [doc:1], got %v", results)
+	}
+	if s.Seq() != seq {
+		t.Fatalf("expected seq %d, got %d", seq, s.Seq())
+	}
+}
+
+type sliceReader []Event
+
+func (r *sliceReader) ReadEvent(ctx context.Context) (Event, error) {
+	if len(*r) == 0 {
+		return Event{}, io.EOF
+	}
+	e := (*r)[0]
+	*r = (*r)[1:]
+	return e, nil
+}
+
+// TestFromReader tests streaming the events of a broker reader
+func TestFromReader(t *testing.T) {
+	r := &sliceReader{{PK: "doc:1", Words: fulltext.BagOfWords{"golang": {}}}}
+	events, failed := FromReader(context.Background(), r)
+	s := New(nil, nil)
+	if err := s.Consume(context.Background(), events); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := failed(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if results := lookupAll(s, "golang"); len(results) != 1 || results[0] != "doc:1" {
+		t.Fatalf("expected [doc:1], got %v", results)
+	}
+}
@@ -0,0 +1,20 @@
+package fulltext
+
+// stride returns the offset step between the indexed buckets of the shard, see NewOpts.ShingleStride
+func (p *index) stride() int {
+	return max(1, int(p.Stride))
+}
+
+// queryShingles returns the number of shingles of a query of length n a matching row holds in the indexed buckets.
+// Exact lookups align the query to bucket 0 and count the offsets within Depth, subword lookups take the least of
+// the stride alignments.
+func (p *index) queryShingles(n, minWord int, exact bool) int {
+	n = n - minWord + 1
+	if exact {
+		if p.Depth > 0 {
+			n = min(n, p.Depth)
+		}
+		return (n-1)/p.stride() + 1
+	}
+	return max(1, n/p.stride())
+}
@@ -0,0 +1,86 @@
+package fulltext
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestShingleStride tests that a strided index is smaller and still finds every word, exactly and by subwords
+func TestShingleStride(t *testing.T) {
+	data := make(map[string]BagOfWords)
+	for j := 0; j < 300; j++ {
+		data[fmt.Sprintf("doc:%04d", j)] = BagOfWords{fmt.Sprintf("prefix%dsuffix", j): {}}
+	}
+	build := func(stride byte) *Index {
+		opts := NewDefaultOpts()
+		opts.ShingleStride = stride
+		idx, err := New(opts, data, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return idx
+	}
+	bucketBytes := func(idx *Index) (n int) {
+		for _, p := range idx.private {
+			for b := range p.Buckets {
+				n += len(p.Buckets[b]) + len(p.Counts[b])
+			}
+		}
+		return
+	}
+	full, strided := build(1), build(3)
+	if bucketBytes(strided)*2 > bucketBytes(full) {
+		t.Fatalf("expected strided buckets well below %d bytes, got %d", bucketBytes(full), bucketBytes(strided))
+	}
+	serialized, _ := strided.SerializeProto()
+	var loaded Index
+	if err := loaded.DeserializeProto(serialized); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, j := range []int{0, 7, 42, 299} {
+		pk := fmt.Sprintf("doc:%04d", j)
+		for _, q := range []struct {
+			word  string
+			exact bool
+		}{
+			{fmt.Sprintf("prefix%dsuffix", j), true},
+			{fmt.Sprintf("prefix%d", j), true},
+			{fmt.Sprintf("fix%dsuf", j), false},
+		} {
+			var found bool
+			for key := range loaded.Lookup(q.word, q.exact, true) {
+				found = found || key == pk
+			}
+			if !found {
+				t.Errorf("expected %s for %q exact=%v", pk, q.word, q.exact)
+			}
+		}
+	}
+}
+
+// TestShingleStrideNegative tests that strided lookups do not match words sharing a single probed shingle
+func TestShingleStrideNegative(t *testing.T) {
+	data := make(map[string]BagOfWords)
+	for j := 0; j < 10; j++ {
+		data[fmt.Sprintf("go:%02d", j)] = BagOfWords{"golang": {}}
+		data[fmt.Sprintf("er:%02d", j)] = BagOfWords{"erlang": {}}
+	}
+	opts := NewDefaultOpts()
+	opts.ShingleStride = 2
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, exact := range []bool{true, false} {
+		var n int
+		for pk := range idx.Lookup("golang", exact, true) {
+			if pk[:3] != "go:" {
+				t.Fatalf("expected golang rows only, got %s exact=%v", pk, exact)
+			}
+			n++
+		}
+		if n != 10 {
+			t.Fatalf("expected 10 results exact=%v, got %d", exact, n)
+		}
+	}
+}
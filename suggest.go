@@ -0,0 +1,124 @@
+package fulltext
+
+import "sort"
+
+// DidYouMean suggests indexed terms within a small edit distance of word, typically called when Lookup found nothing.
+// With a stored term dictionary (NewOpts.StoreTerms) terms up to two edits away are considered, otherwise
+// single edit candidates are probed against the shingle buckets, which can (in rare cases) suggest false positives.
+// Suggestions are ordered by the number of rows containing them, then longer (more specific) terms first.
+func (i *Index) DidYouMean(word string) []string {
+	var found = make(map[string]uint64)
+	var dictionary bool
+	for curr := range i.private {
+		if i.private[curr].Terms == nil {
+			continue
+		}
+		dictionary = true
+		maxDistance := 1
+		if len(word) >= 6 {
+			maxDistance = 2
+		}
+		for term, count := range i.private[curr].Terms {
+			if term != word && editDistance(term, word) <= maxDistance {
+				found[term] += count
+			}
+		}
+	}
+	if !dictionary {
+		for _, candidate := range edits(word) {
+			for curr := range i.private {
+				found[candidate] += i.private[curr].prefixCount(candidate)
+			}
+			if found[candidate] == 0 {
+				delete(found, candidate)
+			}
+		}
+	}
+	var suggestions = make([]string, 0, len(found))
+	for term := range found {
+		suggestions = append(suggestions, term)
+	}
+	sort.Slice(suggestions, func(a, b int) bool {
+		if found[suggestions[a]] != found[suggestions[b]] {
+			return found[suggestions[a]] > found[suggestions[b]]
+		}
+		if len(suggestions[a]) != len(suggestions[b]) {
+			return len(suggestions[a]) > len(suggestions[b])
+		}
+		return suggestions[a] < suggestions[b]
+	})
+	return suggestions
+}
+
+// prefixCount estimates the number of rows having a word starting with term, as the smallest count over its shingles.
+// Only the shingles at offsets with a built bucket count, see NewOpts.ShingleStride and the bucket depth.
+func (p *index) prefixCount(term string) (least uint64) {
+	minWord := p.minWord()
+	term = p.query(term)
+	if len(term) < minWord || p.Rows == 0 {
+		return 0
+	}
+	stride := p.stride()
+	for t := 0; t+minWord <= len(term) && t < len(p.Buckets); t += stride {
+		count := p.count(t, term[t:t+minWord])
+		if count == 0 || count > p.Rows {
+			return 0
+		}
+		if t == 0 || count < least {
+			least = count
+		}
+	}
+	return least
+}
+
+// edits returns the distinct strings one deletion, transposition, substitution or insertion away from word
+func edits(word string) []string {
+	var alphabet = []byte("abcdefghijklmnopqrstuvwxyz0123456789")
+	for _, c := range []byte(word) {
+		alphabet = append(alphabet, c)
+	}
+	var seen = map[string]struct{}{word: {}}
+	var out []string
+	add := func(candidate string) {
+		if _, ok := seen[candidate]; !ok {
+			seen[candidate] = struct{}{}
+			out = append(out, candidate)
+		}
+	}
+	for pos := 0; pos <= len(word); pos++ {
+		if pos < len(word) {
+			add(word[:pos] + word[pos+1:])
+		}
+		if pos+1 < len(word) {
+			add(word[:pos] + string(word[pos+1]) + string(word[pos]) + word[pos+2:])
+		}
+		for _, c := range alphabet {
+			if pos < len(word) {
+				add(word[:pos] + string(c) + word[pos+1:])
+			}
+			add(word[:pos] + string(c) + word[pos:])
+		}
+	}
+	return out
+}
+
+// editDistance returns the Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	var prev = make([]int, len(b)+1)
+	var next = make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for x := 1; x <= len(a); x++ {
+		next[0] = x
+		for y := 1; y <= len(b); y++ {
+			cost := 1
+			if a[x-1] == b[y-1] {
+				cost = 0
+			}
+			next[y] = min(prev[y]+1, next[y-1]+1, prev[y-1]+cost)
+		}
+		prev, next = next, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,37 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestDidYouMean tests suggestions with and without a stored term dictionary, also in strided and depth limited shards
+func TestDidYouMean(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+		"doc:3": {"python", "scripting"},
+	}
+	withTerms := NewDefaultOpts()
+	withTerms.StoreTerms = true
+	strided := NewDefaultOpts()
+	strided.ShingleStride = 2
+	shallow := NewDefaultOpts()
+	shallow.MaxBucketDepth = 4
+	for _, opts := range []*NewOpts{nil, withTerms, strided, shallow} {
+		idx, err := New(opts, data, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		suggestions := idx.DidYouMean("bakend")
+		if len(suggestions) == 0 || suggestions[0] != "backend" {
+			t.Fatalf("expected backend first, got %v", suggestions)
+		}
+		// past its depth, the shallow index cannot tell python from longer words starting with it
+		if opts == shallow {
+			continue
+		}
+		if suggestions := idx.DidYouMean("pythno"); len(suggestions) == 0 || suggestions[0] != "python" {
+			t.Fatalf("expected python first, got %v", suggestions)
+		}
+	}
+}
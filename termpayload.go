@@ -0,0 +1,55 @@
+package fulltext
+
+// termPayloadBits is the size of the payload of a word in a row
+const termPayloadBits = 16
+
+// addTermPayloads records the payload of every word of row pos, normalized like its words, ORing the payloads of words
+// normalizing alike. Words of bag without a payload record 0, so every word of the row resolves to its payload.
+func (p *index) addTermPayloads(pos int, bag BagOfWords, payloads map[string]uint16, normalize func(BagOfWords) BagOfWords) {
+	if p.termPayloads == nil {
+		p.termPayloads = make(map[string]uint64)
+	}
+	for word := range bag {
+		key := p.counterKey(p.salted(word), uint64(pos))
+		p.termPayloads[key] = p.termPayloads[key]
+	}
+	for word, payload := range payloads {
+		words := BagOfWords{word: {}}
+		if normalize != nil {
+			words = normalize(words)
+		}
+		for word := range words {
+			key := p.counterKey(p.salted(word), uint64(pos))
+			p.termPayloads[key] |= uint64(payload)
+		}
+	}
+}
+
+// buildTermPayloads turns the recorded term payloads into the shard filter
+func (p *index) buildTermPayloads() {
+	if len(p.termPayloads) > 0 {
+		p.TermPayloads = p.backend().New(numbers(p.termPayloads, termPayloadBits), termPayloadBits, 0)
+	}
+	p.termPayloads = nil
+}
+
+// termPayload returns the payload of word in row pos, 0 in shards without recorded term payloads
+func (p *index) termPayload(pos uint64, word string) uint16 {
+	if len(p.TermPayloads) < 2 {
+		return 0
+	}
+	return uint16(p.backend().GetNum(p.TermPayloads, termPayloadBits, p.counterKey(p.salted(word), pos)))
+}
+
+// LookupTermPayload iterates the primary keys of rows containing word like an exact deduplicated Lookup, yielding
+// the payload of word in the row, as recorded with NewOpts.TermPayloads, such as a bitmask of the fields holding
+// the word. Payloads are recorded per whole word, so word should be a complete word, and rows without recorded
+// payloads yield 0.
+func (i *Index) LookupTermPayload(word string) func(yield func(primaryKey string, payload uint16) bool) {
+	return func(yield func(string, uint16) bool) {
+		i.lookup(word, true, true, func(shard int, pos uint64) bool {
+			p := &i.private[shard]
+			return yield(p.key(pos), p.termPayload(pos, p.query(word)))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestLookupTermPayload tests that the payloads of words come back per row after serialization
+func TestLookupTermPayload(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+	}
+	// bit 1 = title, bit 2 = body
+	fields := map[string]map[string]uint16{
+		"doc:1": {"golang": 1, "backend": 2},
+		"doc:2": {"backend": 3},
+	}
+	opts := NewDefaultOpts()
+	opts.TermPayloads = func(pk string) map[string]uint16 { return fields[pk] }
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, serialize := range []func() ([]byte, error){idx.Serialize, idx.SerializeProto} {
+		serialized, _ := serialize()
+		var loaded Index
+		if err := loaded.deserializeAny(serialized); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		payloads := make(map[string]uint16)
+		for pk, payload := range loaded.LookupTermPayload("backend") {
+			payloads[pk] = payload
+		}
+		if len(payloads) != 2 || payloads["doc:1"] != 2 || payloads["doc:2"] != 3 {
+			t.Fatalf("unexpected payloads %v", payloads)
+		}
+		for pk, payload := range loaded.LookupTermPayload("rust") {
+			if pk != "doc:2" || payload != 0 {
+				t.Fatalf("expected doc:2 without a payload, got %s %d", pk, payload)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package fulltext
+
+import "sort"
+
+// Terms iterates the term dictionary in lexicographic order, yielding every indexed term with the number of rows containing it.
+// The dictionary is only kept when the index was built with NewOpts.StoreTerms, otherwise nothing is yielded.
+func (i *Index) Terms() func(yield func(term string, docCount uint64) bool) {
+	return func(yield func(string, uint64) bool) {
+		var merged = make(map[string]uint64)
+		for curr := range i.private {
+			for term, count := range i.private[curr].Terms {
+				merged[term] += count
+			}
+		}
+		for _, term := range sortedTerms(merged) {
+			if !yield(term, merged[term]) {
+				return
+			}
+		}
+	}
+}
+
+func sortedTerms(terms map[string]uint64) []string {
+	var sorted = make([]string, 0, len(terms))
+	for term := range terms {
+		sorted = append(sorted, term)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// TermStats returns the number of rows an exact deduplicated lookup of word finds, and that number per shard,
+// for experimenting with scoring functions. Like lookups, the counts can include false positives.
+func (i *Index) TermStats(word string) (docFreq uint64, shardHits []uint64) {
+	shardHits = make([]uint64, len(i.private))
+	i.lookup(word, true, true, func(shard int, pos uint64) bool {
+		shardHits[shard]++
+		docFreq++
+		return true
+	})
+	return
+}
@@ -0,0 +1,59 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestTermsDocumentFrequency tests that the stored dictionary survives serialization and counts rows
+func TestTermsDocumentFrequency(t *testing.T) {
+	data := map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+		"doc:3": {"golang", "frontend"},
+	}
+	opts := NewDefaultOpts()
+	opts.StoreTerms = true
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	serialized, _ := idx.SerializeProto()
+	var loaded Index
+	if err := loaded.DeserializeProto(serialized); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var terms []string
+	for term, count := range loaded.Terms() {
+		terms = append(terms, term)
+		if (term == "golang" || term == "backend") && count != 2 {
+			t.Fatalf("expected 2 rows for %s, got %d", term, count)
+		}
+	}
+	if len(terms) != 4 || terms[0] != "backend" || terms[3] != "rust" {
+		t.Fatalf("expected 4 sorted terms, got %v", terms)
+	}
+
+	plain, _ := New(nil, data, nil)
+	for term := range plain.Terms() {
+		t.Fatalf("expected no terms without StoreTerms, got %s", term)
+	}
+}
+
+// TestTermStats tests counting the rows matching a word in total and per shard
+func TestTermStats(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	docFreq, shardHits := idx.TermStats("common")
+	if docFreq != 40 || len(shardHits) != len(idx.private) {
+		t.Fatalf("expected 40 rows over %d shards, got %d over %d", len(idx.private), docFreq, len(shardHits))
+	}
+	for curr, hits := range shardHits {
+		if hits != idx.private[curr].Rows {
+			t.Fatalf("expected %d hits in shard %d, got %d", idx.private[curr].Rows, curr, hits)
+		}
+	}
+	idx.Delete("doc:007")
+	if docFreq, _ := idx.TermStats("common"); docFreq != 39 {
+		t.Fatalf("expected 39 rows after delete, got %d", docFreq)
+	}
+}
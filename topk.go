@@ -0,0 +1,197 @@
+package fulltext
+
+import "container/heap"
+import "context"
+import "math"
+import "sort"
+
+// topKeys keeps the k best scored keys in a min heap, the worst kept key on top, each key once with its best score
+type topKeys struct {
+	heap []scored
+	at   map[string]int
+	k    int
+}
+
+func (t *topKeys) Len() int           { return len(t.heap) }
+func (t *topKeys) Less(a, b int) bool { return worse(t.heap[a], t.heap[b]) }
+func (t *topKeys) Swap(a, b int) {
+	t.heap[a], t.heap[b] = t.heap[b], t.heap[a]
+	t.at[t.heap[a].key], t.at[t.heap[b].key] = a, b
+}
+func (t *topKeys) Push(x any) {
+	t.at[x.(scored).key] = len(t.heap)
+	t.heap = append(t.heap, x.(scored))
+}
+func (t *topKeys) Pop() any {
+	x := t.heap[len(t.heap)-1]
+	t.heap = t.heap[:len(t.heap)-1]
+	delete(t.at, x.key)
+	return x
+}
+
+// worse orders by ascending score, ties by descending key
+func worse(a, b scored) bool {
+	if a.score != b.score {
+		return a.score < b.score
+	}
+	return a.key > b.key
+}
+
+func newTopKeys(k int) *topKeys {
+	return &topKeys{at: make(map[string]int), k: k}
+}
+
+// offer keeps key if it scores among the k best
+func (t *topKeys) offer(key string, score float64) {
+	s := scored{key: key, score: score}
+	if at, ok := t.at[key]; ok {
+		if score > t.heap[at].score {
+			t.heap[at].score = score
+			heap.Fix(t, at)
+		}
+	} else if len(t.heap) < t.k {
+		heap.Push(t, s)
+	} else if worse(t.heap[0], s) {
+		delete(t.at, t.heap[0].key)
+		t.heap[0] = s
+		t.at[key] = 0
+		heap.Fix(t, 0)
+	}
+}
+
+// beaten reports whether k keys are kept all scoring at least score, so no key scoring at most score can enter
+func (t *topKeys) beaten(score float64) bool {
+	return len(t.heap) == t.k && t.heap[0].score >= score
+}
+
+// sorted returns the kept keys by descending score, ties by key
+func (t *topKeys) sorted() []scored {
+	sort.Slice(t.heap, func(a, b int) bool { return worse(t.heap[b], t.heap[a]) })
+	return t.heap
+}
+
+// yieldTop yields the kept keys best first
+func yieldTop(top []scored, yield func(string, float64) bool) {
+	for _, r := range top {
+		if !yield(r.key, r.score) {
+			return
+		}
+	}
+}
+
+// maxWeight is the highest word weight a row can record
+const maxWeight = float64(1<<weightBits-1) / weightScale
+
+// TopK yields the k best scored primary keys of word, best first, each once. Exact lookups score the weight of word
+// in the row like LookupWeighted, other lookups the fraction of the query shingles matched like LookupCoverage.
+// The matches stream from the shards straight into a heap of at most k keys, and the lookup stops once k keys
+// reached the highest score possible, keeping among keys tying at it those found first. Opts can be nil.
+func (i *Index) TopK(word string, k int, opts *LookupOpts) func(yield func(primaryKey string, score float64) bool) {
+	return func(yield func(string, float64) bool) {
+		if k <= 0 {
+			return
+		}
+		if opts == nil {
+			opts = new(LookupOpts)
+		}
+		yieldTop(i.topK(word, k, opts, true, 1).sorted(), yield)
+	}
+}
+
+// topK selects the k best keys of word, scoring exact matches by their weight and others by their coverage,
+// or 1 without coverage, all scaled by scale > 0
+func (i *Index) topK(word string, k int, opts *LookupOpts, coverage bool, scale float64) *topKeys {
+	top := newTopKeys(k)
+	var excluded map[string]struct{}
+	if len(opts.Exclude) > 0 {
+		excluded = i.excluded(opts)
+	}
+	weighted := opts.Exact && i.weighted()
+	bound := scale
+	if weighted {
+		bound *= maxWeight
+	}
+	minCoverage := opts.MinCoverage
+	if coverage && !opts.Exact {
+		minCoverage = max(minCoverage, math.SmallestNonzeroFloat64)
+	}
+	i.lookupWithin(context.Background(), opts.mark(word), opts.Exact, true, minCoverage, opts.budget(), func(shard int, pos uint64, score float64) bool {
+		p := &i.private[shard]
+		pk := p.key(pos)
+		if _, ok := excluded[pk]; ok || opts.Allow != nil && !opts.Allow(pk) {
+			return true
+		}
+		switch {
+		case weighted:
+			score = p.weight(pos, p.query(opts.mark(word)))
+		case opts.Exact || !coverage:
+			score = 1
+		}
+		top.offer(pk, score*scale)
+		return !top.beaten(bound)
+	})
+	return top
+}
+
+// TopK yields the k best scored primary keys of word like LookupScored, selected with a heap of at most k keys
+// instead of sorting every match. A single searched field streams its matches straight into the heap like
+// Index.TopK. Several fields sum their scores per key, so the matches of each field are collected, and after each
+// field the keys that cannot reach the k best anymore are dropped, unless a boost is negative.
+func (f *FieldIndex) TopK(word string, k int, opts *LookupOpts) func(yield func(primaryKey string, score float64) bool) {
+	return func(yield func(string, float64) bool) {
+		if k <= 0 {
+			return
+		}
+		if opts == nil {
+			opts = new(LookupOpts)
+		}
+		fieldOpts := *opts
+		fieldOpts.GlobalDedup = true
+		var names []string
+		var rest, negative = 0.0, false
+		for _, name := range f.Fields() {
+			if boost := opts.boost(name); boost != 0 {
+				names = append(names, name)
+				rest += boost * f.fields[name].bound(opts)
+				negative = negative || boost < 0
+			}
+		}
+		if len(names) == 1 && !negative {
+			yieldTop(f.fields[names[0]].topK(word, k, &fieldOpts, false, opts.boost(names[0])).sorted(), yield)
+			return
+		}
+		totals := make(map[string]float64)
+		for _, name := range names {
+			f.add(totals, name, word, &fieldOpts)
+			rest -= opts.boost(name) * f.fields[name].bound(opts)
+			if negative {
+				continue
+			}
+			floor := newTopKeys(k)
+			for pk, score := range totals {
+				floor.offer(pk, score)
+			}
+			if len(floor.heap) < k {
+				continue
+			}
+			for pk, score := range totals {
+				if score+rest < floor.heap[0].score {
+					delete(totals, pk)
+				}
+			}
+		}
+		top := newTopKeys(k)
+		for pk, score := range totals {
+			top.offer(pk, score)
+		}
+		yieldTop(top.sorted(), yield)
+	}
+}
+
+// bound returns the highest score a match of the index weighs in a FieldIndex lookup, before the boost
+func (i *Index) bound(opts *LookupOpts) float64 {
+	if opts.Exact && i.weighted() {
+		return maxWeight
+	}
+	return 1
+}
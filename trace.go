@@ -0,0 +1,28 @@
+package fulltext
+
+import "context"
+
+// Tracer starts spans for builds ("fulltext.New"), lookups ("fulltext.Lookup") and the per shard
+// probes of a lookup ("fulltext.Shard"). An OpenTelemetry trace.Tracer is adapted in a few lines.
+// Tracer and Span methods are called concurrently, so implementations must be thread safe.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation
+type Span interface {
+	SetAttribute(key string, value any)
+	End()
+}
+
+// WithTracer traces the lookups of the index with t, nil disables tracing.
+// WithTracer is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) WithTracer(t Tracer) *Index {
+	i.tracer = t
+	return i
+}
+
+// LookupContext is Lookup with the spans of the lookup parented under ctx. The lookup stops early once ctx is done.
+func (i *Index) LookupContext(ctx context.Context, word string, exact, dedup bool) func(yield func(primaryKey string) bool) {
+	return i.lookupKeys(ctx, cacheKey{word: word, exact: exact, dedup: dedup}, nil)
+}
@@ -0,0 +1,58 @@
+package fulltext
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+type testTracer struct {
+	mut   sync.Mutex
+	names map[string]int
+}
+
+type testSpan struct{}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mut.Lock()
+	t.names[name]++
+	t.mut.Unlock()
+	return ctx, testSpan{}
+}
+
+func (testSpan) SetAttribute(key string, value any) {}
+func (testSpan) End()                               {}
+
+// TestTracer tests that builds, lookups and shard probes are traced
+func TestTracer(t *testing.T) {
+	tracer := &testTracer{names: make(map[string]int)}
+	opts := NewDefaultOpts()
+	opts.Tracer = tracer
+	idx, err := New(opts, map[string][]string{
+		"doc:1": {"golang", "backend"},
+		"doc:2": {"rust", "backend"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	count := 0
+	for range idx.LookupContext(context.Background(), "backend", true, true) {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 results, got %d", count)
+	}
+	if tracer.names["fulltext.New"] != 1 || tracer.names["fulltext.Lookup"] != 1 || tracer.names["fulltext.Shard"] != 2 {
+		t.Fatalf("expected 1 build, 1 lookup and 2 shard spans, got %v", tracer.names)
+	}
+}
+
+// TestLookupContextCanceled tests that a canceled context stops the lookup
+func TestLookupContextCanceled(t *testing.T) {
+	idx := newTestIndex(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for pk := range idx.LookupContext(ctx, "backend", true, true) {
+		t.Errorf("expected no results, got %s", pk)
+	}
+}
@@ -0,0 +1,65 @@
+package fulltext
+
+import "sync"
+
+// Upgrade rewrites every Version 1 and 2 shard into the Version 3 layout, so old serialized indexes
+// stop paying the legacy lookup paths. Old filters cannot be enumerated, so the getter must
+// return the same words the shards were originally built from, which are transformed like the shards transformed them,
+// such as by their Analyzer or ASCIIFold. Opts can be nil, only its false positive settings and Sync are used.
+// Upgrade is NOT a thread safe operation. Use external synchronization to protect mutation of the index.
+func (i *Index) Upgrade(opts *NewOpts, getter func(primaryKey string) BagOfWords) error {
+	if getter == nil {
+		return ErrNilGetter
+	}
+	if opts == nil || opts.configured == false {
+		// defaults
+		opts = NewDefaultOpts()
+	}
+	var syncGetter = getter
+	if opts.Sync {
+		var mut sync.Mutex
+		syncGetter = func(pk string) (ret BagOfWords) {
+			mut.Lock()
+			ret = getter(pk)
+			mut.Unlock()
+			return
+		}
+	}
+	var getters = make([]func(primaryKey string) BagOfWords, len(i.private))
+	for curr := range i.private {
+		if i.private[curr].Version > 2 {
+			continue
+		}
+		normalize, err := i.private[curr].opts().normalizer()
+		if err != nil {
+			return err
+		}
+		getters[curr] = syncGetter
+		if normalize != nil {
+			getters[curr] = func(pk string) BagOfWords {
+				return normalize(syncGetter(pk))
+			}
+		}
+	}
+	var wg sync.WaitGroup
+	for curr := range i.private {
+		if getters[curr] == nil {
+			continue
+		}
+		if i.private[curr].Version <= 1 {
+			i.private[curr].MinWord = 3
+		}
+		i.private[curr].Version = 3
+		i.private[curr].Checksum = 0
+		i.private[curr].Counts = make([][]byte, len(i.private[curr].Buckets))
+		for offset := range i.private[curr].Buckets {
+			wg.Add(1)
+			go func(curr, offset int) {
+				i.private[curr].buildBucket(offset, opts.falsePositiveFunctions(offset), getters[curr])
+				wg.Done()
+			}(curr, offset)
+		}
+	}
+	wg.Wait()
+	return nil
+}
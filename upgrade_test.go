@@ -0,0 +1,140 @@
+package fulltext
+
+import (
+	"fmt"
+	"testing"
+
+	quaternary "github.com/neurlang/quaternary/v1"
+)
+
+// downgrade rewrites the shards of idx into the Version 1 layout, where counts live in the buckets under term+"0"
+func downgrade(idx *Index, getter func(string) BagOfWords) {
+	for curr := range idx.private {
+		p := &idx.private[curr]
+		for offset := range p.Buckets {
+			bag := make(map[string]uint64)
+			for j := uint64(1); j <= p.Rows; j++ {
+				for word := range getter(string(quaternary.Get(p.Pk, p.Pkbits, j))) {
+					if len(word) < 3+offset {
+						continue
+					}
+					wrd := word[offset : offset+3]
+					bag[wrd+"0"]++
+					bag[wrd+fmt.Sprint(bag[wrd+"0"])] = j
+				}
+			}
+			p.Buckets[offset] = quaternary.New(bag, p.Logrows, 0)
+		}
+		p.Version = 1
+		p.MinWord = 0
+		p.Counts = nil
+	}
+}
+
+// TestUpgradeVersion1 tests that a Version 1 index is rewritten and still finds its rows
+func TestUpgradeVersion1(t *testing.T) {
+	words := map[string]BagOfWords{
+		"doc:1": {"golang": struct{}{}, "backend": struct{}{}},
+		"doc:2": {"rust": struct{}{}, "backend": struct{}{}},
+	}
+	getter := func(key string) BagOfWords { return words[key] }
+	idx, err := New(nil, words, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	downgrade(idx, getter)
+
+	count := func() (n int) {
+		for range idx.Lookup("backend", true, true) {
+			n++
+		}
+		return
+	}
+	if n := count(); n != 2 {
+		t.Fatalf("expected 2 results before upgrade, got %d", n)
+	}
+	if err := idx.Upgrade(nil, getter); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, p := range idx.private {
+		if p.Version != 3 || len(p.Counts) != len(p.Buckets) {
+			t.Fatalf("expected version 3 shard with counts, got version %d", p.Version)
+		}
+	}
+	if err := idx.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n := count(); n != 2 {
+		t.Fatalf("expected 2 results after upgrade, got %d", n)
+	}
+}
+
+// TestUpgradeVersion2 tests that decimal counter keys of Version 2 shards are read and rewritten as binary
+func TestUpgradeVersion2(t *testing.T) {
+	words := map[string]BagOfWords{
+		"doc:1": {"golang": struct{}{}, "backend": struct{}{}},
+		"doc:2": {"rust": struct{}{}, "backend": struct{}{}},
+	}
+	getter := func(key string) BagOfWords { return words[key] }
+	idx, err := New(nil, words, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for curr := range idx.private {
+		p := &idx.private[curr]
+		p.Version = 2
+		for offset := range p.Buckets {
+			p.buildBucket(offset, 0, getter)
+		}
+	}
+	for _, upgrade := range []bool{false, true} {
+		if upgrade {
+			if err := idx.Upgrade(nil, getter); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		var n int
+		for range idx.Lookup("backend", true, true) {
+			n++
+		}
+		if n != 2 || (upgrade && idx.private[0].Version != 3) {
+			t.Fatalf("expected 2 results at version 3 after upgrade %v, got %d at version %d", upgrade, n, idx.private[0].Version)
+		}
+	}
+}
+
+// TestUpgradeTransformed tests that a Version 2 shard is rewritten with its own word transformations
+func TestUpgradeTransformed(t *testing.T) {
+	words := map[string]BagOfWords{
+		"doc:1": {"Café": struct{}{}, "crème": struct{}{}},
+		"doc:2": {"naïve": struct{}{}, "résumé": struct{}{}},
+	}
+	getter := func(key string) BagOfWords { return words[key] }
+	opts := NewDefaultOpts()
+	opts.ASCIIFold = true
+	opts.MaxWordLength = 5
+	idx, err := New(opts, words, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	normalize, _ := opts.normalizer()
+	for curr := range idx.private {
+		p := &idx.private[curr]
+		p.Version = 2
+		for offset := range p.Buckets {
+			p.buildBucket(offset, 0, func(key string) BagOfWords { return normalize(getter(key)) })
+		}
+	}
+	if err := idx.Upgrade(nil, getter); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for word, key := range map[string]string{"cafe": "doc:1", "Crème": "doc:1", "resumes": "doc:2"} {
+		var keys []string
+		for pk := range idx.Lookup(word, true, true) {
+			keys = append(keys, pk)
+		}
+		if len(keys) != 1 || keys[0] != key {
+			t.Fatalf("expected %s for %q after upgrade, got %v", key, word, keys)
+		}
+	}
+}
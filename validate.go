@@ -0,0 +1,140 @@
+package fulltext
+
+import "crypto/sha256"
+import "encoding/json"
+import "fmt"
+import "math/bits"
+
+var ErrInconsistentRows = fmt.Errorf("inconsistent_rows")
+var ErrMisalignedBuckets = fmt.Errorf("misaligned_buckets")
+var ErrMalformedFilter = fmt.Errorf("malformed_filter")
+var ErrUndecodablePk = fmt.Errorf("undecodable_primary_key")
+
+// ValidationError names the shard and the field that failed validation.
+type ValidationError struct {
+	Shard int
+	Field string
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("fulltext: shard %d: %s: %v", e.Shard, e.Field, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks the structural integrity of every shard: version bytes, Rows/Logrows consistency,
+// bucket/count slice alignment and that every primary key can be decoded. Loading runs the same checks on every
+// shard, wrapping the problem in ErrCorrupted. Returns a *ValidationError for the first problem found.
+func (i *Index) Validate() error {
+	for curr := range i.private {
+		if err := i.private[curr].validate(); err != nil {
+			err.Shard = curr
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *index) validate() *ValidationError {
+	if p.Version == 0 || p.Version > 3 {
+		return &ValidationError{Field: "version", Err: ErrFormatVersionMismatch}
+	}
+	if p.Logrows != byte(bits.Len64(p.Rows)) {
+		return &ValidationError{Field: "logrows", Err: ErrInconsistentRows}
+	}
+	if p.Version >= 2 && len(p.Counts) != len(p.Buckets) {
+		return &ValidationError{Field: "counts", Err: ErrMisalignedBuckets}
+	}
+	minWord := p.minWord()
+	if len(p.Buckets) > 0 && len(p.Buckets) > p.Maxword-minWord+1 {
+		return &ValidationError{Field: "buckets", Err: ErrMisalignedBuckets}
+	}
+	if p.Depth < 0 || (p.Depth > 0 && len(p.Buckets) > p.Depth) {
+		return &ValidationError{Field: "depth", Err: ErrMisalignedBuckets}
+	}
+	for _, f := range p.Buckets {
+		if !p.validFilter(f, uint64(p.Logrows)) {
+			return &ValidationError{Field: "buckets", Err: ErrMalformedFilter}
+		}
+	}
+	for _, f := range p.Counts {
+		if !p.validFilter(f, uint64(p.Logrows)) {
+			return &ValidationError{Field: "counts", Err: ErrMalformedFilter}
+		}
+	}
+	if !p.validFilter(p.Frequencies, frequencyBits) {
+		return &ValidationError{Field: "frequencies", Err: ErrMalformedFilter}
+	}
+	if !p.validFilter(p.Weights, weightBits) {
+		return &ValidationError{Field: "weights", Err: ErrMalformedFilter}
+	}
+	if !p.validFilter(p.TermPayloads, termPayloadBits) {
+		return &ValidationError{Field: "term_payloads", Err: ErrMalformedFilter}
+	}
+	if len(p.Stats) > 0 && !json.Valid(p.Stats) {
+		return &ValidationError{Field: "stats", Err: ErrCorrupted}
+	}
+	if len(p.InputHash) > 0 && len(p.InputHash) != sha256.Size {
+		return &ValidationError{Field: "input_hash", Err: ErrCorrupted}
+	}
+	for word, posting := range p.Postings {
+		// decoded postings were checked when decoded
+		if _, ok := p.postings[word]; ok {
+			continue
+		}
+		if _, err := decodePosting(posting, p.Rows); err != nil {
+			return &ValidationError{Field: "postings", Err: err}
+		}
+	}
+	if p.Rows == 0 {
+		return nil
+	}
+	if !validPadding(p.Padding) {
+		return &ValidationError{Field: "padding", Err: ErrUndecodablePk}
+	}
+	if p.Pkbits == 0 || p.Pkbits%8 != 0 || !p.validFilter(p.Pk, p.Pkbits) {
+		return &ValidationError{Field: "pk", Err: ErrUndecodablePk}
+	}
+	for j := uint64(1); j <= p.Rows; j++ {
+		if !p.decodable(p.Pk, p.Pkbits, j) {
+			return &ValidationError{Field: "pk", Err: ErrUndecodablePk}
+		}
+	}
+	return nil
+}
+
+// validFilter reports whether f can be probed for answers of anslen bits without panicking.
+func (p *index) validFilter(f []byte, anslen uint64) bool {
+	if len(f) == 0 {
+		return true
+	}
+	if len(f) < 2 {
+		return false
+	}
+	if len(f) == 2 || anslen == 0 {
+		return true
+	}
+	var ok = true
+	func() {
+		defer func() {
+			if recover() != nil {
+				ok = false
+			}
+		}()
+		p.backend().Get(f, anslen, "")
+	}()
+	return ok
+}
+
+// decodable reports whether row j of a primary key filter resolves to a full width key.
+func (p *index) decodable(pk []byte, pkbits, j uint64) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return uint64(len(p.backend().Get(pk, pkbits, rowKey(j))))*8 == pkbits
+}
@@ -0,0 +1,126 @@
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func newTestIndex(t *testing.T) *Index {
+	pk := BagOfWords{"doc:1": struct{}{}, "doc:2": struct{}{}, "doc:3": struct{}{}}
+	getter := func(key string) BagOfWords {
+		words := map[string]BagOfWords{
+			"doc:1": {"golang": struct{}{}, "backend": struct{}{}},
+			"doc:2": {"rust": struct{}{}, "backend": struct{}{}},
+			"doc:3": {"python": struct{}{}, "scripting": struct{}{}},
+		}
+		return words[key]
+	}
+	idx, err := New(nil, pk, getter)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return idx
+}
+
+// newAppendedTestIndex is newTestIndex with an appended shard, an updated row and a tombstone, so keys repeat across
+// shards
+func newAppendedTestIndex(t *testing.T) *Index {
+	idx := newTestIndex(t)
+	more, err := New(nil, map[string][]string{"doc:4": {"haskell", "backend"}}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.Append(more)
+	if err := idx.Update("doc:2", BagOfWords{"rust": {}, "systems": {}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	idx.Delete("doc:3")
+	return idx
+}
+
+// newSeededTestIndex is the corpus of newShardedTestIndex built with salted filter keys, colliding unlike unsalted ones
+func newSeededTestIndex(t *testing.T) *Index {
+	data := make(map[string][]string)
+	for j := 0; j < 40; j++ {
+		data[fmt.Sprintf("doc:%03d", j)] = []string{"common", fmt.Sprintf("word%03d", j)}
+	}
+	opts := NewDefaultOpts()
+	opts.TargetShardRows = 10
+	opts.HashSeed = 0x9e3779b97f4a7c15
+	idx, err := New(opts, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return idx
+}
+
+// TestValidateBuiltIndex tests that freshly built, sharded, appended and seeded indexes validate, also reloaded
+func TestValidateBuiltIndex(t *testing.T) {
+	for name, newIndex := range map[string]func(*testing.T) *Index{
+		"small":    newTestIndex,
+		"sharded":  newShardedTestIndex,
+		"appended": newAppendedTestIndex,
+		"seeded":   newSeededTestIndex,
+	} {
+		idx := newIndex(t)
+		if err := idx.Validate(); err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		data, _ := idx.Serialize()
+		var loaded Index
+		if err := loaded.Deserialize(data); err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		if err := loaded.Validate(); err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		data, _ = idx.SerializeProto()
+		if err := loaded.DeserializeProto(data); err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		if err := loaded.Validate(); err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+	}
+}
+
+// TestValidateCorrupted tests that structural corruption is reported with the offending shard
+func TestValidateCorrupted(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	last := len(idx.private) - 1
+	idx.private[last].Logrows++
+	var verr *ValidationError
+	if err := idx.Validate(); !errors.Is(err, ErrInconsistentRows) || !errors.As(err, &verr) || verr.Shard != last {
+		t.Fatalf("expected ErrInconsistentRows in shard %d, got %v", last, err)
+	}
+
+	idx = newShardedTestIndex(t)
+	idx.private[1].Counts = idx.private[1].Counts[1:]
+	if err := idx.Validate(); !errors.As(err, &verr) || verr.Shard != 1 || !errors.Is(err, ErrMisalignedBuckets) {
+		t.Fatalf("expected misaligned buckets in shard 1, got %v", err)
+	}
+
+	idx = newAppendedTestIndex(t)
+	for last = len(idx.private) - 1; idx.private[last].Rows == 0; last-- {
+	}
+	idx.private[last].Pk = []byte{1}
+	if err := idx.Validate(); !errors.Is(err, ErrUndecodablePk) || !errors.As(err, &verr) || verr.Shard != last {
+		t.Fatalf("expected ErrUndecodablePk in the appended shard %d, got %v", last, err)
+	}
+}
+
+// TestLoadUnknownAnalyzer tests that loading a shard analyzed by an analyzer not registered in this process fails
+func TestLoadUnknownAnalyzer(t *testing.T) {
+	idx := newTestIndex(t)
+	idx.private[0].Analyzer = "klingon"
+	data, _ := idx.Serialize()
+	var verr *ValidationError
+	if err := new(Index).Deserialize(data); !errors.Is(err, ErrUnknownAnalyzer) || !errors.As(err, &verr) || verr.Shard != 0 {
+		t.Fatalf("expected ErrUnknownAnalyzer in shard 0, got %v", err)
+	}
+	data, _ = idx.SerializeProto()
+	if err := new(Index).DeserializeProto(data); !errors.Is(err, ErrUnknownAnalyzer) {
+		t.Fatalf("expected ErrUnknownAnalyzer, got %v", err)
+	}
+}
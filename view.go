@@ -0,0 +1,31 @@
+package fulltext
+
+import "bytes"
+
+// View returns a read-only index over data produced by SerializeProto or SerializeSharded, without copying the shards.
+// The filters stay subslices of data, so a single buffer, such as a shared memory mapping, can back the indexes of many
+// processes. Data must not be modified while the index is in use.
+func View(data []byte) (*Index, error) {
+	i := new(Index)
+	if err := i.bounds().size(len(data)); err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, shardedMagic) {
+		count, _, err := shardDirectory(data)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int, count)
+		for curr := range ids {
+			ids[curr] = curr
+		}
+		if err := i.deserializeShards(data, ids, true); err != nil {
+			return nil, err
+		}
+		return i, nil
+	}
+	if err := i.deserializeProto(data); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
@@ -0,0 +1,44 @@
+package fulltext
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// within reports whether b is a subslice of data
+func within(b, data []byte) bool {
+	start := uintptr(unsafe.Pointer(unsafe.SliceData(data)))
+	p := uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+	return len(b) > 0 && p >= start && p+uintptr(len(b)) <= start+uintptr(len(data))
+}
+
+// TestView tests lookups over an index viewing the serialized buffer without copies
+func TestView(t *testing.T) {
+	idx := newShardedTestIndex(t)
+	sharded, _ := idx.SerializeSharded()
+	proto, _ := idx.SerializeProto()
+	for _, data := range [][]byte{sharded, proto} {
+		v, err := View(data)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(v.private) != len(idx.private) {
+			t.Fatalf("expected %d shards, got %d", len(idx.private), len(v.private))
+		}
+		for curr := range v.private {
+			if !within(v.private[curr].Pk, data) {
+				t.Fatalf("expected shard %d to view the buffer", curr)
+			}
+		}
+		var n int
+		for range v.Lookup("common", true, true) {
+			n++
+		}
+		if n != 40 {
+			t.Fatalf("expected 40 results, got %d", n)
+		}
+	}
+	if _, err := View([]byte("FTXS\x01\xff")); err == nil {
+		t.Fatal("expected an error for a truncated header")
+	}
+}
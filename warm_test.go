@@ -0,0 +1,18 @@
+package fulltext
+
+import (
+	"testing"
+)
+
+// TestWarm tests that warming leaves lookups intact
+func TestWarm(t *testing.T) {
+	idx := newTestIndex(t)
+	idx.Warm([]string{"backend", "golang", "nonexistent", ""})
+	count := 0
+	for range idx.Lookup("backend", true, true) {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 results, got %d", count)
+	}
+}
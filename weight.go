@@ -0,0 +1,90 @@
+package fulltext
+
+import "context"
+import "math"
+
+// weightBits and weightScale store word weights as fixed point numbers in steps of 1/16, up to almost 256
+const weightBits = 12
+const weightScale = 16
+
+// addWeights records the word weights of row pos, normalized like its words, keeping the highest weight
+// of words normalizing alike
+func (p *index) addWeights(pos int, weights map[string]float32, normalize func(BagOfWords) BagOfWords) {
+	if p.weights == nil {
+		p.weights = make(map[string]uint64)
+	}
+	for word, weight := range weights {
+		words := BagOfWords{word: {}}
+		if normalize != nil {
+			words = normalize(words)
+		}
+		fixed := uint64(math.Round(math.Min(math.Max(float64(weight), 0)*weightScale, 1<<weightBits-1)))
+		for word := range words {
+			key := p.counterKey(p.salted(word), uint64(pos))
+			p.weights[key] = max(p.weights[key], fixed)
+		}
+	}
+}
+
+// buildWeights turns the recorded word weights into the shard filter
+func (p *index) buildWeights() {
+	if len(p.weights) > 0 {
+		p.Weights = p.backend().New(numbers(p.weights, weightBits), weightBits, 0)
+	}
+	p.weights = nil
+}
+
+// weight returns the weight of word in row pos, 1 in shards without recorded weights
+func (p *index) weight(pos uint64, word string) float64 {
+	if len(p.Weights) < 2 {
+		return 1
+	}
+	return float64(p.backend().GetNum(p.Weights, weightBits, p.counterKey(p.salted(word), pos))) / weightScale
+}
+
+// weighted reports whether any shard holds word weights
+func (i *Index) weighted() bool {
+	for curr := range i.private {
+		if len(i.private[curr].Weights) >= 2 {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupWeighted iterates the primary keys of rows containing word like an exact deduplicated Lookup, yielding
+// the weight of word in the row, as recorded with NewOpts.Weights or map[string]float32 data. Weights are
+// recorded per whole word, so word should be a complete word, and rows without recorded weights yield 1.
+func (i *Index) LookupWeighted(word string) func(yield func(primaryKey string, weight float32) bool) {
+	return func(yield func(string, float32) bool) {
+		i.lookup(word, true, true, func(shard int, pos uint64) bool {
+			p := &i.private[shard]
+			return yield(p.key(pos), float32(p.weight(pos, p.query(word))))
+		})
+	}
+}
+
+// weights collects the highest weight of word per primary key matching the lookup opts.
+// Inexact lookups match parts of words, which carry no weight, so they weigh 1.
+func (i *Index) weights(word string, opts *LookupOpts) map[string]float64 {
+	weights := make(map[string]float64)
+	i.lookupContext(context.Background(), word, opts.Exact, true, opts.MinCoverage, func(shard int, pos uint64) bool {
+		p := &i.private[shard]
+		pk := p.key(pos)
+		if opts.Allow != nil && !opts.Allow(pk) {
+			return true
+		}
+		weight := 1.0
+		if opts.Exact {
+			weight = p.weight(pos, p.query(word))
+		}
+		weights[pk] = max(weights[pk], weight)
+		return true
+	})
+	if len(opts.Exclude) > 0 {
+		for pk := range i.excluded(opts) {
+			delete(weights, pk)
+		}
+	}
+	return weights
+}
@@ -0,0 +1,106 @@
+package fulltext
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestLookupWeighted tests that word weights survive serialization
+func TestLookupWeighted(t *testing.T) {
+	data := map[string]map[string]float32{
+		"doc:1": {"golang": 3, "backend": 1},
+		"doc:2": {"golang": 0.5},
+	}
+	idx, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	serialized, err := idx.SerializeProto()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var loaded Index
+	if err := loaded.DeserializeProto(serialized); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	weights := make(map[string]float32)
+	for pk, weight := range loaded.LookupWeighted("golang") {
+		weights[pk] = weight
+	}
+	if weights["doc:1"] != 3 || weights["doc:2"] != 0.5 {
+		t.Fatalf("unexpected weights %v", weights)
+	}
+}
+
+// TestLookupScoredWeights tests that weighted words rank their rows first in scored lookups
+func TestLookupScoredWeights(t *testing.T) {
+	words := map[string]map[string]float32{
+		"doc:1": {"golang": 1, "tutorial": 1},
+		"doc:2": {"golang": 3, "tutorial": 1},
+	}
+	opts := NewDefaultOpts()
+	opts.Weights = func(pk string) map[string]float32 { return words[pk] }
+	f, err := NewFieldIndex(opts, words, map[string]func(string) BagOfWords{
+		"body": func(pk string) BagOfWords {
+			bag := make(BagOfWords)
+			for word := range words[pk] {
+				bag[word] = struct{}{}
+			}
+			return bag
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var results []scored
+	for pk, score := range f.LookupScored("golang", &LookupOpts{Exact: true}) {
+		results = append(results, scored{key: pk, score: score})
+	}
+	if len(results) < 2 || results[0].key != "doc:2" || results[0].score != 3 {
+		t.Fatalf("expected doc:2 first with score 3, got %v", results)
+	}
+}
+
+// TestIndexTopK tests that the k best weighted or covered rows are selected, stopping once none can score better
+func TestIndexTopK(t *testing.T) {
+	data := map[string]map[string]float32{
+		"doc:1": {"golang": 3},
+		"doc:2": {"golang": 0.5},
+		"doc:3": {"golang": 2},
+		"doc:4": {"gopher": 1},
+	}
+	idx, err := New(nil, data, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var results []scored
+	for pk, score := range idx.TopK("golang", 2, &LookupOpts{Exact: true}) {
+		results = append(results, scored{key: pk, score: score})
+	}
+	if len(results) != 2 || results[0] != (scored{"doc:1", 3}) || results[1] != (scored{"doc:3", 2}) {
+		t.Fatalf("expected doc:1=3 doc:3=2, got %v", results)
+	}
+	results = nil
+	for pk, score := range idx.TopK("golang", 1, &LookupOpts{Exclude: []string{"golang"}}) {
+		results = append(results, scored{key: pk, score: score})
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected excluded rows skipped, got %v", results)
+	}
+
+	plain := make(map[string]struct{})
+	for n := range 200 {
+		plain[fmt.Sprintf("doc:%03d", n)] = struct{}{}
+	}
+	idx, err = New(nil, plain, func(string) BagOfWords { return BagOfWords{"golang": {}} })
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var asked int
+	for pk, score := range idx.TopK("golang", 3, &LookupOpts{Allow: func(string) bool { asked++; return true }}) {
+		results = append(results, scored{key: pk, score: score})
+	}
+	if len(results) != 3 || results[0].score != 1 || asked != 3 {
+		t.Fatalf("expected 3 full coverage rows after 3 hits, got %v after %d", results, asked)
+	}
+}